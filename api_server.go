@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/afkarxyz/SpotiDownloader/backend"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultAPIServerBind = "127.0.0.1"
+	defaultAPIServerPort = 8765
+
+	apiMaxRequestBody     = 1 << 20
+	apiWebSocketBuffer    = 256
+	apiWebSocketPingWait  = 30 * time.Second
+	apiWebSocketWriteTTL  = 10 * time.Second
+	apiQueueSnapshotEvent = "queue"
+)
+
+type apiServerConfig struct {
+	Enabled bool
+	Bind    string
+	Port    int
+	Key     string
+}
+
+type apiServer struct {
+	app      *App
+	key      string
+	server   *http.Server
+	upgrader websocket.Upgrader
+}
+
+type apiStreamMessage struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+func loadAPIServerConfig() apiServerConfig {
+	config := apiServerConfig{Bind: defaultAPIServerBind, Port: defaultAPIServerPort}
+
+	settings, err := backend.LoadConfigSettings()
+	if err != nil || settings == nil {
+		return config
+	}
+
+	config.Enabled, _ = settings["apiServerEnabled"].(bool)
+	if bind, ok := settings["apiServerBind"].(string); ok && strings.TrimSpace(bind) != "" {
+		config.Bind = strings.TrimSpace(bind)
+	}
+	if port, ok := settings["apiServerPort"].(float64); ok && port > 0 && port < 65536 {
+		config.Port = int(port)
+	}
+	if key, ok := settings["apiServerKey"].(string); ok {
+		config.Key = strings.TrimSpace(key)
+	}
+	return config
+}
+
+func newAPIServer(app *App, config apiServerConfig) (*apiServer, error) {
+	if config.Key == "" {
+		return nil, fmt.Errorf("apiServerKey must be set in config.json to enable the API server")
+	}
+
+	s := &apiServer{
+		app: app,
+		key: config.Key,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/status", s.handleStatus)
+	mux.HandleFunc("POST /api/v1/metadata", s.handleMetadata)
+	mux.HandleFunc("POST /api/v1/downloads", s.handleEnqueueDownloads)
+	mux.HandleFunc("POST /api/v1/downloads/url", s.handleEnqueueURL)
+	mux.HandleFunc("GET /api/v1/queue", s.handleQueue)
+	mux.HandleFunc("GET /api/v1/history", s.handleHistory)
+	mux.HandleFunc("POST /api/v1/lyrics", s.handleLyrics)
+	mux.HandleFunc("POST /api/v1/cover", s.handleCover)
+	mux.HandleFunc("GET /api/v1/ws", s.handleWebSocket)
+
+	s.server = &http.Server{
+		Addr:              net.JoinHostPort(config.Bind, strconv.Itoa(config.Port)),
+		Handler:           s.authorize(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+func (s *apiServer) start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.server.Addr, err)
+	}
+
+	fmt.Printf("[API] Listening on http://%s\n", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("[API] Server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+func (s *apiServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = s.server.Shutdown(ctx)
+}
+
+func (a *App) startAPIServer() {
+	config := loadAPIServerConfig()
+	if !config.Enabled {
+		return
+	}
+
+	server, err := newAPIServer(a, config)
+	if err != nil {
+		fmt.Printf("[API] %v\n", err)
+		return
+	}
+	if err := server.start(); err != nil {
+		fmt.Printf("[API] %v\n", err)
+		return
+	}
+	a.api = server
+}
+
+func (a *App) stopAPIServer() {
+	if a.api != nil {
+		a.api.stop()
+		a.api = nil
+	}
+}
+
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token == "" && r.URL.Path == "/api/v1/ws" {
+			token = r.URL.Query().Get("token")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.key)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing API key"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("[API] Failed to write response: %v\n", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, apiMaxRequestBody))
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	queue := backend.GetDownloadQueue()
+	writeAPIJSON(w, http.StatusOK, map[string]interface{}{
+		"version":        backend.AppVersion,
+		"is_downloading": queue.IsDownloading,
+		"queued":         queue.QueuedCount,
+		"downloading":    queue.DownloadingCount,
+	})
+}
+
+func (s *apiServer) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	var req SpotifyMetadataRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	if req.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("URL parameter is required"))
+		return
+	}
+	if req.Delay == 0 {
+		req.Delay = 1.0
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 300.0
+	}
+	if req.Separator == "" {
+		req.Separator = loadDownloadDefaults().Separator
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout*float64(time.Second)))
+	defer cancel()
+
+	data, err := backend.GetFilteredSpotifyData(ctx, req.URL, req.Batch, time.Duration(req.Delay*float64(time.Second)), req.Separator, nil)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, fmt.Errorf("failed to fetch metadata: %v", err))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, data)
+}
+
+func (s *apiServer) handleEnqueueDownloads(w http.ResponseWriter, r *http.Request) {
+	var requests []DownloadRequest
+	if !decodeAPIRequest(w, r, &requests) {
+		return
+	}
+	if len(requests) == 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("at least one download request is required"))
+		return
+	}
+
+	ids, err := s.app.EnqueueDownloads(requests)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusAccepted, map[string][]string{"item_ids": ids})
+}
+
+func (s *apiServer) handleEnqueueURL(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueURLRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	ids, err := s.app.EnqueueSpotifyURL(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusAccepted, map[string][]string{"item_ids": ids})
+}
+
+func (s *apiServer) handleQueue(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, s.app.GetDownloadQueue())
+}
+
+func (s *apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	items, err := s.app.GetDownloadHistory()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, items)
+}
+
+func (s *apiServer) handleLyrics(w http.ResponseWriter, r *http.Request) {
+	var req LyricsDownloadRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	resp, err := s.app.DownloadLyrics(req)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadGateway, resp)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+func (s *apiServer) handleCover(w http.ResponseWriter, r *http.Request) {
+	var req CoverDownloadRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	resp, err := s.app.DownloadCover(req)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadGateway, resp)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+func (s *apiServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(apiWebSocketPingWait * 2))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(apiWebSocketPingWait * 2))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	events := make(chan apiStreamMessage, apiWebSocketBuffer)
+	var dropped atomic.Bool
+	unsubscribe := backend.SubscribeEvents(func(name string, data interface{}) {
+		if !backend.IsDownloadEvent(name) {
+			return
+		}
+		select {
+		case events <- apiStreamMessage{Type: name, Data: data}:
+		default:
+			dropped.Store(true)
+		}
+	})
+	defer unsubscribe()
+
+	send := func(message apiStreamMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(apiWebSocketWriteTTL))
+		return conn.WriteJSON(message)
+	}
+	sendSnapshot := func() error {
+		return send(apiStreamMessage{Type: apiQueueSnapshotEvent, Data: backend.GetDownloadQueue()})
+	}
+
+	if err := sendSnapshot(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(apiWebSocketPingWait)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case message := <-events:
+			if err := send(message); err != nil {
+				return
+			}
+			if dropped.Swap(false) {
+				if err := sendSnapshot(); err != nil {
+					return
+				}
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(apiWebSocketWriteTTL))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -22,7 +22,11 @@ import (
 )
 
 type App struct {
-	ctx context.Context
+	ctx   context.Context
+	tasks sync.WaitGroup
+	api   *apiServer
+
+	unsubscribeEvents func()
 }
 
 type CurrentIPInfo struct {
@@ -330,6 +334,9 @@ func (a *App) getFirstArtist(artistString string) string {
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.unsubscribeEvents = backend.SubscribeEvents(func(name string, data interface{}) {
+		runtime.EventsEmit(ctx, name, data)
+	})
 
 	if err := backend.InitHistoryDB("SpotiDownloader"); err != nil {
 		fmt.Printf("Failed to init history DB: %v\n", err)
@@ -337,11 +344,38 @@ func (a *App) startup(ctx context.Context) {
 	if err := backend.InitISRCCacheDB(); err != nil {
 		fmt.Printf("Failed to init ISRC cache DB: %v\n", err)
 	}
+	if err := backend.InitLibraryIndexDB(); err != nil {
+		fmt.Printf("Failed to init library index DB: %v\n", err)
+	}
+	if err := backend.InitMusicBrainzCacheDB(); err != nil {
+		fmt.Printf("Failed to init MusicBrainz cache DB: %v\n", err)
+	}
+	if err := backend.InitDownloadQueueDB(); err != nil {
+		fmt.Printf("Failed to init download queue DB: %v\n", err)
+	}
+	if err := backend.RecoverPartialDownloads(); err != nil {
+		fmt.Printf("Failed to recover partial downloads: %v\n", err)
+	}
+	if err := backend.StartDownloadQueue(a.runQueuedDownload, backend.GetDownloadWorkerSetting()); err != nil {
+		fmt.Printf("Failed to start download queue: %v\n", err)
+	}
+	backend.StartWatchScheduler(a.fetchWatchJobs)
+	a.startAPIServer()
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	a.stopAPIServer()
+	backend.StopWatchScheduler()
+	backend.StopDownloadQueue()
+	backend.CloseDownloadQueueDB()
 	backend.CloseHistoryDB()
 	backend.CloseISRCCacheDB()
+	backend.CloseLibraryIndexDB()
+	backend.CloseMusicBrainzCacheDB()
+
+	if a.unsubscribeEvents != nil {
+		a.unsubscribeEvents()
+	}
 }
 
 type SpotifyMetadataRequest struct {
@@ -366,12 +400,15 @@ type DownloadRequest struct {
 	TotalTracks         int    `json:"total_tracks,omitempty"`
 	SpotifyTotalDiscs   int    `json:"spotify_total_discs,omitempty"`
 	ISRC                string `json:"isrc,omitempty"`
+	UPC                 string `json:"upc,omitempty"`
+	Explicit            bool   `json:"explicit,omitempty"`
 	Copyright           string `json:"copyright,omitempty"`
 	Publisher           string `json:"publisher,omitempty"`
 	Composer            string `json:"composer,omitempty"`
 	OutputDir           string `json:"output_dir,omitempty"`
 	AudioFormat         string `json:"audio_format,omitempty"`
 	FilenameFormat      string `json:"filename_format,omitempty"`
+	FolderTemplate      string `json:"folder_template,omitempty"`
 	TrackNumber         bool   `json:"track_number,omitempty"`
 	Position            int    `json:"position,omitempty"`
 	UseAlbumTrackNumber bool   `json:"use_album_track_number,omitempty"`
@@ -383,6 +420,7 @@ type DownloadRequest struct {
 	ItemID               string `json:"item_id,omitempty"`
 	PlaylistName         string `json:"playlist_name,omitempty"`
 	PlaylistOwner        string `json:"playlist_owner,omitempty"`
+	IsAlbum              bool   `json:"is_album,omitempty"`
 	UseFirstArtistOnly   bool   `json:"use_first_artist_only,omitempty"`
 	UseSingleGenre       bool   `json:"use_single_genre,omitempty"`
 	EmbedGenre           bool   `json:"embed_genre,omitempty"`
@@ -444,7 +482,7 @@ func (a *App) GetSpotifyMetadata(req SpotifyMetadataRequest) (string, error) {
 		}
 	}
 	data, err := backend.GetFilteredSpotifyData(ctx, req.URL, req.Batch, time.Duration(req.Delay*float64(time.Second)), separator, func(tracks interface{}) {
-		runtime.EventsEmit(a.ctx, "metadata-stream", tracks)
+		backend.EmitEvent("metadata-stream", tracks)
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch metadata: %v", err)
@@ -519,18 +557,19 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}, fmt.Errorf("session token is required")
 	}
 
-	if req.OutputDir == "" {
-		req.OutputDir = "."
-	} else {
-
-		if req.PlaylistName != "" {
-			sanitizedPlaylist := backend.SanitizeFilename(req.PlaylistName)
-			req.OutputDir = filepath.Join(req.OutputDir, sanitizedPlaylist)
+	if err := validateDownloadTemplates(req); err != nil {
+		if req.ItemID != "" {
+			backend.FailDownloadItem(req.ItemID, err.Error())
 		}
-
-		req.OutputDir = backend.SanitizeFolderPath(req.OutputDir)
+		return DownloadResponse{
+			Success: false,
+			Error:   err.Error(),
+			ItemID:  req.ItemID,
+		}, err
 	}
 
+	original := req
+
 	if req.AudioFormat == "" {
 		req.AudioFormat = "mp3"
 	}
@@ -538,7 +577,7 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 	if req.FilenameFormat == "" {
 		req.FilenameFormat = "title-artist"
 	}
-	if req.ISRC == "" && strings.Contains(req.FilenameFormat, "{isrc}") {
+	if req.ISRC == "" && downloadTemplatesUse(req, "isrc") {
 		lookupTrackID := req.TrackID
 		if lookupTrackID == "" {
 			lookupTrackID = req.SpotifyID
@@ -562,33 +601,30 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 
 		backend.AddToQueue(itemID, req.TrackName, req.ArtistName, req.AlbumName, spotifyIDForQueue)
+	} else if status, ok := backend.GetDownloadItemStatus(itemID); ok && (status == backend.StatusCancelled || status == backend.StatusPaused) {
+		return DownloadResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Download %s", status),
+			ItemID:  itemID,
+		}, fmt.Errorf("download %s", status)
 	}
 
 	backend.SetDownloading(true)
 	backend.StartDownloadItem(itemID)
 	defer backend.SetDownloading(false)
 
+	itemCtx, releaseItemCtx := backend.AcquireDownloadItemContext(itemID)
+	defer releaseItemCtx()
+
 	metadataTrackID := req.SpotifyID
 	if metadataTrackID == "" {
 		metadataTrackID = req.TrackID
 	}
 
-	metadataSeparator := req.Separator
-	if metadataSeparator == "" {
-		metadataSeparator = ", "
-		metadataSettings, _ := a.LoadSettings()
-		if metadataSettings != nil {
-			if sep, ok := metadataSettings["separator"].(string); ok {
-				if sep == "semicolon" {
-					metadataSeparator = "; "
-				} else if sep == "comma" {
-					metadataSeparator = ", "
-				}
-			}
-		}
-	}
+	metadataSeparator := a.resolveMetadataSeparator(req.Separator)
 
-	if metadataTrackID != "" && (req.Copyright == "" || req.Publisher == "" || req.Composer == "" || req.SpotifyTotalDiscs == 0 || req.ReleaseDate == "" || req.TotalTracks == 0 || req.AlbumTrackNumber == 0) {
+	missingTemplateMetadata := (req.UPC == "" && downloadTemplatesUse(req, "upc")) || (req.Duration == 0 && downloadTemplatesUse(req, "duration"))
+	if metadataTrackID != "" && (req.Copyright == "" || req.Publisher == "" || req.Composer == "" || req.SpotifyTotalDiscs == 0 || req.ReleaseDate == "" || req.TotalTracks == 0 || req.AlbumTrackNumber == 0 || missingTemplateMetadata) {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
@@ -605,6 +641,9 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 					TotalTracks int    `json:"total_tracks"`
 					TrackNumber int    `json:"track_number"`
 					ReleaseDate string `json:"release_date"`
+					UPC         string `json:"upc"`
+					DurationMS  int    `json:"duration_ms"`
+					IsExplicit  bool   `json:"is_explicit"`
 				} `json:"track"`
 			}
 			if jsonData, jsonErr := json.Marshal(trackData); jsonErr == nil {
@@ -631,17 +670,28 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 					if req.ReleaseDate == "" && trackResp.Track.ReleaseDate != "" {
 						req.ReleaseDate = trackResp.Track.ReleaseDate
 					}
+					if req.UPC == "" && trackResp.Track.UPC != "" {
+						req.UPC = trackResp.Track.UPC
+					}
+					if req.Duration == 0 && trackResp.Track.DurationMS > 0 {
+						req.Duration = trackResp.Track.DurationMS / 1000
+					}
+					if trackResp.Track.IsExplicit {
+						req.Explicit = true
+					}
 				}
 			}
 		}
 	}
 
+	req.OutputDir = resolveDownloadDir(req)
+
 	if req.TrackName != "" && req.ArtistName != "" {
 		fileExt := ".mp3"
 		if req.AudioFormat == "flac" {
 			fileExt = ".flac"
 		}
-		expectedFilename := backend.BuildFilenameWithISRC(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.DiscNumber, req.FilenameFormat, req.TrackNumber, req.Position, req.UseAlbumTrackNumber, req.PlaylistName, req.PlaylistOwner, req.ISRC)
+		expectedFilename := backend.BuildFilenameFromTemplate(req.FilenameFormat, downloadTemplateData(req), req.TrackNumber)
 		expectedFilename = backend.SanitizeFilename(expectedFilename) + fileExt
 		expectedPath := filepath.Join(req.OutputDir, expectedFilename)
 
@@ -659,19 +709,38 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 	}
 
+	if !backend.GetRedownloadWithSuffixSetting() && backend.GetSkipExistingISRCSetting() {
+		if req.ISRC == "" && metadataTrackID != "" {
+			req.ISRC = backend.ResolveTrackISRC(metadataTrackID)
+		}
+		if existing, ok := backend.FindLibraryTrackByISRC(backend.GetLibraryRootSetting(), req.ISRC); ok {
+			fmt.Printf("[Library] ISRC %s already in library: %s\n", req.ISRC, existing.Path)
+			backend.SkipDownloadItem(itemID, existing.Path)
+			return DownloadResponse{
+				Success:       true,
+				Message:       "Recording already in library",
+				File:          existing.Path,
+				AlreadyExists: true,
+				ItemID:        itemID,
+			}, nil
+		}
+	}
+
 	trackID := req.TrackID
 	if trackID == "" {
 		trackID = req.SpotifyID
 	}
 
 	lyricsChan := make(chan string, 1)
+	var lyricsSource string
 	if req.EmbedLyrics && trackID != "" {
 		go func() {
 			fmt.Println("Fetching lyrics in background...")
-			client := backend.NewLyricsClient()
-			resp, _, err := client.FetchLyricsAllSources(trackID, req.TrackName, req.ArtistName, req.AlbumName, req.Duration)
+			client := backend.NewLyricsClientWithContext(itemCtx)
+			resp, source, err := client.FetchLyricsAllSources(trackID, req.TrackName, req.ArtistName, req.AlbumName, req.Duration)
 			if err == nil && resp != nil && len(resp.Lines) > 0 {
 				lrc := client.ConvertToLRC(resp, req.TrackName, req.ArtistName)
+				lyricsSource = source
 				lyricsChan <- lrc
 			} else {
 				lyricsChan <- ""
@@ -681,46 +750,82 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		close(lyricsChan)
 	}
 
-	downloader := backend.NewSpotiDownloader(req.SessionToken)
+	downloader := backend.NewSpotiDownloaderForItem(itemCtx, req.SessionToken, itemID)
 
 	actualTrackNumber := req.AlbumTrackNumber
 	if actualTrackNumber == 0 {
 		actualTrackNumber = 1
 	}
 
-	filename, err := downloader.DownloadTrack(
-		trackID,
-		req.OutputDir,
-		req.AudioFormat,
-		req.FilenameFormat,
-		req.TrackNumber,
-		req.Position,
-		req.TrackName,
-		req.ArtistName,
-		req.AlbumName,
-		req.AlbumArtist,
-		req.ReleaseDate,
-		req.CoverURL,
-		actualTrackNumber,
-		req.DiscNumber,
-		req.TotalTracks,
-		req.UseAlbumTrackNumber,
-		req.EmbedMaxQualityCover,
-		req.SpotifyTotalDiscs,
-		req.Copyright,
-		req.Publisher,
-		req.Composer,
-		metadataSeparator,
-		req.ISRC,
-		req.PlaylistName,
-		req.PlaylistOwner,
-		req.UseFirstArtistOnly,
-		req.UseSingleGenre,
-		req.EmbedGenre,
-	)
+	policy := backend.GetRetryPolicySetting()
+
+	var filename string
+	var err error
+	for attempt := 1; ; attempt++ {
+		backend.SetDownloadItemAttempt(itemID, attempt, policy.MaxAttempts)
+
+		filename, err = downloader.DownloadTrack(
+			trackID,
+			req.OutputDir,
+			req.AudioFormat,
+			req.FilenameFormat,
+			req.TrackNumber,
+			req.Position,
+			req.TrackName,
+			req.ArtistName,
+			req.AlbumName,
+			req.AlbumArtist,
+			req.ReleaseDate,
+			req.CoverURL,
+			actualTrackNumber,
+			req.DiscNumber,
+			req.TotalTracks,
+			req.UseAlbumTrackNumber,
+			req.EmbedMaxQualityCover,
+			req.SpotifyTotalDiscs,
+			req.Copyright,
+			req.Publisher,
+			req.Composer,
+			metadataSeparator,
+			req.ISRC,
+			req.PlaylistName,
+			req.PlaylistOwner,
+			req.UseFirstArtistOnly,
+			req.UseSingleGenre,
+			req.EmbedGenre,
+			req.UPC,
+			req.Explicit,
+			req.Duration,
+		)
+
+		if err == nil || attempt >= policy.MaxAttempts || !backend.IsRetryableDownloadError(err) || backend.DownloadStopReason(itemCtx) != nil {
+			break
+		}
+
+		delay := policy.Delay(attempt, err)
+		fmt.Printf("Attempt %d/%d failed: %v (retrying in %s)\n", attempt, policy.MaxAttempts, err, delay.Round(time.Second))
+		backend.ScheduleDownloadItemRetry(itemID, time.Now().Add(delay).Unix(), fmt.Sprintf("Attempt %d failed: %v", attempt, err))
+
+		select {
+		case <-itemCtx.Done():
+		case <-time.After(delay):
+		}
+		if backend.DownloadStopReason(itemCtx) != nil {
+			break
+		}
+
+		if token, tokenErr := backend.FetchSessionToken(); tokenErr == nil && token != "" {
+			downloader = backend.NewSpotiDownloaderForItem(itemCtx, token, itemID)
+		}
+		backend.StartDownloadItem(itemID)
+	}
 
 	if err != nil {
+		if reason := backend.DownloadStopReason(itemCtx); reason != nil {
+			return stoppedDownloadResponse(original, itemID, reason)
+		}
 		backend.FailDownloadItem(itemID, fmt.Sprintf("Download failed: %v", err))
+		recordFailedDownload(original, itemID)
 		return DownloadResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Download failed: %v", err),
@@ -740,6 +845,7 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			cleanupInvalidDownloadArtifacts(filename)
 			errorMessage := validationErr.Error()
 			backend.FailDownloadItem(itemID, errorMessage)
+			recordFailedDownload(original, itemID)
 			return DownloadResponse{
 				Success: false,
 				Error:   errorMessage,
@@ -760,7 +866,8 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			fmt.Printf("--- End LRC Content ---\n\n")
 
 			fmt.Printf("Embedding into: %s\n", filename)
-			if err := backend.EmbedLyricsOnly(filename, lyrics); err != nil {
+			fmt.Printf("Lyrics source: %s\n", lyricsSource)
+			if err := backend.EmbedLyricsWithSource(filename, lyrics, lyricsSource); err != nil {
 				fmt.Printf("Failed to embed lyrics: %v\n", err)
 			} else {
 				fmt.Printf("Lyrics embedded successfully!\n")
@@ -798,11 +905,17 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			backend.CompleteDownloadItem(itemID, filename, 0)
 		}
 
+		a.tasks.Add(1)
 		go func(fPath, track, artist, album, sID, cover, format string) {
+			defer a.tasks.Done()
 			quality := "Unknown"
 			durationStr := "--:--"
 
-			meta, err := backend.GetTrackMetadata(fPath)
+			meta, err := backend.AnalyzeTrackQuality(fPath)
+			if err != nil {
+				fmt.Printf("[History] Quality analysis failed for %s: %v\n", fPath, err)
+				meta, err = backend.GetTrackMetadata(fPath)
+			}
 			if err == nil && meta != nil {
 				if meta.BitsPerSample > 0 {
 					quality = fmt.Sprintf("%d-bit/%.1fkHz", meta.BitsPerSample, float64(meta.SampleRate)/1000.0)
@@ -833,6 +946,17 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			if item.Format == "" {
 				item.Format = strings.ToUpper(strings.TrimPrefix(filepath.Ext(fPath), "."))
 			}
+			if meta != nil && meta.Transcode != nil {
+				item.QualityVerdict = meta.Transcode.Verdict
+				item.QualityConfidence = meta.Transcode.Confidence
+				item.CutoffHz = meta.Transcode.CutoffHz
+			}
+			backend.AddLibraryFile(fPath)
+			if checksum, err := backend.ComputeAudioChecksum(fPath); err != nil {
+				fmt.Printf("[History] Checksum failed for %s: %v\n", fPath, err)
+			} else {
+				item.SetChecksum(checksum)
+			}
 			backend.AddHistoryItem(item, "SpotiDownloader")
 		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, req.AudioFormat)
 	}
@@ -891,9 +1015,7 @@ func (a *App) SaveSpectrumImage(audioFilePath string, base64Data string) (string
 		return "", fmt.Errorf("failed to decode base64 image: %v", err)
 	}
 
-	ext := filepath.Ext(audioFilePath)
-	baseName := strings.TrimSuffix(filepath.Base(audioFilePath), ext)
-	outPath := filepath.Join(filepath.Dir(audioFilePath), baseName+".png")
+	outPath := backend.SpectrogramOutputPath(audioFilePath, "")
 
 	err = os.WriteFile(outPath, data, 0644)
 	if err != nil {
@@ -903,6 +1025,20 @@ func (a *App) SaveSpectrumImage(audioFilePath string, base64Data string) (string
 	return outPath, nil
 }
 
+func (a *App) RenderSpectrogram(audioFilePath string, options backend.SpectrogramOptions) (string, error) {
+	if audioFilePath == "" {
+		return "", fmt.Errorf("file path is required")
+	}
+	return backend.RenderSpectrogram(audioFilePath, options)
+}
+
+func (a *App) RenderSpectrograms(paths []string, options backend.SpectrogramOptions) ([]backend.SpectrogramResult, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one file or folder is required")
+	}
+	return backend.RenderSpectrograms(paths, options)
+}
+
 func (a *App) GetDefaults() map[string]string {
 	return map[string]string{
 		"downloadPath": backend.GetDefaultMusicPath(),
@@ -1043,6 +1179,152 @@ func (a *App) AddToDownloadQueue(trackID, trackName, artistName, albumName strin
 	return itemID
 }
 
+type EnqueueURLRequest struct {
+	URL            string `json:"url"`
+	OutputDir      string `json:"output_dir,omitempty"`
+	AudioFormat    string `json:"audio_format,omitempty"`
+	FilenameFormat string `json:"filename_format,omitempty"`
+}
+
+func newDownloadJob(req DownloadRequest) (backend.DownloadJob, error) {
+	req.SessionToken = ""
+
+	spotifyID := req.SpotifyID
+	if spotifyID == "" {
+		spotifyID = req.TrackID
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return backend.DownloadJob{}, fmt.Errorf("failed to encode download request: %v", err)
+	}
+
+	return backend.DownloadJob{
+		ID:         req.ItemID,
+		SpotifyID:  spotifyID,
+		TrackName:  req.TrackName,
+		ArtistName: req.ArtistName,
+		AlbumName:  req.AlbumName,
+		Payload:    payload,
+	}, nil
+}
+
+func (a *App) EnqueueDownloads(requests []DownloadRequest) ([]string, error) {
+	jobs := make([]backend.DownloadJob, 0, len(requests))
+	for _, req := range requests {
+		if req.TrackID == "" && req.SpotifyID == "" {
+			return nil, fmt.Errorf("track ID or Spotify ID is required")
+		}
+		if err := validateDownloadTemplates(req); err != nil {
+			return nil, err
+		}
+		job, err := newDownloadJob(req)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return backend.EnqueueDownloadJobs(jobs), nil
+}
+
+func (a *App) EnqueueSpotifyURL(req EnqueueURLRequest) ([]string, error) {
+	if req.URL == "" {
+		return nil, fmt.Errorf("URL parameter is required")
+	}
+
+	defaults := loadDownloadDefaults()
+	if req.OutputDir != "" {
+		defaults.OutputDir = req.OutputDir
+	}
+	if req.AudioFormat != "" {
+		defaults.AudioFormat = req.AudioFormat
+	}
+	if req.FilenameFormat != "" {
+		defaults.FilenameFormat = req.FilenameFormat
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	data, err := backend.GetFilteredSpotifyData(ctx, req.URL, false, time.Second, defaults.Separator, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %v", err)
+	}
+
+	collection, err := buildDownloadCollection(data, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.EnqueueDownloads(collection.Requests)
+}
+
+func (a *App) SetDownloadWorkers(workers int) error {
+	backend.SetDownloadWorkerCount(workers)
+	return backend.SaveConfigSetting("downloadWorkers", backend.GetDownloadWorkerCount())
+}
+
+func (a *App) runQueuedDownload(job backend.DownloadJob) error {
+	var req DownloadRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil {
+		backend.FailDownloadItem(job.ID, fmt.Sprintf("Invalid download job: %v", err))
+		return err
+	}
+	req.ItemID = job.ID
+
+	token, err := backend.FetchSessionToken()
+	if err != nil {
+		backend.FailDownloadItem(job.ID, fmt.Sprintf("Failed to fetch session token: %v", err))
+		backend.RecordFailedDownloadJob(job)
+		return err
+	}
+	req.SessionToken = token
+
+	_, err = a.DownloadTrack(req)
+	return err
+}
+
+func recordFailedDownload(req DownloadRequest, itemID string) {
+	req.ItemID = itemID
+	if job, err := newDownloadJob(req); err == nil {
+		backend.RecordFailedDownloadJob(job)
+	}
+}
+
+func stoppedDownloadResponse(req DownloadRequest, itemID string, reason error) (DownloadResponse, error) {
+	if errors.Is(reason, backend.ErrDownloadPaused) {
+		req.ItemID = itemID
+		if job, err := newDownloadJob(req); err == nil {
+			backend.HoldPausedDownloadJob(job)
+		} else {
+			fmt.Printf("Failed to save paused download %s: %v\n", itemID, err)
+		}
+	}
+
+	return DownloadResponse{
+		Success: false,
+		Error:   reason.Error(),
+		ItemID:  itemID,
+	}, reason
+}
+
+func (a *App) RetryFailedDownloads() int {
+	return backend.RetryFailedDownloads()
+}
+
+func (a *App) PauseDownloadItem(itemID string) error {
+	return backend.PauseDownloadItem(itemID)
+}
+
+func (a *App) ResumeDownloadItem(itemID string) error {
+	return backend.ResumeDownloadItem(itemID)
+}
+
+func (a *App) CancelDownloadItem(itemID string) error {
+	return backend.CancelDownloadItem(itemID)
+}
+
 func (a *App) ClearCompletedDownloads() {
 	backend.ClearDownloadQueue()
 }
@@ -1206,6 +1488,10 @@ type LyricsDownloadRequest struct {
 	DiscNumber          int    `json:"disc_number"`
 }
 
+func (a *App) GetLyricsProviders() []string {
+	return backend.AvailableLyricsProviders()
+}
+
 func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadResponse, error) {
 	if req.SpotifyID == "" {
 		return backend.LyricsDownloadResponse{
@@ -1434,19 +1720,19 @@ type DownloadFFmpegResponse struct {
 }
 
 func (a *App) DownloadFFmpeg() DownloadFFmpegResponse {
-	runtime.EventsEmit(a.ctx, "ffmpeg:status", "starting")
+	backend.EmitEvent("ffmpeg:status", "starting")
 	err := backend.DownloadFFmpeg(func(progress int) {
-		runtime.EventsEmit(a.ctx, "ffmpeg:progress", progress)
+		backend.EmitEvent("ffmpeg:progress", progress)
 	})
 	if err != nil {
-		runtime.EventsEmit(a.ctx, "ffmpeg:status", "failed")
+		backend.EmitEvent("ffmpeg:status", "failed")
 		return DownloadFFmpegResponse{
 			Success: false,
 			Error:   err.Error(),
 		}
 	}
 
-	runtime.EventsEmit(a.ctx, "ffmpeg:status", "completed")
+	backend.EmitEvent("ffmpeg:status", "completed")
 	return DownloadFFmpegResponse{
 		Success: true,
 		Message: "FFmpeg installed successfully",
@@ -1537,6 +1823,38 @@ func (a *App) DecodeAudioForAnalysis(filePath string) (*backend.AnalysisDecodeRe
 	return backend.DecodeAudioForAnalysis(filePath)
 }
 
+func (a *App) AnalyzeTrackQuality(filePath string) (*backend.AnalysisResult, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+
+	return backend.AnalyzeTrackQuality(filePath)
+}
+
+func (a *App) VerifyLibrary(dir string) ([]backend.VerifyResult, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory is required")
+	}
+
+	return backend.VerifyLibrary(dir, "SpotiDownloader")
+}
+
+func (a *App) MeasureLoudness(filePath string) (*backend.LoudnessResult, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+
+	return backend.MeasureLoudness(a.ctx, filePath)
+}
+
+func (a *App) ApplyReplayGain(paths []string, albumMode bool) ([]backend.ReplayGainResult, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one file or folder is required")
+	}
+
+	return backend.ApplyReplayGain(paths, albumMode)
+}
+
 func (a *App) RenameFileTo(oldPath, newName string) error {
 	dir := filepath.Dir(oldPath)
 	ext := filepath.Ext(oldPath)
@@ -1585,6 +1903,14 @@ type CheckFileExistenceRequest struct {
 	IncludeTrackNumber  bool   `json:"include_track_number,omitempty"`
 	AudioFormat         string `json:"audio_format,omitempty"`
 	RelativePath        string `json:"relative_path,omitempty"`
+	FolderTemplate      string `json:"folder_template,omitempty"`
+	PlaylistName        string `json:"playlist_name,omitempty"`
+	PlaylistOwner       string `json:"playlist_owner,omitempty"`
+	IsAlbum             bool   `json:"is_album,omitempty"`
+	UPC                 string `json:"upc,omitempty"`
+	TotalTracks         int    `json:"total_tracks,omitempty"`
+	Duration            int    `json:"duration,omitempty"`
+	Explicit            bool   `json:"explicit,omitempty"`
 }
 
 type CheckFileExistenceResult struct {
@@ -1595,6 +1921,22 @@ type CheckFileExistenceResult struct {
 	ArtistName string `json:"artist_name,omitempty"`
 }
 
+func (a *App) ResolveFolderPath(req DownloadRequest) (FolderPathResult, error) {
+	if err := backend.ValidateFolderTemplate(req.FolderTemplate); err != nil {
+		return FolderPathResult{}, fmt.Errorf("folder template: %w", err)
+	}
+	if req.ISRC == "" && backend.TemplateUsesToken(req.FolderTemplate, "isrc") {
+		lookupTrackID := req.SpotifyID
+		if lookupTrackID == "" {
+			lookupTrackID = req.TrackID
+		}
+		if lookupTrackID != "" {
+			req.ISRC = backend.ResolveTrackISRC(lookupTrackID)
+		}
+	}
+	return resolveFolderPath(backend.NormalizePath(req.OutputDir), req.PlaylistName, req.FolderTemplate, req.IsAlbum, downloadTemplateData(req)), nil
+}
+
 func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat string, tracks []CheckFileExistenceRequest) []CheckFileExistenceResult {
 	if len(tracks) == 0 {
 		return []CheckFileExistenceResult{}
@@ -1615,30 +1957,6 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat
 
 	resultsChan := make(chan result, len(tracks))
 
-	var rootDirFiles map[string]string
-	rootDirFilesOnce := false
-	getRootDirFiles := func() map[string]string {
-		if rootDirFilesOnce {
-			return rootDirFiles
-		}
-		rootDirFiles = make(map[string]string)
-		if rootDir != "" && rootDir != outputDir {
-			filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return nil
-				}
-				if !info.IsDir() {
-					if strings.EqualFold(filepath.Ext(path), ".flac") || strings.EqualFold(filepath.Ext(path), ".mp3") {
-						rootDirFiles[info.Name()] = path
-					}
-				}
-				return nil
-			})
-		}
-		rootDirFilesOnce = true
-		return rootDirFiles
-	}
-
 	for i, track := range tracks {
 		go func(idx int, t CheckFileExistenceRequest) {
 			res := CheckFileExistenceResult{
@@ -1658,7 +1976,7 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat
 				filenameFormat = defaultFilenameFormat
 			}
 			isrc := strings.TrimSpace(t.ISRC)
-			if isrc == "" && strings.Contains(filenameFormat, "{isrc}") && t.SpotifyID != "" {
+			if isrc == "" && (backend.TemplateUsesToken(filenameFormat, "isrc") || backend.TemplateUsesToken(t.FolderTemplate, "isrc")) && t.SpotifyID != "" {
 				isrc = backend.ResolveTrackISRC(t.SpotifyID)
 			}
 
@@ -1672,26 +1990,31 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat
 				fileExt = ".flac"
 			}
 
-			expectedFilename := backend.BuildFilenameWithISRC(
-				t.TrackName,
-				t.ArtistName,
-				t.AlbumName,
-				t.AlbumArtist,
-				t.ReleaseDate,
-				t.DiscNumber,
-				filenameFormat,
-				t.IncludeTrackNumber,
-				trackNumber,
-				t.UseAlbumTrackNumber,
-				"",
-				"",
-				isrc,
-			)
+			data := backend.FilenameTemplateData{
+				Title:       t.TrackName,
+				Artist:      t.ArtistName,
+				Album:       t.AlbumName,
+				AlbumArtist: t.AlbumArtist,
+				ReleaseDate: t.ReleaseDate,
+				Playlist:    t.PlaylistName,
+				Creator:     t.PlaylistOwner,
+				ISRC:        isrc,
+				UPC:         t.UPC,
+				SpotifyID:   t.SpotifyID,
+				Track:       trackNumber,
+				Disc:        t.DiscNumber,
+				TotalTracks: t.TotalTracks,
+				Duration:    t.Duration,
+				Explicit:    t.Explicit,
+			}
+			expectedFilename := backend.BuildFilenameFromTemplate(filenameFormat, data, t.IncludeTrackNumber)
 			expectedFilename = backend.SanitizeFilename(expectedFilename) + fileExt
 
 			targetDir := outputDir
 			if t.RelativePath != "" {
 				targetDir = filepath.Join(outputDir, t.RelativePath)
+			} else if t.FolderTemplate != "" || t.PlaylistName != "" {
+				targetDir = resolveFolderPath(outputDir, t.PlaylistName, t.FolderTemplate, t.IsAlbum, data).OutputDir
 			}
 
 			expectedPath := filepath.Join(targetDir, expectedFilename)
@@ -1722,26 +2045,51 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat
 		}
 	}
 
-	if len(missingIndices) > 0 && rootDir != "" {
-		filesMap := getRootDirFiles()
-		if len(filesMap) > 0 {
-			for _, idx := range missingIndices {
-				expectedFilename := results[idx].FilePath
-				baseName := filepath.Base(expectedFilename)
-				if path, ok := filesMap[baseName]; ok {
+	if len(missingIndices) > 0 && !redownloadWithSuffix && (rootDir != "" || backend.GetSkipExistingISRCSetting()) {
+		libraryRoot := rootDir
+		if libraryRoot == "" {
+			libraryRoot = backend.GetLibraryRootSetting()
+		}
+		skipByISRC := backend.GetSkipExistingISRCSetting()
+
+		for _, idx := range missingIndices {
+			t := tracks[idx]
+			if existing, ok := backend.FindLibraryTrackBySpotifyID(libraryRoot, t.SpotifyID); ok {
+				results[idx].Exists = true
+				results[idx].FilePath = existing.Path
+				continue
+			}
+
+			if skipByISRC {
+				isrc := strings.TrimSpace(t.ISRC)
+				if isrc == "" && t.SpotifyID != "" {
+					isrc = backend.ResolveTrackISRC(t.SpotifyID)
+				}
+				if existing, ok := backend.FindLibraryTrackByISRC(libraryRoot, isrc); ok {
 					results[idx].Exists = true
-					results[idx].FilePath = path
-				} else {
-					results[idx].FilePath = ""
+					results[idx].FilePath = existing.Path
+					continue
 				}
 			}
-		} else {
-			for _, idx := range missingIndices {
-				results[idx].FilePath = ""
+
+			if rootDir == "" || results[idx].FilePath == "" {
+				continue
+			}
+			for _, candidate := range backend.FindLibraryTracksByFilename(libraryRoot, results[idx].FilePath) {
+				if candidate.SpotifyID != "" && t.SpotifyID != "" && candidate.SpotifyID != t.SpotifyID {
+					continue
+				}
+				if info, err := os.Stat(candidate.Path); err == nil && info.Size() > 0 {
+					results[idx].Exists = true
+					results[idx].FilePath = candidate.Path
+					break
+				}
 			}
 		}
-	} else {
-		for _, idx := range missingIndices {
+	}
+
+	for _, idx := range missingIndices {
+		if !results[idx].Exists {
 			results[idx].FilePath = ""
 		}
 	}
@@ -1749,6 +2097,55 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, audioFormat
 	return results
 }
 
+func (a *App) FindLibraryDuplicates(rootDir string, useFingerprint bool) ([]backend.DuplicateGroup, error) {
+	if rootDir == "" {
+		rootDir = backend.GetLibraryRootSetting()
+	}
+	return backend.FindLibraryDuplicates(rootDir, useFingerprint)
+}
+
+func (a *App) RefreshLibraryIndex(rootDir string) (*backend.LibraryScanStats, error) {
+	if rootDir == "" {
+		rootDir = backend.GetLibraryRootSetting()
+	}
+	return backend.RefreshLibraryIndex(rootDir)
+}
+
+func (a *App) resolveMetadataSeparator(separator string) string {
+	if separator != "" {
+		return separator
+	}
+
+	separator = ", "
+	metadataSettings, _ := a.LoadSettings()
+	if metadataSettings != nil {
+		if sep, ok := metadataSettings["separator"].(string); ok {
+			if sep == "semicolon" {
+				separator = "; "
+			} else if sep == "comma" {
+				separator = ", "
+			}
+		}
+	}
+	return separator
+}
+
+func (a *App) RetagFiles(paths []string, options backend.RetagOptions) ([]backend.RetagResult, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files provided")
+	}
+	options.Separator = a.resolveMetadataSeparator(options.Separator)
+	return backend.RetagFiles(a.ctx, paths, options, "SpotiDownloader"), nil
+}
+
+func (a *App) GetMusicBrainzCacheStats(recentLimit int) (*backend.MusicBrainzCacheStats, error) {
+	return backend.GetMusicBrainzCacheStats(recentLimit)
+}
+
+func (a *App) PurgeMusicBrainzCache(expiredOnly bool) (int, error) {
+	return backend.PurgeMusicBrainzCache(expiredOnly)
+}
+
 func (a *App) GetPreviewURL(trackID string) (string, error) {
 	return backend.GetPreviewURL(trackID)
 }
@@ -1765,7 +2162,44 @@ func (a *App) GetConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
+func (a *App) PreviewFilenameTemplate(template, separator string) (string, error) {
+	if err := backend.ValidateFilenameTemplate(template); err != nil {
+		return "", err
+	}
+	if separator == "" {
+		separator = "; "
+	}
+
+	preview := backend.BuildFilenameFromTemplate(template, backend.FilenameTemplateData{
+		Title:       "All The Stars",
+		Artist:      "Kendrick Lamar" + separator + "SZA",
+		Album:       "Black Panther",
+		AlbumArtist: "Kendrick Lamar",
+		ReleaseDate: "2018-02-09",
+		ISRC:        "USUM71801234",
+		UPC:         "00602567439500",
+		SpotifyID:   "3GCdLUSnKSMJhs4Tj6CV3s",
+		Track:       1,
+		Disc:        1,
+		TotalTracks: 14,
+		Duration:    232,
+		Explicit:    true,
+	}, false)
+	return backend.SanitizeFilename(preview), nil
+}
+
 func (a *App) SaveSettings(settings map[string]interface{}) error {
+	if template, ok := settings["filenameTemplate"].(string); ok {
+		if err := backend.ValidateFilenameTemplate(template); err != nil {
+			return fmt.Errorf("filename template: %w", err)
+		}
+	}
+	if template, ok := settings["folderTemplate"].(string); ok {
+		if err := backend.ValidateFolderTemplate(template); err != nil {
+			return fmt.Errorf("folder template: %w", err)
+		}
+	}
+
 	configPath, err := a.GetConfigPath()
 	if err != nil {
 		return err
@@ -1783,7 +2217,13 @@ func (a *App) SaveSettings(settings map[string]interface{}) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return err
+	}
+
+	backend.ReloadBandwidthSettings()
+	backend.SetDownloadWorkerCount(backend.GetDownloadWorkerSetting())
+	return nil
 }
 
 func (a *App) LoadSettings() (map[string]interface{}, error) {
@@ -1814,50 +2254,6 @@ func (a *App) CheckFFmpegInstalled() (bool, error) {
 }
 
 func (a *App) CreateM3U8File(m3u8Name string, outputDir string, filePaths []string) error {
-	if len(filePaths) == 0 {
-		return nil
-	}
-
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return err
-	}
-
-	fnName := m3u8Name
-
-	safeName := backend.SanitizeFilename(fnName)
-	if safeName == "" {
-		safeName = "playlist"
-	}
-
-	m3u8Path := filepath.Join(outputDir, safeName+".m3u8")
-
-	f, err := os.Create(m3u8Path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	if _, err := f.WriteString("#EXTM3U\n"); err != nil {
-		return err
-	}
-
-	for _, path := range filePaths {
-		if path == "" {
-			continue
-		}
-
-		relPath, err := filepath.Rel(outputDir, path)
-		if err != nil {
-
-			relPath = path
-		}
-
-		relPath = filepath.ToSlash(relPath)
-
-		if _, err := f.WriteString(relPath + "\n"); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	_, err := backend.WriteM3U8File(m3u8Name, outputDir, filePaths)
+	return err
 }
@@ -24,6 +24,8 @@ type AnalysisResult struct {
 	DynamicRange  float64 `json:"dynamic_range"`
 	PeakAmplitude float64 `json:"peak_amplitude"`
 	RMSLevel      float64 `json:"rms_level"`
+
+	Transcode *TranscodeAnalysis `json:"transcode,omitempty"`
 }
 
 type AnalysisDecodeResponse struct {
@@ -154,10 +156,18 @@ func DecodeAudioForAnalysis(filePath string) (*AnalysisDecodeResponse, error) {
 }
 
 func extractAnalysisPCMBase64(filePath string) (string, error) {
-	ffmpegPath, err := GetFFmpegPath()
+	pcm, err := extractAnalysisPCM(filePath)
 	if err != nil {
 		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(pcm), nil
+}
+
+func extractAnalysisPCM(filePath string) ([]byte, error) {
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
 
 	argSets := [][]string{
 		{
@@ -203,12 +213,12 @@ func extractAnalysisPCMBase64(filePath string) (string, error) {
 			continue
 		}
 
-		return base64.StdEncoding.EncodeToString(stdout.Bytes()), nil
+		return stdout.Bytes(), nil
 	}
 
 	if lastErr != nil {
-		return "", lastErr
+		return nil, lastErr
 	}
 
-	return "", fmt.Errorf("ffmpeg analysis decode failed")
+	return nil, fmt.Errorf("ffmpeg analysis decode failed")
 }
@@ -0,0 +1,326 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bandwidthScheduleRefresh = 15 * time.Second
+	minBandwidthChunk        = 4 * 1024
+)
+
+type DownloadWindow struct {
+	Start     int     `json:"start"`
+	End       int     `json:"end"`
+	LimitKBps float64 `json:"limit_kbps"`
+	HasLimit  bool    `json:"has_limit"`
+}
+
+type BandwidthStatus struct {
+	LimitKBps    float64 `json:"limit_kbps"`
+	WindowOpen   bool    `json:"window_open"`
+	Scheduled    bool    `json:"scheduled"`
+	NextWindowAt int64   `json:"next_window_at,omitempty"`
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+var (
+	bandwidthBucket = &tokenBucket{}
+
+	bandwidthStateMu     sync.Mutex
+	bandwidthStatus      = BandwidthStatus{WindowOpen: true}
+	bandwidthStateLoaded time.Time
+)
+
+func (b *tokenBucket) setRate(bytesPerSecond float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate == bytesPerSecond {
+		return
+	}
+	b.rate = bytesPerSecond
+	b.tokens = 0
+	b.last = time.Now()
+}
+
+func (b *tokenBucket) chunkSize(size int) int {
+	b.mu.Lock()
+	rate := b.rate
+	b.mu.Unlock()
+
+	if rate <= 0 {
+		return size
+	}
+	chunk := max(int(rate/10), minBandwidthChunk)
+	return min(size, chunk)
+}
+
+func (b *tokenBucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 || n <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type throttledReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func ThrottleReader(ctx context.Context, reader io.Reader) io.Reader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &throttledReader{ctx: ctx, reader: reader}
+}
+
+func throttleResponseBody(resp *http.Response) io.Reader {
+	ctx := context.Background()
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+	}
+	return ThrottleReader(ctx, resp.Body)
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	refreshBandwidthState(false)
+
+	n, err := t.reader.Read(p[:bandwidthBucket.chunkSize(len(p))])
+	if delay := bandwidthBucket.reserve(n); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-t.ctx.Done():
+			return n, context.Cause(t.ctx)
+		}
+	}
+	return n, err
+}
+
+func parseClockMinutes(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid minute in %q", value)
+	}
+	return h*60 + m, nil
+}
+
+func ParseDownloadWindow(value string) (DownloadWindow, error) {
+	var window DownloadWindow
+
+	span, limit, hasLimit := strings.Cut(strings.TrimSpace(value), "@")
+	start, end, ok := strings.Cut(span, "-")
+	if !ok {
+		return window, fmt.Errorf("invalid download window %q, expected HH:MM-HH:MM", value)
+	}
+
+	var err error
+	if window.Start, err = parseClockMinutes(start); err != nil {
+		return window, err
+	}
+	if window.End, err = parseClockMinutes(end); err != nil {
+		return window, err
+	}
+	if hasLimit {
+		window.HasLimit = true
+		limit = strings.TrimSpace(limit)
+		if strings.EqualFold(limit, "unlimited") {
+			return window, nil
+		}
+		window.LimitKBps, err = strconv.ParseFloat(limit, 64)
+		if err != nil || window.LimitKBps < 0 {
+			return window, fmt.Errorf("invalid bandwidth limit in %q", value)
+		}
+	}
+	return window, nil
+}
+
+func parseDownloadWindows(value interface{}) []DownloadWindow {
+	var entries []string
+	switch v := value.(type) {
+	case string:
+		entries = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' || r == '\n' })
+	case []interface{}:
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				entries = append(entries, s)
+			}
+		}
+	}
+
+	var windows []DownloadWindow
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		window, err := ParseDownloadWindow(entry)
+		if err != nil {
+			fmt.Printf("[Bandwidth] Ignoring download window: %v\n", err)
+			continue
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+func (w DownloadWindow) contains(minute int) bool {
+	switch {
+	case w.Start == w.End:
+		return true
+	case w.Start < w.End:
+		return minute >= w.Start && minute < w.End
+	default:
+		return minute >= w.Start || minute < w.End
+	}
+}
+
+func evaluateBandwidthSchedule(now time.Time, windows []DownloadWindow, limitKBps float64) BandwidthStatus {
+	status := BandwidthStatus{LimitKBps: limitKBps, WindowOpen: true, Scheduled: len(windows) > 0}
+	if len(windows) == 0 {
+		return status
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	for _, window := range windows {
+		if window.contains(minute) {
+			if window.HasLimit {
+				status.LimitKBps = window.LimitKBps
+			}
+			return status
+		}
+	}
+
+	status.WindowOpen = false
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, window := range windows {
+		start := midnight.Add(time.Duration(window.Start) * time.Minute)
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if status.NextWindowAt == 0 || start.Unix() < status.NextWindowAt {
+			status.NextWindowAt = start.Unix()
+		}
+	}
+	return status
+}
+
+func loadBandwidthSchedule() ([]DownloadWindow, float64) {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return nil, 0
+	}
+
+	limit, _ := settings["bandwidthLimit"].(float64)
+	if limit < 0 {
+		limit = 0
+	}
+	return parseDownloadWindows(settings["downloadWindows"]), limit
+}
+
+func refreshBandwidthState(force bool) BandwidthStatus {
+	bandwidthStateMu.Lock()
+	if !force && time.Since(bandwidthStateLoaded) < bandwidthScheduleRefresh {
+		status := bandwidthStatus
+		bandwidthStateMu.Unlock()
+		return status
+	}
+
+	windows, limit := loadBandwidthSchedule()
+	status := evaluateBandwidthSchedule(time.Now(), windows, limit)
+	previous := bandwidthStatus
+	bandwidthStatus = status
+	bandwidthStateLoaded = time.Now()
+	bandwidthStateMu.Unlock()
+
+	bandwidthBucket.setRate(status.LimitKBps * 1024)
+
+	if previous.WindowOpen != status.WindowOpen {
+		if status.WindowOpen {
+			fmt.Println("[Bandwidth] Download window opened, resuming queue")
+			jobQueueMu.Lock()
+			jobQueueCond.Broadcast()
+			jobQueueMu.Unlock()
+		} else {
+			fmt.Printf("[Bandwidth] Outside download windows, queue paused until %s\n", time.Unix(status.NextWindowAt, 0).Format("15:04"))
+		}
+	}
+	if previous.LimitKBps != status.LimitKBps {
+		if status.LimitKBps > 0 {
+			fmt.Printf("[Bandwidth] Limit set to %.0f KB/s\n", status.LimitKBps)
+		} else {
+			fmt.Println("[Bandwidth] Limit removed")
+		}
+	}
+	if previous != status {
+		EmitEvent(EventDownloadBandwidth, status)
+	}
+
+	return status
+}
+
+func bandwidthWindowOpen() bool {
+	bandwidthStateMu.Lock()
+	defer bandwidthStateMu.Unlock()
+	return bandwidthStatus.WindowOpen
+}
+
+func refreshBandwidthSchedule() BandwidthStatus {
+	return refreshBandwidthState(false)
+}
+
+func GetBandwidthStatus() BandwidthStatus {
+	return refreshBandwidthSchedule()
+}
+
+func ReloadBandwidthSettings() BandwidthStatus {
+	return refreshBandwidthState(true)
+}
+
+func watchDownloadWindows(stop chan struct{}) {
+	ticker := time.NewTicker(bandwidthScheduleRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			refreshBandwidthState(true)
+		}
+	}
+}
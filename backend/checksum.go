@@ -0,0 +1,290 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-flac/go-flac"
+)
+
+const (
+	VerifyStatusOK         = "ok"
+	VerifyStatusTagsEdited = "tags_edited"
+	VerifyStatusModified   = "modified"
+	VerifyStatusTruncated  = "truncated"
+	VerifyStatusCorrupted  = "corrupted"
+	VerifyStatusMissing    = "missing"
+	VerifyStatusUntracked  = "untracked"
+	VerifyStatusUnreadable = "unreadable"
+
+	verifyDurationTolerance = 0.5
+)
+
+type AudioChecksum struct {
+	PCMMD5        string   `json:"pcm_md5"`
+	StreamInfoMD5 string   `json:"streaminfo_md5,omitempty"`
+	FileSize      int64    `json:"file_size"`
+	Duration      float64  `json:"duration"`
+	DecodeErrors  []string `json:"decode_errors,omitempty"`
+}
+
+type VerifyResult struct {
+	Path             string         `json:"path"`
+	Status           string         `json:"status"`
+	Details          string         `json:"details,omitempty"`
+	HistoryID        string         `json:"history_id,omitempty"`
+	Expected         *AudioChecksum `json:"expected,omitempty"`
+	Actual           *AudioChecksum `json:"actual,omitempty"`
+	StreamInfoIntact bool           `json:"streaminfo_intact"`
+}
+
+func readFlacStreamInfo(filePath string) (*flac.StreamInfoBlock, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := flac.ParseMetadata(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLAC metadata: %w", err)
+	}
+	return f.GetStreamInfo()
+}
+
+func pcmCodecForBitDepth(bits int) string {
+	switch {
+	case bits <= 0:
+		return "pcm_s16le"
+	case bits <= 8:
+		return "pcm_s8"
+	case bits <= 16:
+		return "pcm_s16le"
+	case bits <= 24:
+		return "pcm_s24le"
+	default:
+		return "pcm_s32le"
+	}
+}
+
+func ComputeAudioChecksum(filePath string) (*AudioChecksum, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := &AudioChecksum{FileSize: info.Size()}
+	bitDepth := 16
+
+	if strings.EqualFold(filepath.Ext(filePath), ".flac") {
+		streamInfo, err := readFlacStreamInfo(filePath)
+		if err != nil {
+			return nil, err
+		}
+		bitDepth = streamInfo.BitDepth
+		if !bytes.Equal(streamInfo.AudioMD5, make([]byte, len(streamInfo.AudioMD5))) {
+			checksum.StreamInfoMD5 = hex.EncodeToString(streamInfo.AudioMD5)
+		}
+		if streamInfo.SampleRate > 0 {
+			checksum.Duration = float64(streamInfo.SampleCount) / float64(streamInfo.SampleRate)
+		}
+	}
+
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-c:a", pcmCodecForBitDepth(bitDepth),
+		"-f", "md5",
+		"-",
+	)
+	setHideWindow(cmd)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode failed: %v - %s", err, lastLines(stderr.String(), 5))
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	if !strings.HasPrefix(output, "MD5=") {
+		return nil, fmt.Errorf("unexpected ffmpeg md5 output: %q", output)
+	}
+	checksum.PCMMD5 = strings.TrimPrefix(output, "MD5=")
+
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			checksum.DecodeErrors = append(checksum.DecodeErrors, line)
+		}
+	}
+
+	if checksum.Duration == 0 {
+		if duration, err := GetAudioDuration(filePath); err == nil {
+			checksum.Duration = duration
+		}
+	}
+
+	return checksum, nil
+}
+
+func (item HistoryItem) checksum() *AudioChecksum {
+	if item.PCMMD5 == "" {
+		return nil
+	}
+	return &AudioChecksum{
+		PCMMD5:        item.PCMMD5,
+		StreamInfoMD5: item.StreamInfoMD5,
+		FileSize:      item.FileSize,
+		Duration:      item.DurationSeconds,
+	}
+}
+
+func (item *HistoryItem) SetChecksum(checksum *AudioChecksum) {
+	if checksum == nil {
+		return
+	}
+	item.PCMMD5 = checksum.PCMMD5
+	item.StreamInfoMD5 = checksum.StreamInfoMD5
+	item.FileSize = checksum.FileSize
+	item.DurationSeconds = math.Round(checksum.Duration*1000) / 1000
+}
+
+func verifyAgainst(result *VerifyResult, expected, actual *AudioChecksum) {
+	result.Expected = expected
+	result.Actual = actual
+	result.StreamInfoIntact = actual.StreamInfoMD5 == "" || actual.StreamInfoMD5 == actual.PCMMD5
+
+	switch {
+	case !result.StreamInfoIntact:
+		result.Status = VerifyStatusCorrupted
+		result.Details = "decoded audio does not match the FLAC STREAMINFO MD5"
+		return
+	case len(actual.DecodeErrors) > 0:
+		result.Status = VerifyStatusCorrupted
+		result.Details = actual.DecodeErrors[0]
+		return
+	}
+
+	if expected == nil {
+		result.Status = VerifyStatusUntracked
+		result.Details = "no checksum recorded in download history"
+		return
+	}
+
+	switch {
+	case expected.Duration > 0 && actual.Duration < expected.Duration-verifyDurationTolerance:
+		result.Status = VerifyStatusTruncated
+		result.Details = fmt.Sprintf("duration %.2fs, expected %.2fs", actual.Duration, expected.Duration)
+	case actual.PCMMD5 != expected.PCMMD5:
+		result.Status = VerifyStatusModified
+		result.Details = "audio was re-encoded or edited since download"
+		if expected.StreamInfoMD5 != "" && actual.StreamInfoMD5 != "" && expected.StreamInfoMD5 != actual.StreamInfoMD5 {
+			result.Details = "FLAC was re-encoded from different audio since download"
+		}
+	case actual.FileSize != expected.FileSize:
+		result.Status = VerifyStatusTagsEdited
+		result.Details = "audio is identical, metadata or artwork changed"
+	default:
+		result.Status = VerifyStatusOK
+	}
+}
+
+func VerifyLibrary(dir string, appName string) ([]VerifyResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	items, err := GetHistoryItems(appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	root := filepath.Clean(dir)
+	recorded := make(map[string]HistoryItem)
+	for _, item := range items {
+		if item.Path == "" || item.PCMMD5 == "" {
+			continue
+		}
+		path := filepath.Clean(item.Path)
+		if _, ok := recorded[path]; !ok {
+			recorded[path] = item
+		}
+	}
+
+	files, err := ListAudioFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VerifyResult, 0, len(files))
+	seen := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		path := filepath.Clean(file.Path)
+		seen[path] = true
+
+		result := VerifyResult{Path: path}
+		var expected *AudioChecksum
+		if item, ok := recorded[path]; ok {
+			result.HistoryID = item.ID
+			expected = item.checksum()
+		}
+
+		actual, err := ComputeAudioChecksum(path)
+		if err != nil {
+			result.Status = VerifyStatusUnreadable
+			result.Details = err.Error()
+			result.Expected = expected
+			results = append(results, result)
+			continue
+		}
+
+		verifyAgainst(&result, expected, actual)
+		if result.Status != VerifyStatusOK {
+			fmt.Printf("[Verify] %s: %s\n", filepath.Base(path), result.Status)
+		}
+		results = append(results, result)
+	}
+
+	for path, item := range recorded {
+		if seen[path] {
+			continue
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		results = append(results, VerifyResult{
+			Path:      path,
+			Status:    VerifyStatusMissing,
+			Details:   "file recorded in download history no longer exists",
+			HistoryID: item.ID,
+			Expected:  item.checksum(),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	return results, nil
+}
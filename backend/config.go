@@ -49,6 +49,29 @@ func LoadConfigSettings() (map[string]interface{}, error) {
 	return settings, nil
 }
 
+func SaveConfigSetting(key string, value interface{}) error {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	settings, err := LoadConfigSettings()
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	settings[key] = value
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configPath, data, 0644)
+}
+
 func GetRedownloadWithSuffixSetting() bool {
 	settings, err := LoadConfigSettings()
 	if err != nil || settings == nil {
@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
@@ -56,58 +55,15 @@ func NewCoverClient() *CoverClient {
 }
 
 func buildCoverFilename(trackName, artistName, albumName, albumArtist, releaseDate, filenameFormat string, includeTrackNumber bool, position, discNumber int) string {
-	safeTitle := SanitizeFilename(trackName)
-	safeArtist := SanitizeFilename(artistName)
-	safeAlbum := SanitizeFilename(albumName)
-	safeAlbumArtist := SanitizeFilename(albumArtist)
-
-	year := ""
-	if len(releaseDate) >= 4 {
-		year = releaseDate[:4]
-	}
-
-	var filename string
-
-	if strings.Contains(filenameFormat, "{") {
-		filename = filenameFormat
-		filename = strings.ReplaceAll(filename, "{title}", safeTitle)
-		filename = strings.ReplaceAll(filename, "{artist}", safeArtist)
-		filename = strings.ReplaceAll(filename, "{album}", safeAlbum)
-		filename = strings.ReplaceAll(filename, "{album_artist}", safeAlbumArtist)
-		filename = strings.ReplaceAll(filename, "{year}", year)
-		filename = strings.ReplaceAll(filename, "{date}", SanitizeFilename(releaseDate))
-
-		if discNumber > 0 {
-			filename = strings.ReplaceAll(filename, "{disc}", fmt.Sprintf("%d", discNumber))
-		} else {
-			filename = strings.ReplaceAll(filename, "{disc}", "")
-		}
-
-		if position > 0 {
-			filename = strings.ReplaceAll(filename, "{track}", fmt.Sprintf("%02d", position))
-		} else {
-
-			filename = regexp.MustCompile(`\{track\}\.\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*-\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*`).ReplaceAllString(filename, "")
-		}
-	} else {
-
-		switch filenameFormat {
-		case "artist-title":
-			filename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
-		case "title":
-			filename = safeTitle
-		default:
-			filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-		}
-
-		if includeTrackNumber && position > 0 {
-			filename = fmt.Sprintf("%02d. %s", position, filename)
-		}
-	}
-
-	return filename + ".jpg"
+	return BuildFilenameFromTemplate(filenameFormat, FilenameTemplateData{
+		Title:       trackName,
+		Artist:      artistName,
+		Album:       albumName,
+		AlbumArtist: albumArtist,
+		ReleaseDate: releaseDate,
+		Track:       position,
+		Disc:        discNumber,
+	}, includeTrackNumber) + ".jpg"
 }
 
 func convertSmallToMedium(imageURL string) string {
@@ -152,7 +108,7 @@ func (c *CoverClient) DownloadCoverToPath(coverURL, outputPath string, embedMaxQ
 	}
 	defer file.Close()
 
-	if _, err := io.Copy(file, resp.Body); err != nil {
+	if _, err := io.Copy(file, throttleResponseBody(resp)); err != nil {
 		return fmt.Errorf("failed to write cover file: %v", err)
 	}
 
@@ -287,7 +243,7 @@ func (c *CoverClient) DownloadCover(req CoverDownloadRequest) (*CoverDownloadRes
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(file, throttleResponseBody(resp))
 	if err != nil {
 		return &CoverDownloadResponse{
 			Success: false,
@@ -383,7 +339,7 @@ func (c *CoverClient) DownloadHeader(req HeaderDownloadRequest) (*HeaderDownload
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(file, throttleResponseBody(resp))
 	if err != nil {
 		return &HeaderDownloadResponse{
 			Success: false,
@@ -480,7 +436,7 @@ func (c *CoverClient) DownloadGalleryImage(req GalleryImageDownloadRequest) (*Ga
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(file, throttleResponseBody(resp))
 	if err != nil {
 		return &GalleryImageDownloadResponse{
 			Success: false,
@@ -576,7 +532,7 @@ func (c *CoverClient) DownloadAvatar(req AvatarDownloadRequest) (*AvatarDownload
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(file, throttleResponseBody(resp))
 	if err != nil {
 		return &AvatarDownloadResponse{
 			Success: false,
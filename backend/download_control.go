@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	ErrDownloadPaused    = errors.New("download paused")
+	ErrDownloadCancelled = errors.New("download cancelled")
+)
+
+var (
+	itemContexts   = make(map[string]context.CancelCauseFunc)
+	itemContextsMu sync.Mutex
+)
+
+func AcquireDownloadItemContext(id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	itemContextsMu.Lock()
+	itemContexts[id] = cancel
+	itemContextsMu.Unlock()
+
+	release := func() {
+		itemContextsMu.Lock()
+		delete(itemContexts, id)
+		itemContextsMu.Unlock()
+		cancel(nil)
+
+		if status, ok := GetDownloadItemStatus(id); ok && status == StatusCancelled {
+			removePartialDownloadsForItem(id)
+		}
+	}
+
+	return ctx, release
+}
+
+func cancelDownloadItemContext(id string, cause error) bool {
+	itemContextsMu.Lock()
+	cancel, ok := itemContexts[id]
+	itemContextsMu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+func DownloadStopReason(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrDownloadPaused) || errors.Is(cause, ErrDownloadCancelled) {
+		return cause
+	}
+	return nil
+}
+
+func PauseDownloadItem(id string) error {
+	status, ok := GetDownloadItemStatus(id)
+	if !ok {
+		return fmt.Errorf("download item not found: %s", id)
+	}
+
+	switch status {
+	case StatusQueued:
+		job, ok := takePendingDownloadJob(id)
+		if !ok {
+			return fmt.Errorf("download item %s is not managed by the download queue", id)
+		}
+		HoldPausedDownloadJob(job)
+		setDownloadItemStatus(id, StatusPaused, "")
+	case StatusDownloading:
+		setDownloadItemStatus(id, StatusPaused, "")
+		if !cancelDownloadItemContext(id, ErrDownloadPaused) {
+			setDownloadItemStatus(id, StatusDownloading, "")
+			return fmt.Errorf("download item %s cannot be paused", id)
+		}
+	default:
+		return fmt.Errorf("cannot pause a %s download", status)
+	}
+
+	fmt.Printf("[DownloadControl] Paused %s\n", id)
+	return nil
+}
+
+func ResumeDownloadItem(id string) error {
+	status, ok := GetDownloadItemStatus(id)
+	if !ok {
+		return fmt.Errorf("download item not found: %s", id)
+	}
+	if status != StatusPaused {
+		return fmt.Errorf("cannot resume a %s download", status)
+	}
+
+	job, ok := takePausedDownloadJob(id)
+	if !ok {
+		return fmt.Errorf("download item %s has no saved request to resume", id)
+	}
+
+	setDownloadItemStatus(id, StatusQueued, "")
+	requeueDownloadJob(job)
+
+	fmt.Printf("[DownloadControl] Resumed %s\n", id)
+	return nil
+}
+
+func CancelDownloadItem(id string) error {
+	status, ok := GetDownloadItemStatus(id)
+	if !ok {
+		return fmt.Errorf("download item not found: %s", id)
+	}
+
+	switch status {
+	case StatusQueued:
+		takePendingDownloadJob(id)
+		setDownloadItemStatus(id, StatusCancelled, "Cancelled")
+		if err := removePersistedDownloadJobs(id); err != nil {
+			fmt.Printf("[DownloadControl] Failed to remove job %s: %v\n", id, err)
+		}
+	case StatusPaused:
+		takePausedDownloadJob(id)
+		setDownloadItemStatus(id, StatusCancelled, "Cancelled")
+		if err := removePersistedDownloadJobs(id); err != nil {
+			fmt.Printf("[DownloadControl] Failed to remove job %s: %v\n", id, err)
+		}
+		removePartialDownloadsForItem(id)
+	case StatusDownloading:
+		setDownloadItemStatus(id, StatusCancelled, "Cancelled")
+		cancelDownloadItemContext(id, ErrDownloadCancelled)
+	default:
+		return fmt.Errorf("cannot cancel a %s download", status)
+	}
+
+	fmt.Printf("[DownloadControl] Cancelled %s\n", id)
+	return nil
+}
+
+func removePartialDownloadsForItem(id string) {
+	entries, err := loadPartialDownloads()
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.ItemID != id {
+			continue
+		}
+		if err := os.Remove(entry.PartPath); err == nil {
+			fmt.Printf("Removed partial download: %s\n", entry.PartPath)
+		}
+		unregisterPartialDownload(entry.PartPath)
+	}
+}
@@ -0,0 +1,459 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	downloadQueueDBFile    = "download_queue.db"
+	downloadQueueJobBucket = "DownloadJobs"
+	defaultDownloadWorkers = 3
+	maxDownloadWorkers     = 10
+)
+
+type DownloadJob struct {
+	ID         string          `json:"id"`
+	SpotifyID  string          `json:"spotify_id"`
+	TrackName  string          `json:"track_name"`
+	ArtistName string          `json:"artist_name"`
+	AlbumName  string          `json:"album_name"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  int64           `json:"created_at"`
+}
+
+type DownloadJobHandler func(job DownloadJob) error
+
+var (
+	downloadQueueDB   *bolt.DB
+	downloadQueueDBMu sync.Mutex
+
+	jobQueueMu      sync.Mutex
+	jobQueueCond    = sync.NewCond(&jobQueueMu)
+	jobQueuePending []DownloadJob
+	jobQueuePaused  = make(map[string]DownloadJob)
+	jobQueueFailed  = make(map[string]DownloadJob)
+	jobQueueHandler DownloadJobHandler
+	jobQueueRunning bool
+	jobQueueWorkers int
+	jobQueueDesired int
+	jobQueueWindows chan struct{}
+)
+
+func InitDownloadQueueDB() error {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB != nil {
+		return nil
+	}
+
+	appDir, err := EnsureAppDir()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(appDir, downloadQueueDBFile)
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(downloadQueueJobBucket))
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+
+	downloadQueueDB = db
+	return nil
+}
+
+func CloseDownloadQueueDB() {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB != nil {
+		_ = downloadQueueDB.Close()
+		downloadQueueDB = nil
+	}
+}
+
+func persistDownloadJob(job DownloadJob) error {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode download job: %w", err)
+	}
+
+	return downloadQueueDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(downloadQueueJobBucket))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(job.ID), payload)
+	})
+}
+
+func removePersistedDownloadJobs(ids ...string) error {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil || len(ids) == 0 {
+		return nil
+	}
+
+	return downloadQueueDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(downloadQueueJobBucket))
+		if bucket == nil {
+			return nil
+		}
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func loadPersistedDownloadJobs() ([]DownloadJob, error) {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil {
+		return nil, nil
+	}
+
+	var jobs []DownloadJob
+	err := downloadQueueDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(downloadQueueJobBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var job DownloadJob
+			if err := json.Unmarshal(v, &job); err != nil {
+				fmt.Printf("[DownloadQueue] Dropping unreadable job %s: %v\n", string(k), err)
+				return nil
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortDownloadJobs(jobs)
+	return jobs, nil
+}
+
+func sortDownloadJobs(jobs []DownloadJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt < jobs[j].CreatedAt
+	})
+}
+
+func GetDownloadWorkerSetting() int {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return defaultDownloadWorkers
+	}
+
+	workers, ok := settings["downloadWorkers"].(float64)
+	if !ok {
+		return defaultDownloadWorkers
+	}
+
+	return clampDownloadWorkers(int(workers))
+}
+
+func clampDownloadWorkers(workers int) int {
+	if workers < 1 {
+		return 1
+	}
+	if workers > maxDownloadWorkers {
+		return maxDownloadWorkers
+	}
+	return workers
+}
+
+func GetDownloadWorkerCount() int {
+	jobQueueMu.Lock()
+	defer jobQueueMu.Unlock()
+	return jobQueueDesired
+}
+
+func StartDownloadQueue(handler DownloadJobHandler, workers int) error {
+	jobQueueMu.Lock()
+	if jobQueueRunning {
+		jobQueueMu.Unlock()
+		return nil
+	}
+	jobQueueHandler = handler
+	jobQueueRunning = true
+	jobQueueWindows = make(chan struct{})
+	go watchDownloadWindows(jobQueueWindows)
+	jobQueueMu.Unlock()
+
+	ReloadBandwidthSettings()
+	SetDownloadWorkerCount(workers)
+
+	jobs, err := loadPersistedDownloadJobs()
+	if err != nil {
+		return fmt.Errorf("failed to restore download queue: %w", err)
+	}
+	if len(jobs) > 0 {
+		fmt.Printf("[DownloadQueue] Restoring %d unfinished job(s)\n", len(jobs))
+		enqueueDownloadJobs(jobs, false)
+	}
+
+	return nil
+}
+
+func StopDownloadQueue() {
+	jobQueueMu.Lock()
+	jobQueueRunning = false
+	jobQueuePending = nil
+	jobQueuePaused = make(map[string]DownloadJob)
+	if jobQueueWindows != nil {
+		close(jobQueueWindows)
+		jobQueueWindows = nil
+	}
+	jobQueueCond.Broadcast()
+	jobQueueMu.Unlock()
+}
+
+func SetDownloadWorkerCount(workers int) {
+	workers = clampDownloadWorkers(workers)
+
+	jobQueueMu.Lock()
+	defer jobQueueMu.Unlock()
+
+	jobQueueDesired = workers
+	if !jobQueueRunning {
+		return
+	}
+	for jobQueueWorkers < jobQueueDesired {
+		jobQueueWorkers++
+		go downloadQueueWorker()
+	}
+	jobQueueCond.Broadcast()
+}
+
+func EnqueueDownloadJobs(jobs []DownloadJob) []string {
+	return enqueueDownloadJobs(jobs, true)
+}
+
+func enqueueDownloadJobs(jobs []DownloadJob, persist bool) []string {
+	ids := make([]string, 0, len(jobs))
+	now := time.Now().UnixNano()
+
+	for i := range jobs {
+		job := &jobs[i]
+		if job.ID == "" {
+			job.ID = fmt.Sprintf("%s-%d", job.SpotifyID, now+int64(i))
+		}
+		if job.CreatedAt == 0 {
+			job.CreatedAt = now + int64(i)
+		}
+
+		AddToQueue(job.ID, job.TrackName, job.ArtistName, job.AlbumName, job.SpotifyID)
+
+		if persist {
+			if err := persistDownloadJob(*job); err != nil {
+				fmt.Printf("[DownloadQueue] Failed to persist job %s: %v\n", job.ID, err)
+			}
+		}
+		ids = append(ids, job.ID)
+	}
+
+	jobQueueMu.Lock()
+	jobQueuePending = append(jobQueuePending, jobs...)
+	jobQueueCond.Broadcast()
+	jobQueueMu.Unlock()
+
+	return ids
+}
+
+func requeueDownloadJob(job DownloadJob) {
+	if err := persistDownloadJob(job); err != nil {
+		fmt.Printf("[DownloadQueue] Failed to persist job %s: %v\n", job.ID, err)
+	}
+
+	jobQueueMu.Lock()
+	jobQueuePending = append(jobQueuePending, job)
+	jobQueueCond.Broadcast()
+	jobQueueMu.Unlock()
+}
+
+func takePendingDownloadJob(id string) (DownloadJob, bool) {
+	jobQueueMu.Lock()
+	defer jobQueueMu.Unlock()
+
+	for i, job := range jobQueuePending {
+		if job.ID == id {
+			jobQueuePending = append(jobQueuePending[:i], jobQueuePending[i+1:]...)
+			return job, true
+		}
+	}
+	return DownloadJob{}, false
+}
+
+func HoldPausedDownloadJob(job DownloadJob) {
+	if err := persistDownloadJob(job); err != nil {
+		fmt.Printf("[DownloadQueue] Failed to persist paused job %s: %v\n", job.ID, err)
+	}
+
+	jobQueueMu.Lock()
+	jobQueuePaused[job.ID] = job
+	jobQueueMu.Unlock()
+}
+
+func takePausedDownloadJob(id string) (DownloadJob, bool) {
+	jobQueueMu.Lock()
+	defer jobQueueMu.Unlock()
+
+	job, ok := jobQueuePaused[id]
+	if ok {
+		delete(jobQueuePaused, id)
+	}
+	return job, ok
+}
+
+func RecordFailedDownloadJob(job DownloadJob) {
+	if job.CreatedAt == 0 {
+		job.CreatedAt = time.Now().UnixNano()
+	}
+
+	jobQueueMu.Lock()
+	jobQueueFailed[job.ID] = job
+	jobQueueMu.Unlock()
+}
+
+func forgetFailedDownloadJobs() {
+	jobQueueMu.Lock()
+	jobQueueFailed = make(map[string]DownloadJob)
+	jobQueueMu.Unlock()
+}
+
+func RetryFailedDownloads() int {
+	jobQueueMu.Lock()
+	jobs := make([]DownloadJob, 0, len(jobQueueFailed))
+	for _, job := range jobQueueFailed {
+		jobs = append(jobs, job)
+	}
+	jobQueueMu.Unlock()
+
+	sortDownloadJobs(jobs)
+
+	retried := 0
+	for _, job := range jobs {
+		if !resetDownloadItemForRetry(job.ID) {
+			continue
+		}
+
+		jobQueueMu.Lock()
+		delete(jobQueueFailed, job.ID)
+		jobQueueMu.Unlock()
+
+		requeueDownloadJob(job)
+		retried++
+	}
+
+	if retried > 0 {
+		fmt.Printf("[DownloadQueue] Re-enqueued %d failed download(s)\n", retried)
+	}
+	return retried
+}
+
+func clearPendingDownloadJobs() {
+	jobQueueMu.Lock()
+	ids := make([]string, 0, len(jobQueuePending)+len(jobQueuePaused))
+	for _, job := range jobQueuePending {
+		ids = append(ids, job.ID)
+	}
+	for id := range jobQueuePaused {
+		ids = append(ids, id)
+	}
+	jobQueuePending = nil
+	jobQueuePaused = make(map[string]DownloadJob)
+	jobQueueFailed = make(map[string]DownloadJob)
+	jobQueueMu.Unlock()
+
+	if err := removePersistedDownloadJobs(ids...); err != nil {
+		fmt.Printf("[DownloadQueue] Failed to remove cancelled jobs: %v\n", err)
+	}
+}
+
+func nextDownloadJob() (DownloadJob, bool) {
+	refreshBandwidthSchedule()
+
+	jobQueueMu.Lock()
+	defer jobQueueMu.Unlock()
+
+	for {
+		if !jobQueueRunning || jobQueueWorkers > jobQueueDesired {
+			jobQueueWorkers--
+			return DownloadJob{}, false
+		}
+		if len(jobQueuePending) > 0 && bandwidthWindowOpen() {
+			job := jobQueuePending[0]
+			jobQueuePending = jobQueuePending[1:]
+			return job, true
+		}
+		jobQueueCond.Wait()
+	}
+}
+
+func downloadQueueWorker() {
+	for {
+		job, ok := nextDownloadJob()
+		if !ok {
+			return
+		}
+
+		jobQueueMu.Lock()
+		handler := jobQueueHandler
+		jobQueueMu.Unlock()
+
+		if handler == nil {
+			FailDownloadItem(job.ID, "download queue has no handler")
+		} else if err := runDownloadJob(handler, job); err != nil {
+			fmt.Printf("[DownloadQueue] Job %s failed: %v\n", job.ID, err)
+		}
+
+		if status, ok := GetDownloadItemStatus(job.ID); ok && status == StatusPaused {
+			continue
+		}
+		if err := removePersistedDownloadJobs(job.ID); err != nil {
+			fmt.Printf("[DownloadQueue] Failed to remove finished job %s: %v\n", job.ID, err)
+		}
+	}
+}
+
+func runDownloadJob(handler DownloadJobHandler, job DownloadJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("download job panicked: %v", r)
+			FailDownloadItem(job.ID, err.Error())
+		}
+	}()
+
+	return handler(job)
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventDownloadItemAdded     = "download:item-added"
+	EventDownloadItemStarted   = "download:item-started"
+	EventDownloadItemUpdated   = "download:item-updated"
+	EventDownloadProgress      = "download:progress"
+	EventDownloadItemCompleted = "download:item-completed"
+	EventDownloadItemFailed    = "download:item-failed"
+	EventDownloadQueueCleared  = "download:queue-cleared"
+	EventDownloadSessionReset  = "download:session-reset"
+	EventDownloadBandwidth     = "download:bandwidth"
+
+	progressEventInterval = 250 * time.Millisecond
+)
+
+type DownloadProgressEvent struct {
+	ItemID   string       `json:"item_id,omitempty"`
+	Progress float64      `json:"progress"`
+	Speed    float64      `json:"speed"`
+	Overall  ProgressInfo `json:"overall"`
+}
+
+type EventListener func(name string, data interface{})
+
+var (
+	eventListeners      = make(map[int]EventListener)
+	eventListenersLock  sync.RWMutex
+	nextEventListenerID int
+)
+
+func SubscribeEvents(listener EventListener) func() {
+	eventListenersLock.Lock()
+	id := nextEventListenerID
+	nextEventListenerID++
+	eventListeners[id] = listener
+	eventListenersLock.Unlock()
+
+	return func() {
+		eventListenersLock.Lock()
+		delete(eventListeners, id)
+		eventListenersLock.Unlock()
+	}
+}
+
+func HasEventListeners() bool {
+	eventListenersLock.RLock()
+	defer eventListenersLock.RUnlock()
+	return len(eventListeners) > 0
+}
+
+func EmitEvent(name string, data interface{}) {
+	eventListenersLock.RLock()
+	if len(eventListeners) == 0 {
+		eventListenersLock.RUnlock()
+		return
+	}
+	listeners := make([]EventListener, 0, len(eventListeners))
+	for _, listener := range eventListeners {
+		listeners = append(listeners, listener)
+	}
+	eventListenersLock.RUnlock()
+
+	for _, listener := range listeners {
+		listener(name, data)
+	}
+}
+
+func IsDownloadEvent(name string) bool {
+	return strings.HasPrefix(name, "download:")
+}
+
+func emitDownloadItemEvent(name string, item DownloadItem, ok bool) {
+	if ok {
+		EmitEvent(name, item)
+	}
+}
+
+func emitDownloadProgressEvent(itemID string, progress, speed float64) {
+	if !HasEventListeners() {
+		return
+	}
+	EmitEvent(EventDownloadProgress, DownloadProgressEvent{
+		ItemID:   itemID,
+		Progress: progress,
+		Speed:    speed,
+		Overall:  GetDownloadProgress(),
+	})
+}
@@ -323,9 +323,10 @@ func downloadAndExtract(url, destDir string, progressCallback func(int), progres
 		fmt.Printf("[FFmpeg] Downloading... (size unknown)\n")
 	}
 
+	body := throttleResponseBody(resp)
 	buf := make([]byte, 32*1024)
 	for {
-		n, err := resp.Body.Read(buf)
+		n, err := body.Read(buf)
 		if n > 0 {
 			_, writeErr := tmpFile.Write(buf[:n])
 			if writeErr != nil {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -32,6 +33,19 @@ type AudioMetadata struct {
 	Year        string `json:"year"`
 	ISRC        string `json:"isrc"`
 	UPC         string `json:"upc"`
+	Genre       string `json:"genre,omitempty"`
+	SpotifyID   string `json:"spotify_id,omitempty"`
+
+	Provenance Provenance `json:"provenance"`
+}
+
+var spotifyTrackIDPattern = regexp.MustCompile(`(?:open\.spotify\.com/(?:intl-[A-Za-z-]+/)?track/|spotify:track:)([A-Za-z0-9]{22})`)
+
+func findSpotifyTrackIDInText(text string) string {
+	if match := spotifyTrackIDPattern.FindStringSubmatch(text); len(match) == 2 {
+		return match[1]
+	}
+	return ""
 }
 
 type RenamePreview struct {
@@ -183,11 +197,27 @@ func readFlacMetadata(filePath string) (*AudioMetadata, error) {
 					assignPreferredUPC(&metadata.UPC, value, true)
 				case "BARCODE":
 					assignPreferredUPC(&metadata.UPC, value, false)
+				case "GENRE":
+					if metadata.Genre != "" {
+						metadata.Genre += "; " + value
+					} else {
+						metadata.Genre = value
+					}
+				case "COMMENT", "DESCRIPTION", "URL", "WEBSITE":
+					if metadata.SpotifyID == "" {
+						metadata.SpotifyID = findSpotifyTrackIDInText(value)
+					}
+				default:
+					applyProvenanceField(&metadata.Provenance, fieldName, value)
 				}
 			}
 		}
 	}
 
+	if metadata.Provenance.SpotifyTrackID != "" {
+		metadata.SpotifyID = metadata.Provenance.SpotifyTrackID
+	}
+
 	return metadata, nil
 }
 
@@ -203,6 +233,7 @@ func readMp3Metadata(filePath string) (*AudioMetadata, error) {
 		Artist: tag.Artist(),
 		Album:  tag.Album(),
 		Year:   tag.Year(),
+		Genre:  strings.ReplaceAll(strings.TrimRight(tag.Genre(), "\x00"), "\x00", "; "),
 	}
 
 	if frames := tag.GetFrames("TPE2"); len(frames) > 0 {
@@ -234,6 +265,17 @@ func readMp3Metadata(filePath string) (*AudioMetadata, error) {
 			metadata.ISRC = textFrame.Text
 		}
 	}
+	metadata.Provenance = readProvenanceMP3Frames(tag)
+	metadata.SpotifyID = metadata.Provenance.SpotifyTrackID
+
+	for _, frame := range tag.GetFrames(tag.CommonID("Comments")) {
+		if metadata.SpotifyID != "" {
+			break
+		}
+		if commentFrame, ok := frame.(id3v2.CommentFrame); ok {
+			metadata.SpotifyID = findSpotifyTrackIDInText(commentFrame.Text)
+		}
+	}
 	if frames := tag.GetFrames("TXXX"); len(frames) > 0 {
 		for _, frame := range frames {
 			userTextFrame, ok := frame.(id3v2.UserDefinedTextFrame)
@@ -329,9 +371,21 @@ func readMetadataWithFFprobe(filePath string) (*AudioMetadata, error) {
 			}
 		case "isrc", "tsrc":
 			metadata.ISRC = value
+		case "genre":
+			metadata.Genre = value
+		case "comment", "description", "url":
+			if metadata.SpotifyID == "" {
+				metadata.SpotifyID = findSpotifyTrackIDInText(value)
+			}
+		default:
+			applyProvenanceField(&metadata.Provenance, key, value)
 		}
 	}
 
+	if metadata.Provenance.SpotifyTrackID != "" {
+		metadata.SpotifyID = metadata.Provenance.SpotifyTrackID
+	}
+
 	metadata.UPC = firstPreferredFFprobeUPCValue(allTags)
 
 	return metadata, nil
@@ -350,32 +404,23 @@ func GenerateFilename(metadata *AudioMetadata, format string, ext string) string
 		return ""
 	}
 
-	result := format
-
-	year := metadata.Year
-	if len(year) >= 4 {
-		year = year[:4]
-	}
-
-	result = strings.ReplaceAll(result, "{title}", sanitizeFilename(metadata.Title))
-	result = strings.ReplaceAll(result, "{artist}", sanitizeFilename(metadata.Artist))
-	result = strings.ReplaceAll(result, "{album}", sanitizeFilename(metadata.Album))
-	result = strings.ReplaceAll(result, "{album_artist}", sanitizeFilename(metadata.AlbumArtist))
-	result = strings.ReplaceAll(result, "{year}", sanitizeFilename(year))
-	result = strings.ReplaceAll(result, "{date}", sanitizeFilename(metadata.Year))
-	result = strings.ReplaceAll(result, "{isrc}", sanitizeFilename(metadata.ISRC))
-
-	if metadata.TrackNumber > 0 {
-		result = strings.ReplaceAll(result, "{track}", fmt.Sprintf("%02d", metadata.TrackNumber))
-	} else {
-		result = strings.ReplaceAll(result, "{track}", "")
+	tmpl, err := ParseFilenameTemplate(format)
+	if err != nil {
+		return ""
 	}
 
-	if metadata.DiscNumber > 0 {
-		result = strings.ReplaceAll(result, "{disc}", fmt.Sprintf("%d", metadata.DiscNumber))
-	} else {
-		result = strings.ReplaceAll(result, "{disc}", "")
-	}
+	result := tmpl.Render(FilenameTemplateData{
+		Title:       metadata.Title,
+		Artist:      metadata.Artist,
+		Album:       metadata.Album,
+		AlbumArtist: metadata.AlbumArtist,
+		ReleaseDate: metadata.Year,
+		ISRC:        metadata.ISRC,
+		UPC:         metadata.UPC,
+		SpotifyID:   metadata.SpotifyID,
+		Track:       metadata.TrackNumber,
+		Disc:        metadata.DiscNumber,
+	}, "")
 
 	result = strings.TrimSpace(result)
 	result = strings.Join(strings.Fields(result), " ")
@@ -389,19 +434,20 @@ func GenerateFilename(metadata *AudioMetadata, format string, ext string) string
 	return result + ext
 }
 
-func sanitizeFilename(name string) string {
-
-	invalid := []string{"<", ">", ":", "\"", "/", "\\", "|", "?", "*"}
-	result := name
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "")
-	}
-	return strings.TrimSpace(result)
-}
-
 func PreviewRename(files []string, format string) []RenamePreview {
 	var previews []RenamePreview
 
+	if _, err := ParseFilenameTemplate(format); err != nil {
+		for _, filePath := range files {
+			previews = append(previews, RenamePreview{
+				OldPath: filePath,
+				OldName: filepath.Base(filePath),
+				Error:   err.Error(),
+			})
+		}
+		return previews
+	}
+
 	for _, filePath := range files {
 		preview := RenamePreview{
 			OldPath: filePath,
@@ -449,6 +495,16 @@ func GetFileSizes(files []string) map[string]int64 {
 func RenameFiles(files []string, format string) []RenameResult {
 	var results []RenameResult
 
+	if _, err := ParseFilenameTemplate(format); err != nil {
+		for _, filePath := range files {
+			results = append(results, RenameResult{
+				OldPath: filePath,
+				Error:   err.Error(),
+			})
+		}
+		return results
+	}
+
 	for _, filePath := range files {
 		result := RenameResult{
 			OldPath: filePath,
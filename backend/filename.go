@@ -15,69 +15,45 @@ func BuildFilename(trackName, artistName, albumName, albumArtist, releaseDate st
 }
 
 func buildFormattedFilenameBase(trackName, artistName, albumName, albumArtist, releaseDate string, discNumber int, format string, includeTrackNumber bool, position int, useAlbumTrackNumber bool, playlistName, playlistOwner string, isrc string) string {
-	safeTitle := SanitizeFilename(trackName)
-	safeArtist := SanitizeFilename(artistName)
-	safeAlbum := SanitizeFilename(albumName)
-	safeAlbumArtist := SanitizeFilename(albumArtist)
-	safeISRC := SanitizeOptionalFilename(isrc)
-
-	safePlaylist := SanitizeFilename(playlistName)
-	safeCreator := SanitizeFilename(playlistOwner)
-
-	year := ""
-	if len(releaseDate) >= 4 {
-		year = releaseDate[:4]
-	}
-
-	var filename string
-
-	if strings.Contains(format, "{") {
-		filename = format
-		filename = strings.ReplaceAll(filename, "{title}", safeTitle)
-		filename = strings.ReplaceAll(filename, "{artist}", safeArtist)
-		filename = strings.ReplaceAll(filename, "{album}", safeAlbum)
-		filename = strings.ReplaceAll(filename, "{album_artist}", safeAlbumArtist)
-		filename = strings.ReplaceAll(filename, "{year}", year)
-		filename = strings.ReplaceAll(filename, "{date}", SanitizeFilename(releaseDate))
-		filename = strings.ReplaceAll(filename, "{playlist}", safePlaylist)
-		filename = strings.ReplaceAll(filename, "{creator}", safeCreator)
-		filename = strings.ReplaceAll(filename, "{isrc}", safeISRC)
-
-		if discNumber > 0 {
-			filename = strings.ReplaceAll(filename, "{disc}", fmt.Sprintf("%d", discNumber))
-		} else {
-			filename = strings.ReplaceAll(filename, "{disc}", "")
-		}
+	return BuildFilenameFromTemplate(format, FilenameTemplateData{
+		Title:       trackName,
+		Artist:      artistName,
+		Album:       albumName,
+		AlbumArtist: albumArtist,
+		ReleaseDate: releaseDate,
+		Playlist:    playlistName,
+		Creator:     playlistOwner,
+		ISRC:        isrc,
+		Track:       position,
+		Disc:        discNumber,
+	}, includeTrackNumber)
+}
 
-		if position > 0 {
-			filename = strings.ReplaceAll(filename, "{track}", fmt.Sprintf("%02d", position))
-		} else {
+func BuildFilenameWithISRC(trackName, artistName, albumName, albumArtist, releaseDate string, discNumber int, format string, includeTrackNumber bool, position int, useAlbumTrackNumber bool, playlistName, playlistOwner, isrc string) string {
+	return buildFormattedFilenameBase(trackName, artistName, albumName, albumArtist, releaseDate, discNumber, format, includeTrackNumber, position, useAlbumTrackNumber, playlistName, playlistOwner, isrc)
+}
 
-			filename = regexp.MustCompile(`\{track\}\.\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*-\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*`).ReplaceAllString(filename, "")
+func BuildFolderPath(template string, data FilenameTemplateData) string {
+	segments := splitFolderTemplate(template)
+
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		resolved := segment
+		if strings.Contains(segment, "{") {
+			tmpl, err := ParseFilenameTemplate(segment)
+			if err != nil {
+				fmt.Printf("[Filename] %v, skipping folder\n", err)
+				continue
+			}
+			resolved = tmpl.Render(data, "Unknown")
 		}
-		return filename
-	}
-
-	switch format {
-	case "artist-title":
-		filename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
-	case "title":
-		filename = safeTitle
-	default:
-		filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-	}
-
-	if includeTrackNumber && position > 0 {
-		filename = fmt.Sprintf("%02d. %s", position, filename)
+		if strings.Trim(resolved, ". ") == "" {
+			continue
+		}
+		parts = append(parts, sanitizeFolderName(resolved))
 	}
 
-	return filename
-}
-
-func BuildFilenameWithISRC(trackName, artistName, albumName, albumArtist, releaseDate string, discNumber int, format string, includeTrackNumber bool, position int, useAlbumTrackNumber bool, playlistName, playlistOwner, isrc string) string {
-	return buildFormattedFilenameBase(trackName, artistName, albumName, albumArtist, releaseDate, discNumber, format, includeTrackNumber, position, useAlbumTrackNumber, playlistName, playlistOwner, isrc)
+	return filepath.Join(parts...)
 }
 
 func ResolveOutputPathForDownload(path string, redownloadWithSuffix bool) (string, bool) {
@@ -0,0 +1,474 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type FilenameTemplateData struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	ReleaseDate string
+	Playlist    string
+	Creator     string
+	ISRC        string
+	UPC         string
+	SpotifyID   string
+	Track       int
+	Disc        int
+	TotalTracks int
+	Duration    int
+	Explicit    bool
+}
+
+type FilenameTemplate struct {
+	nodes []templateNode
+}
+
+type templateNode struct {
+	text    string
+	token   *templateToken
+	section []templateNode
+}
+
+type templateToken struct {
+	names     []string
+	width     int
+	zeroPad   bool
+	truncate  int
+	transform string
+}
+
+type templateTokenDef struct {
+	value        func(FilenameTemplateData) string
+	numeric      bool
+	defaultWidth int
+	placeholder  bool
+}
+
+var filenameTemplateTokens = map[string]templateTokenDef{
+	"title":        {value: func(d FilenameTemplateData) string { return d.Title }, placeholder: true},
+	"artist":       {value: func(d FilenameTemplateData) string { return d.Artist }, placeholder: true},
+	"album":        {value: func(d FilenameTemplateData) string { return d.Album }, placeholder: true},
+	"album_artist": {value: func(d FilenameTemplateData) string { return d.AlbumArtist }, placeholder: true},
+	"year":         {value: func(d FilenameTemplateData) string { return releaseYear(d.ReleaseDate) }},
+	"date":         {value: func(d FilenameTemplateData) string { return d.ReleaseDate }, placeholder: true},
+	"playlist":     {value: func(d FilenameTemplateData) string { return d.Playlist }, placeholder: true},
+	"creator":      {value: func(d FilenameTemplateData) string { return d.Creator }, placeholder: true},
+	"isrc":         {value: func(d FilenameTemplateData) string { return d.ISRC }},
+	"upc":          {value: func(d FilenameTemplateData) string { return d.UPC }},
+	"spotify_id":   {value: func(d FilenameTemplateData) string { return d.SpotifyID }},
+	"track":        {value: func(d FilenameTemplateData) string { return templateNumber(d.Track) }, numeric: true, defaultWidth: 2},
+	"disc":         {value: func(d FilenameTemplateData) string { return templateNumber(d.Disc) }, numeric: true},
+	"total_tracks": {value: func(d FilenameTemplateData) string { return templateNumber(d.TotalTracks) }, numeric: true},
+	"duration":     {value: func(d FilenameTemplateData) string { return templateDuration(d.Duration) }},
+	"explicit": {value: func(d FilenameTemplateData) string {
+		if d.Explicit {
+			return "Explicit"
+		}
+		return ""
+	}},
+}
+
+var legacyTrackSeparator = regexp.MustCompile(`^(?:\.\s*|\s*-\s*|\s*)`)
+
+func releaseYear(releaseDate string) string {
+	if len(releaseDate) >= 4 {
+		return releaseDate[:4]
+	}
+	return ""
+}
+
+func templateNumber(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func templateDuration(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d.%02d.%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d.%02d", seconds/60, seconds%60)
+}
+
+func templateSyntaxError(template string, pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid template %q at column %d: %s", template, pos+1, fmt.Sprintf(format, args...))
+}
+
+func FilenameTemplateTokenNames() []string {
+	return []string{"title", "artist", "album", "album_artist", "year", "date", "playlist", "creator", "isrc", "upc", "spotify_id", "track", "disc", "total_tracks", "duration", "explicit"}
+}
+
+func ParseFilenameTemplate(template string) (*FilenameTemplate, error) {
+	runes := []rune(template)
+
+	var nodes, section []templateNode
+	var text strings.Builder
+	inSection := false
+	sectionStart := 0
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		node := templateNode{text: text.String()}
+		text.Reset()
+		if inSection {
+			section = append(section, node)
+		} else {
+			nodes = append(nodes, node)
+		}
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if strings.ContainsRune("[]{}", r) && i+1 < len(runes) && runes[i+1] == r {
+			text.WriteRune(r)
+			i++
+			continue
+		}
+
+		switch r {
+		case '[':
+			if inSection {
+				return nil, templateSyntaxError(template, i, "nested \"[\" sections are not supported")
+			}
+			flush()
+			inSection = true
+			sectionStart = i
+			section = nil
+		case ']':
+			if !inSection {
+				return nil, templateSyntaxError(template, i, "\"]\" has no matching \"[\"")
+			}
+			flush()
+			inSection = false
+			nodes = append(nodes, closeTemplateSection(section))
+		case '{':
+			end := -1
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '}' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return nil, templateSyntaxError(template, i, "\"{\" is never closed")
+			}
+			token, err := parseTemplateToken(template, string(runes[i+1:end]), i)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			node := templateNode{token: token}
+			if inSection {
+				section = append(section, node)
+			} else {
+				nodes = append(nodes, node)
+			}
+			i = end
+		case '}':
+			return nil, templateSyntaxError(template, i, "\"}\" has no matching \"{\"")
+		default:
+			text.WriteRune(r)
+		}
+	}
+
+	if inSection {
+		return nil, templateSyntaxError(template, sectionStart, "\"[\" is never closed")
+	}
+	flush()
+
+	return &FilenameTemplate{nodes: wrapLegacyTrackTokens(nodes)}, nil
+}
+
+func parseTemplateToken(template, body string, pos int) (*templateToken, error) {
+	if strings.ContainsAny(body, "{[]") {
+		return nil, templateSyntaxError(template, pos, "unexpected bracket inside {%s}", body)
+	}
+
+	parts := strings.Split(body, ":")
+	if strings.TrimSpace(parts[0]) == "" {
+		return nil, templateSyntaxError(template, pos, "empty token {%s}", body)
+	}
+
+	token := &templateToken{}
+	for _, name := range strings.Split(parts[0], "|") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, templateSyntaxError(template, pos, "empty fallback in {%s}", body)
+		}
+		if _, ok := filenameTemplateTokens[name]; !ok {
+			return nil, templateSyntaxError(template, pos, "unknown token %q in {%s}, expected one of %s", name, body, strings.Join(FilenameTemplateTokenNames(), ", "))
+		}
+		token.names = append(token.names, name)
+	}
+
+	for _, modifier := range parts[1:] {
+		modifier = strings.ToLower(strings.TrimSpace(modifier))
+		switch {
+		case modifier == "upper" || modifier == "lower" || modifier == "title":
+			if token.transform != "" {
+				return nil, templateSyntaxError(template, pos, "conflicting case modifiers in {%s}", body)
+			}
+			token.transform = modifier
+		case strings.HasPrefix(modifier, "."):
+			n, err := strconv.Atoi(modifier[1:])
+			if err != nil || n <= 0 {
+				return nil, templateSyntaxError(template, pos, "invalid truncation %q in {%s}, expected .N with N > 0", modifier, body)
+			}
+			token.truncate = n
+		case modifier != "" && strings.Trim(modifier, "0123456789") == "":
+			n, _ := strconv.Atoi(modifier)
+			if n <= 0 || n > 255 {
+				return nil, templateSyntaxError(template, pos, "invalid width %q in {%s}, expected 1 to 255", modifier, body)
+			}
+			token.width = n
+			token.zeroPad = strings.HasPrefix(modifier, "0")
+		default:
+			return nil, templateSyntaxError(template, pos, "unknown modifier %q in {%s}, expected upper, lower, title, .N or a width such as 03", modifier, body)
+		}
+	}
+
+	return token, nil
+}
+
+func closeTemplateSection(section []templateNode) templateNode {
+	for _, node := range section {
+		if node.token != nil {
+			return templateNode{section: section}
+		}
+	}
+
+	var literal strings.Builder
+	literal.WriteString("[")
+	for _, node := range section {
+		literal.WriteString(node.text)
+	}
+	literal.WriteString("]")
+	return templateNode{text: literal.String()}
+}
+
+func wrapLegacyTrackTokens(nodes []templateNode) []templateNode {
+	wrapped := make([]templateNode, 0, len(nodes))
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		if node.token == nil || !node.token.isBare("track") {
+			wrapped = append(wrapped, node)
+			continue
+		}
+
+		section := []templateNode{node}
+		var rest string
+		if i+1 < len(nodes) && nodes[i+1].token == nil && nodes[i+1].section == nil {
+			next := nodes[i+1].text
+			separator := legacyTrackSeparator.FindString(next)
+			if separator != "" {
+				section = append(section, templateNode{text: separator})
+			}
+			rest = next[len(separator):]
+			i++
+		}
+
+		wrapped = append(wrapped, templateNode{section: section})
+		if rest != "" {
+			wrapped = append(wrapped, templateNode{text: rest})
+		}
+	}
+	return wrapped
+}
+
+func (t *templateToken) isBare(name string) bool {
+	return len(t.names) == 1 && t.names[0] == name && t.width == 0 && t.truncate == 0 && t.transform == ""
+}
+
+func (t *templateToken) resolve(data FilenameTemplateData) (string, bool) {
+	for _, name := range t.names {
+		def := filenameTemplateTokens[name]
+		value := def.value(data)
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		return t.format(value, def), true
+	}
+	return "", false
+}
+
+func (t *templateToken) format(value string, def templateTokenDef) string {
+	if !def.numeric {
+		value = SanitizeFilename(value)
+	}
+
+	switch t.transform {
+	case "upper":
+		value = strings.ToUpper(value)
+	case "lower":
+		value = strings.ToLower(value)
+	case "title":
+		value = titleCaseTemplateValue(value)
+	}
+
+	if t.truncate > 0 && utf8.RuneCountInString(value) > t.truncate {
+		value = strings.TrimSpace(string([]rune(value)[:t.truncate]))
+	}
+
+	width, zeroPad := t.width, t.zeroPad
+	if width == 0 && def.numeric {
+		width, zeroPad = def.defaultWidth, true
+	}
+	if pad := width - utf8.RuneCountInString(value); pad > 0 {
+		fill := " "
+		if zeroPad {
+			fill = "0"
+		}
+		value = strings.Repeat(fill, pad) + value
+	}
+
+	return value
+}
+
+func titleCaseTemplateValue(value string) string {
+	runes := []rune(strings.ToLower(value))
+	start := true
+	for i, r := range runes {
+		if start && unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+		}
+		start = unicode.IsSpace(r) || strings.ContainsRune("-_([", r)
+	}
+	return string(runes)
+}
+
+func (t *FilenameTemplate) Render(data FilenameTemplateData, placeholder string) string {
+	var result strings.Builder
+	for _, node := range t.nodes {
+		switch {
+		case node.section != nil:
+			result.WriteString(renderTemplateSection(node.section, data))
+		case node.token != nil:
+			value, ok := node.token.resolve(data)
+			if !ok && filenameTemplateTokens[node.token.names[0]].placeholder {
+				value = placeholder
+			}
+			result.WriteString(value)
+		default:
+			result.WriteString(node.text)
+		}
+	}
+	return result.String()
+}
+
+func renderTemplateSection(section []templateNode, data FilenameTemplateData) string {
+	var result strings.Builder
+	for _, node := range section {
+		if node.token == nil {
+			result.WriteString(node.text)
+			continue
+		}
+		value, ok := node.token.resolve(data)
+		if !ok {
+			return ""
+		}
+		result.WriteString(value)
+	}
+	return result.String()
+}
+
+func (t *FilenameTemplate) UsesToken(name string) bool {
+	for _, node := range t.nodes {
+		for _, child := range append([]templateNode{node}, node.section...) {
+			if child.token == nil {
+				continue
+			}
+			for _, tokenName := range child.token.names {
+				if tokenName == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func ValidateFilenameTemplate(template string) error {
+	if !strings.Contains(template, "{") {
+		return nil
+	}
+	_, err := ParseFilenameTemplate(template)
+	return err
+}
+
+func ValidateFolderTemplate(template string) error {
+	for _, segment := range splitFolderTemplate(template) {
+		if err := ValidateFilenameTemplate(segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TemplateUsesToken(template, name string) bool {
+	for _, segment := range splitFolderTemplate(template) {
+		if !strings.Contains(segment, "{") {
+			continue
+		}
+		tmpl, err := ParseFilenameTemplate(segment)
+		if err != nil {
+			if strings.Contains(segment, "{"+name) {
+				return true
+			}
+			continue
+		}
+		if tmpl.UsesToken(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitFolderTemplate(template string) []string {
+	return strings.FieldsFunc(template, func(r rune) bool {
+		return r == '/' || r == '\\'
+	})
+}
+
+func BuildFilenameFromTemplate(format string, data FilenameTemplateData, includeTrackNumber bool) string {
+	if strings.Contains(format, "{") {
+		tmpl, err := ParseFilenameTemplate(format)
+		if err == nil {
+			return tmpl.Render(data, "Unknown")
+		}
+		fmt.Printf("[Filename] %v, using default format\n", err)
+		format = ""
+	}
+
+	safeTitle := SanitizeFilename(data.Title)
+	safeArtist := SanitizeFilename(data.Artist)
+
+	var filename string
+	switch format {
+	case "artist-title":
+		filename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
+	case "title":
+		filename = safeTitle
+	default:
+		filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
+	}
+
+	if includeTrackNumber && data.Track > 0 {
+		filename = fmt.Sprintf("%02d. %s", data.Track, filename)
+	}
+
+	return filename
+}
@@ -23,6 +23,15 @@ type HistoryItem struct {
 	Format      string `json:"format"`
 	Path        string `json:"path"`
 	Timestamp   int64  `json:"timestamp"`
+
+	QualityVerdict    string  `json:"quality_verdict,omitempty"`
+	QualityConfidence float64 `json:"quality_confidence,omitempty"`
+	CutoffHz          float64 `json:"cutoff_hz,omitempty"`
+
+	PCMMD5          string  `json:"pcm_md5,omitempty"`
+	StreamInfoMD5   string  `json:"streaminfo_md5,omitempty"`
+	FileSize        int64   `json:"file_size,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
 }
 
 var historyDB *bolt.DB
@@ -39,6 +39,9 @@ type spotifyTrackRawData struct {
 	Album struct {
 		GID string `json:"gid"`
 	} `json:"album"`
+	Artist []struct {
+		GID string `json:"gid"`
+	} `json:"artist"`
 	ExternalID []struct {
 		Type string `json:"type"`
 		ID   string `json:"id"`
@@ -53,8 +56,10 @@ type spotifyAlbumRawData struct {
 }
 
 type SpotifyTrackIdentifiers struct {
-	ISRC string `json:"isrc,omitempty"`
-	UPC  string `json:"upc,omitempty"`
+	ISRC      string   `json:"isrc,omitempty"`
+	UPC       string   `json:"upc,omitempty"`
+	AlbumID   string   `json:"album_id,omitempty"`
+	ArtistIDs []string `json:"artist_ids,omitempty"`
 }
 
 type SongLinkClient struct {
@@ -167,6 +172,12 @@ func mergeSpotifyTrackIdentifiers(target *SpotifyTrackIdentifiers, incoming Spot
 	if incoming.UPC != "" {
 		target.UPC = strings.TrimSpace(incoming.UPC)
 	}
+	if incoming.AlbumID != "" {
+		target.AlbumID = incoming.AlbumID
+	}
+	if len(incoming.ArtistIDs) > 0 {
+		target.ArtistIDs = incoming.ArtistIDs
+	}
 }
 
 func lookupSpotifyAlbumUPC(albumID string) (string, error) {
@@ -384,6 +395,34 @@ func spotifyEntityIDToGID(entityID string) (string, error) {
 	return hexValue, nil
 }
 
+func spotifyGIDToEntityID(gid string) (string, error) {
+	gid = strings.TrimSpace(gid)
+	if gid == "" {
+		return "", errors.New("GID is empty")
+	}
+
+	value, ok := new(big.Int).SetString(gid, 16)
+	if !ok {
+		return "", fmt.Errorf("invalid GID: %q", gid)
+	}
+
+	base := big.NewInt(62)
+	remainder := new(big.Int)
+	encoded := make([]byte, 0, 22)
+	for value.Sign() > 0 {
+		value.DivMod(value, base, remainder)
+		encoded = append(encoded, spotifyBase62Alphabet[remainder.Int64()])
+	}
+	for len(encoded) < 22 {
+		encoded = append(encoded, spotifyBase62Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded), nil
+}
+
 func fetchSpotifyTrackRawData(client *http.Client, trackID string) ([]byte, error) {
 	gid, err := spotifyTrackIDToGID(trackID)
 	if err != nil {
@@ -440,6 +479,15 @@ func extractSpotifyTrackIdentifiers(client *http.Client, payload []byte) (Spotif
 	}
 
 	albumGID := strings.TrimSpace(track.Album.GID)
+	if albumID, err := spotifyGIDToEntityID(albumGID); err == nil {
+		identifiers.AlbumID = albumID
+	}
+	for _, artist := range track.Artist {
+		if artistID, err := spotifyGIDToEntityID(artist.GID); err == nil {
+			identifiers.ArtistIDs = append(identifiers.ArtistIDs, artistID)
+		}
+	}
+
 	if client != nil && albumGID != "" {
 		albumPayload, err := fetchSpotifyRawMetadataByGID(client, "album", albumGID)
 		if err == nil {
@@ -0,0 +1,342 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"os/exec"
+	"sort"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DuplicateReasonISRC        = "isrc"
+	DuplicateReasonFingerprint = "fingerprint"
+
+	fingerprintMaxBitError  = 0.15
+	fingerprintMaxOffset    = 40
+	fingerprintMinOverlap   = 50
+	fingerprintLengthFactor = 0.1
+	fingerprintMaxDuration  = 5.0
+)
+
+type DuplicateGroup struct {
+	Reasons []string       `json:"reasons"`
+	ISRC    string         `json:"isrc,omitempty"`
+	Tracks  []LibraryTrack `json:"tracks"`
+}
+
+func ComputeFingerprint(path string) ([]uint32, error) {
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-v", "error",
+		"-i", path,
+		"-map", "0:a:0",
+		"-t", "120",
+		"-f", "chromaprint",
+		"-fp_format", "raw",
+		"-",
+	)
+	setHideWindow(cmd)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg chromaprint failed: %v - %s", err, lastLines(stderr.String(), 3))
+	}
+
+	fp := decodeFingerprint(stdout.Bytes())
+	if len(fp) == 0 {
+		return nil, fmt.Errorf("empty fingerprint")
+	}
+	return fp, nil
+}
+
+func decodeFingerprint(raw []byte) []uint32 {
+	fp := make([]uint32, len(raw)/4)
+	for i := range fp {
+		fp[i] = binary.LittleEndian.Uint32(raw[i*4:])
+	}
+	return fp
+}
+
+func encodeFingerprint(fp []uint32) []byte {
+	raw := make([]byte, len(fp)*4)
+	for i, v := range fp {
+		binary.LittleEndian.PutUint32(raw[i*4:], v)
+	}
+	return raw
+}
+
+func libraryFingerprint(path string) ([]uint32, error) {
+	var cached []uint32
+	_ = libraryIndexDB.View(func(tx *bolt.Tx) error {
+		if raw := tx.Bucket([]byte(libraryFingerprintBucket)).Get([]byte(path)); len(raw) > 0 {
+			cached = decodeFingerprint(raw)
+		}
+		return nil
+	})
+	if len(cached) > 0 {
+		return cached, nil
+	}
+
+	fp, err := ComputeFingerprint(path)
+	if err != nil {
+		return nil, err
+	}
+	_ = libraryIndexDB.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(libraryFilesBucket)).Get([]byte(path)) == nil {
+			return nil
+		}
+		return tx.Bucket([]byte(libraryFingerprintBucket)).Put([]byte(path), encodeFingerprint(fp))
+	})
+	return fp, nil
+}
+
+func FingerprintBitError(a, b []uint32) float64 {
+	best := 1.0
+	for offset := -fingerprintMaxOffset; offset <= fingerprintMaxOffset; offset++ {
+		var diff, total int
+		for i := range a {
+			j := i + offset
+			if j < 0 || j >= len(b) {
+				continue
+			}
+			diff += bits.OnesCount32(a[i] ^ b[j])
+			total += 32
+		}
+		if total < fingerprintMinOverlap*32 {
+			continue
+		}
+		if rate := float64(diff) / float64(total); rate < best {
+			best = rate
+		}
+	}
+	return best
+}
+
+func fingerprintsMatch(a, b []uint32) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	longer := max(len(a), len(b))
+	if float64(absInt(len(a)-len(b))) > float64(longer)*fingerprintLengthFactor {
+		return false
+	}
+	return FingerprintBitError(a, b) <= fingerprintMaxBitError
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func groupDuplicateTracks(tracks []LibraryTrack, fingerprints [][]uint32) []DuplicateGroup {
+	parent := make([]int, len(tracks))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	reasons := make(map[int]map[string]bool)
+	link := func(a, b int, reason string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+			for r := range reasons[rb] {
+				if reasons[ra] == nil {
+					reasons[ra] = make(map[string]bool)
+				}
+				reasons[ra][r] = true
+			}
+		}
+		if reasons[ra] == nil {
+			reasons[ra] = make(map[string]bool)
+		}
+		reasons[ra][reason] = true
+	}
+
+	firstByISRC := make(map[string]int)
+	for i, track := range tracks {
+		if track.ISRC == "" {
+			continue
+		}
+		if first, ok := firstByISRC[track.ISRC]; ok {
+			link(first, i, DuplicateReasonISRC)
+		} else {
+			firstByISRC[track.ISRC] = i
+		}
+	}
+
+	var timed, untimed []int
+	for i := range fingerprints {
+		if len(fingerprints[i]) == 0 {
+			continue
+		}
+		if tracks[i].Duration > 0 {
+			timed = append(timed, i)
+		} else {
+			untimed = append(untimed, i)
+		}
+	}
+	sort.SliceStable(timed, func(a, b int) bool { return tracks[timed[a]].Duration < tracks[timed[b]].Duration })
+
+	for a, i := range timed {
+		for _, j := range timed[a+1:] {
+			if tracks[j].Duration-tracks[i].Duration > fingerprintMaxDuration {
+				break
+			}
+			if fingerprintsMatch(fingerprints[i], fingerprints[j]) {
+				link(i, j, DuplicateReasonFingerprint)
+			}
+		}
+	}
+	for a, i := range untimed {
+		for _, j := range timed {
+			if fingerprintsMatch(fingerprints[i], fingerprints[j]) {
+				link(i, j, DuplicateReasonFingerprint)
+			}
+		}
+		for _, j := range untimed[a+1:] {
+			if fingerprintsMatch(fingerprints[i], fingerprints[j]) {
+				link(i, j, DuplicateReasonFingerprint)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var order []int
+	for i := range tracks {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			order = append(order, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, root := range order {
+		list := members[root]
+		if len(list) < 2 {
+			continue
+		}
+		group := DuplicateGroup{}
+		for _, i := range list {
+			group.Tracks = append(group.Tracks, tracks[i])
+			if group.ISRC == "" {
+				group.ISRC = tracks[i].ISRC
+			}
+		}
+		for reason := range reasons[find(root)] {
+			group.Reasons = append(group.Reasons, reason)
+		}
+		sort.Strings(group.Reasons)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func FindLibraryDuplicates(root string, withFingerprints bool) ([]DuplicateGroup, error) {
+	if _, err := RefreshLibraryIndex(root); err != nil {
+		return nil, err
+	}
+	tracks, err := ListLibraryTracks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var fingerprints [][]uint32
+	if withFingerprints {
+		fingerprints = fingerprintLibraryTracks(tracks)
+	}
+
+	return groupDuplicateTracks(tracks, fingerprints), nil
+}
+
+func fingerprintCandidates(tracks []LibraryTrack) []bool {
+	order := make([]int, 0, len(tracks))
+	candidates := make([]bool, len(tracks))
+	hasUntimed := false
+	for i, track := range tracks {
+		if track.Duration > 0 {
+			order = append(order, i)
+		} else {
+			candidates[i] = true
+			hasUntimed = true
+		}
+	}
+	if hasUntimed && len(tracks) > 1 {
+		for i := range candidates {
+			candidates[i] = true
+		}
+		return candidates
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return tracks[order[a]].Duration < tracks[order[b]].Duration })
+	for a := 1; a < len(order); a++ {
+		prev, cur := order[a-1], order[a]
+		if tracks[cur].Duration-tracks[prev].Duration <= fingerprintMaxDuration {
+			candidates[prev] = true
+			candidates[cur] = true
+		}
+	}
+	return candidates
+}
+
+func fingerprintLibraryTracks(tracks []LibraryTrack) [][]uint32 {
+	fingerprints := make([][]uint32, len(tracks))
+	candidates := fingerprintCandidates(tracks)
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for w := 0; w < libraryIndexWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fp, err := libraryFingerprint(tracks[i].Path)
+				if err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+					continue
+				}
+				fingerprints[i] = fp
+			}
+		}()
+	}
+	for i, candidate := range candidates {
+		if candidate {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		fmt.Printf("[Library] Fingerprinting failed for some files: %v\n", firstErr)
+	}
+	return fingerprints
+}
@@ -0,0 +1,600 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	libraryIndexDBFile       = "library_index.db"
+	libraryFilesBucket       = "Files"
+	libraryISRCBucket        = "ByISRC"
+	librarySpotifyIDBucket   = "BySpotifyID"
+	libraryNameBucket        = "ByName"
+	libraryRootsBucket       = "Roots"
+	libraryFingerprintBucket = "Fingerprints"
+
+	libraryIndexWorkers   = 4
+	libraryIndexBatchSize = 500
+	libraryRescanInterval = 10 * time.Minute
+)
+
+var libraryIndexBuckets = []string{
+	libraryFilesBucket,
+	libraryISRCBucket,
+	librarySpotifyIDBucket,
+	libraryNameBucket,
+	libraryRootsBucket,
+	libraryFingerprintBucket,
+}
+
+type LibraryTrack struct {
+	Path      string  `json:"path"`
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist"`
+	Album     string  `json:"album"`
+	SpotifyID string  `json:"spotify_id,omitempty"`
+	ISRC      string  `json:"isrc,omitempty"`
+	UPC       string  `json:"upc,omitempty"`
+	Duration  float64 `json:"duration,omitempty"`
+	Format    string  `json:"format"`
+	Size      int64   `json:"size"`
+	ModTime   int64   `json:"mod_time"`
+}
+
+type LibraryScanStats struct {
+	Root      string `json:"root"`
+	Total     int    `json:"total"`
+	Added     int    `json:"added"`
+	Updated   int    `json:"updated"`
+	Removed   int    `json:"removed"`
+	Unchanged int    `json:"unchanged"`
+	ScannedAt int64  `json:"scanned_at"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+var (
+	libraryIndexDB   *bolt.DB
+	libraryIndexDBMu sync.Mutex
+	libraryScanMu    sync.Mutex
+
+	libraryRescans   = make(map[string]bool)
+	libraryRescansMu sync.Mutex
+)
+
+func InitLibraryIndexDB() error {
+	libraryIndexDBMu.Lock()
+	defer libraryIndexDBMu.Unlock()
+
+	if libraryIndexDB != nil {
+		return nil
+	}
+
+	appDir, err := EnsureAppDir()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(appDir, libraryIndexDBFile)
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range libraryIndexBuckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return err
+	}
+
+	libraryIndexDB = db
+	return nil
+}
+
+func CloseLibraryIndexDB() {
+	libraryIndexDBMu.Lock()
+	defer libraryIndexDBMu.Unlock()
+
+	if libraryIndexDB != nil {
+		_ = libraryIndexDB.Close()
+		libraryIndexDB = nil
+	}
+}
+
+func GetSkipExistingISRCSetting() bool {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return false
+	}
+	enabled, _ := settings["skipExistingISRC"].(bool)
+	return enabled
+}
+
+func GetLibraryRootSetting() string {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return GetDefaultMusicPath()
+	}
+	if root, _ := settings["libraryRoot"].(string); strings.TrimSpace(root) != "" {
+		return NormalizePath(root)
+	}
+	if root, _ := settings["downloadPath"].(string); strings.TrimSpace(root) != "" {
+		return NormalizePath(root)
+	}
+	return GetDefaultMusicPath()
+}
+
+func NormalizeISRC(isrc string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(isrc), "-", ""))
+}
+
+func libraryPathKey(path string) string {
+	return filepath.Clean(path)
+}
+
+func libraryRootKey(root string) string {
+	return filepath.Clean(NormalizePath(root))
+}
+
+func libraryNameKey(path string) string {
+	return strings.ToLower(filepath.Base(path))
+}
+
+func libraryIndexKey(value, path string) []byte {
+	return []byte(value + "\x00" + path)
+}
+
+func pathWithinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isLibraryAudioFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac", ".mp3", ".m4a", ".aac":
+		return true
+	default:
+		return false
+	}
+}
+
+func readLibraryTrack(path string, info fs.FileInfo) *LibraryTrack {
+	track := &LibraryTrack{
+		Path:    path,
+		Format:  strings.ToUpper(strings.TrimPrefix(filepath.Ext(path), ".")),
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+	}
+
+	metadata, err := ReadAudioMetadata(path)
+	if err != nil {
+		fmt.Printf("[Library] Failed to read tags from %s: %v\n", filepath.Base(path), err)
+	} else if metadata != nil {
+		track.Title = metadata.Title
+		track.Artist = metadata.Artist
+		track.Album = metadata.Album
+		track.SpotifyID = metadata.SpotifyID
+		track.ISRC = NormalizeISRC(metadata.ISRC)
+		track.UPC = strings.TrimSpace(metadata.UPC)
+	}
+
+	if duration, err := GetAudioDuration(path); err == nil {
+		track.Duration = duration
+	}
+
+	return track
+}
+
+func putLibraryTrack(tx *bolt.Tx, track *LibraryTrack) error {
+	if err := deleteLibraryTrack(tx, track.Path); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(track)
+	if err != nil {
+		return fmt.Errorf("failed to encode library entry: %w", err)
+	}
+	if err := tx.Bucket([]byte(libraryFilesBucket)).Put([]byte(track.Path), payload); err != nil {
+		return err
+	}
+	if track.ISRC != "" {
+		if err := tx.Bucket([]byte(libraryISRCBucket)).Put(libraryIndexKey(track.ISRC, track.Path), nil); err != nil {
+			return err
+		}
+	}
+	if track.SpotifyID != "" {
+		if err := tx.Bucket([]byte(librarySpotifyIDBucket)).Put(libraryIndexKey(track.SpotifyID, track.Path), nil); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket([]byte(libraryNameBucket)).Put(libraryIndexKey(libraryNameKey(track.Path), track.Path), nil)
+}
+
+func deleteLibraryTrack(tx *bolt.Tx, path string) error {
+	files := tx.Bucket([]byte(libraryFilesBucket))
+	value := files.Get([]byte(path))
+	if value == nil {
+		return nil
+	}
+
+	var existing LibraryTrack
+	if err := json.Unmarshal(value, &existing); err == nil {
+		if existing.ISRC != "" {
+			_ = tx.Bucket([]byte(libraryISRCBucket)).Delete(libraryIndexKey(existing.ISRC, path))
+		}
+		if existing.SpotifyID != "" {
+			_ = tx.Bucket([]byte(librarySpotifyIDBucket)).Delete(libraryIndexKey(existing.SpotifyID, path))
+		}
+	}
+	_ = tx.Bucket([]byte(libraryNameBucket)).Delete(libraryIndexKey(libraryNameKey(path), path))
+	_ = tx.Bucket([]byte(libraryFingerprintBucket)).Delete([]byte(path))
+	return files.Delete([]byte(path))
+}
+
+func getLibraryTrack(tx *bolt.Tx, path string) (*LibraryTrack, bool) {
+	value := tx.Bucket([]byte(libraryFilesBucket)).Get([]byte(path))
+	if value == nil {
+		return nil, false
+	}
+	var track LibraryTrack
+	if err := json.Unmarshal(value, &track); err != nil {
+		return nil, false
+	}
+	return &track, true
+}
+
+func UpdateLibraryIndex(root string, force bool) (*LibraryScanStats, error) {
+	root = libraryRootKey(root)
+	if root == "" || root == "." {
+		return nil, fmt.Errorf("library root is required")
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	if err := InitLibraryIndexDB(); err != nil {
+		return nil, err
+	}
+
+	libraryScanMu.Lock()
+	defer libraryScanMu.Unlock()
+
+	if !force {
+		if stats, ok := getLibraryRootStats(root); ok && time.Since(time.Unix(stats.ScannedAt, 0)) < libraryRescanInterval {
+			return stats, nil
+		}
+	}
+
+	started := time.Now()
+	stats := &LibraryScanStats{Root: root}
+
+	known := make(map[string][2]int64)
+	if err := libraryIndexDB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(libraryFilesBucket)).Cursor()
+		prefix := []byte(root)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			path := string(k)
+			if !pathWithinRoot(root, path) {
+				continue
+			}
+			var track LibraryTrack
+			if json.Unmarshal(v, &track) == nil {
+				known[path] = [2]int64{track.Size, track.ModTime}
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		path string
+		info fs.FileInfo
+	}
+	changed := make(chan pending)
+	results := make(chan *LibraryTrack)
+	seen := make(map[string]bool, len(known))
+
+	var workers sync.WaitGroup
+	for i := 0; i < libraryIndexWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for item := range changed {
+				results <- readLibraryTrack(item.path, item.info)
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var writeErr error
+	writeDone := make(chan struct{})
+	go func() {
+		defer close(writeDone)
+		batch := make([]*LibraryTrack, 0, libraryIndexBatchSize)
+		flush := func() {
+			if len(batch) == 0 || writeErr != nil {
+				batch = batch[:0]
+				return
+			}
+			writeErr = libraryIndexDB.Update(func(tx *bolt.Tx) error {
+				for _, track := range batch {
+					if err := putLibraryTrack(tx, track); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			batch = batch[:0]
+		}
+		for track := range results {
+			batch = append(batch, track)
+			if len(batch) >= libraryIndexBatchSize {
+				flush()
+			}
+		}
+		flush()
+	}()
+
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isLibraryAudioFile(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		path = libraryPathKey(path)
+		seen[path] = true
+		stats.Total++
+
+		if prev, ok := known[path]; ok {
+			if prev[0] == info.Size() && prev[1] == info.ModTime().UnixNano() {
+				stats.Unchanged++
+				return nil
+			}
+			stats.Updated++
+			changed <- pending{path: path, info: info}
+			return nil
+		}
+
+		stats.Added++
+		changed <- pending{path: path, info: info}
+		return nil
+	})
+	close(changed)
+	<-writeDone
+
+	if walkErr != nil {
+		return nil, fmt.Errorf("failed to walk library: %w", walkErr)
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	stats.ScannedAt = time.Now().Unix()
+	stats.ElapsedMs = time.Since(started).Milliseconds()
+
+	if err := libraryIndexDB.Update(func(tx *bolt.Tx) error {
+		for path := range known {
+			if seen[path] {
+				continue
+			}
+			if err := deleteLibraryTrack(tx, path); err != nil {
+				return err
+			}
+			stats.Removed++
+		}
+		payload, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(libraryRootsBucket)).Put([]byte(root), payload)
+	}); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("[Library] Indexed %s: %d file(s), %d added, %d updated, %d removed in %dms\n",
+		root, stats.Total, stats.Added, stats.Updated, stats.Removed, stats.ElapsedMs)
+
+	return stats, nil
+}
+
+func getLibraryRootStats(root string) (*LibraryScanStats, bool) {
+	var stats *LibraryScanStats
+	_ = libraryIndexDB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(libraryRootsBucket)).Get([]byte(root))
+		if value == nil {
+			return nil
+		}
+		var s LibraryScanStats
+		if json.Unmarshal(value, &s) == nil {
+			stats = &s
+		}
+		return nil
+	})
+	return stats, stats != nil
+}
+
+func EnsureLibraryIndex(root string) error {
+	_, err := UpdateLibraryIndex(root, false)
+	return err
+}
+
+func RefreshLibraryIndex(root string) (*LibraryScanStats, error) {
+	return UpdateLibraryIndex(root, true)
+}
+
+func scheduleLibraryRescan(root string) {
+	root = libraryRootKey(root)
+
+	libraryRescansMu.Lock()
+	if libraryRescans[root] {
+		libraryRescansMu.Unlock()
+		return
+	}
+	libraryRescans[root] = true
+	libraryRescansMu.Unlock()
+
+	go func() {
+		defer func() {
+			libraryRescansMu.Lock()
+			delete(libraryRescans, root)
+			libraryRescansMu.Unlock()
+		}()
+		if _, err := UpdateLibraryIndex(root, false); err != nil {
+			fmt.Printf("[Library] Background index of %s failed: %v\n", root, err)
+		}
+	}()
+}
+
+func AddLibraryFile(path string) {
+	path = libraryPathKey(path)
+	if !isLibraryAudioFile(path) || InitLibraryIndexDB() != nil {
+		return
+	}
+
+	indexed := false
+	_ = libraryIndexDB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(libraryRootsBucket)).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if pathWithinRoot(string(k), path) {
+				indexed = true
+				return nil
+			}
+		}
+		return nil
+	})
+	if !indexed {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	track := readLibraryTrack(path, info)
+	if err := libraryIndexDB.Update(func(tx *bolt.Tx) error {
+		return putLibraryTrack(tx, track)
+	}); err != nil {
+		fmt.Printf("[Library] Failed to index %s: %v\n", path, err)
+	}
+}
+
+func lookupLibraryTracks(root, bucket, value string) []LibraryTrack {
+	if value == "" || InitLibraryIndexDB() != nil {
+		return nil
+	}
+	root = libraryRootKey(root)
+
+	var tracks []LibraryTrack
+	_ = libraryIndexDB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucket)).Cursor()
+		prefix := []byte(value + "\x00")
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			path := string(k[len(prefix):])
+			if !pathWithinRoot(root, path) {
+				continue
+			}
+			if track, ok := getLibraryTrack(tx, path); ok {
+				tracks = append(tracks, *track)
+			}
+		}
+		return nil
+	})
+	return tracks
+}
+
+func firstExistingLibraryTrack(tracks []LibraryTrack) (LibraryTrack, bool) {
+	for _, track := range tracks {
+		if info, err := os.Stat(track.Path); err == nil && info.Size() > 0 {
+			return track, true
+		}
+	}
+	return LibraryTrack{}, false
+}
+
+func prepareLibraryLookup(root string) bool {
+	if strings.TrimSpace(root) == "" {
+		return false
+	}
+	if err := InitLibraryIndexDB(); err != nil {
+		fmt.Printf("[Library] Failed to open index: %v\n", err)
+		return false
+	}
+	if stats, ok := getLibraryRootStats(libraryRootKey(root)); !ok || time.Since(time.Unix(stats.ScannedAt, 0)) >= libraryRescanInterval {
+		scheduleLibraryRescan(root)
+	}
+	return true
+}
+
+func FindLibraryTrackByISRC(root, isrc string) (LibraryTrack, bool) {
+	isrc = NormalizeISRC(isrc)
+	if isrc == "" || !prepareLibraryLookup(root) {
+		return LibraryTrack{}, false
+	}
+	return firstExistingLibraryTrack(lookupLibraryTracks(root, libraryISRCBucket, isrc))
+}
+
+func FindLibraryTrackBySpotifyID(root, spotifyID string) (LibraryTrack, bool) {
+	spotifyID = strings.TrimSpace(spotifyID)
+	if spotifyID == "" || !prepareLibraryLookup(root) {
+		return LibraryTrack{}, false
+	}
+	return firstExistingLibraryTrack(lookupLibraryTracks(root, librarySpotifyIDBucket, spotifyID))
+}
+
+func FindLibraryTracksByFilename(root, filename string) []LibraryTrack {
+	if filename == "" || !prepareLibraryLookup(root) {
+		return nil
+	}
+	return lookupLibraryTracks(root, libraryNameBucket, libraryNameKey(filename))
+}
+
+func ListLibraryTracks(root string) ([]LibraryTrack, error) {
+	if err := EnsureLibraryIndex(root); err != nil {
+		return nil, err
+	}
+	root = libraryRootKey(root)
+
+	var tracks []LibraryTrack
+	err := libraryIndexDB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(libraryFilesBucket)).Cursor()
+		prefix := []byte(root)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if !pathWithinRoot(root, string(k)) {
+				continue
+			}
+			var track LibraryTrack
+			if json.Unmarshal(v, &track) == nil {
+				tracks = append(tracks, track)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].Path < tracks[j].Path })
+	return tracks, err
+}
@@ -1,16 +1,12 @@
 package backend
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
-	"time"
 )
 
 type LRCLibResponse struct {
@@ -25,10 +21,17 @@ type LRCLibResponse struct {
 	SyncedLyrics string  `json:"syncedLyrics"`
 }
 
-type LyricsLine struct {
+type LyricsSyllable struct {
 	StartTimeMs string `json:"startTimeMs"`
 	Words       string `json:"words"`
-	EndTimeMs   string `json:"endTimeMs"`
+	EndTimeMs   string `json:"endTimeMs,omitempty"`
+}
+
+type LyricsLine struct {
+	StartTimeMs string           `json:"startTimeMs"`
+	Words       string           `json:"words"`
+	EndTimeMs   string           `json:"endTimeMs"`
+	Syllables   []LyricsSyllable `json:"syllables,omitempty"`
 }
 
 type LyricsResponse struct {
@@ -59,173 +62,52 @@ type LyricsDownloadResponse struct {
 	File          string `json:"file,omitempty"`
 	Error         string `json:"error,omitempty"`
 	AlreadyExists bool   `json:"already_exists,omitempty"`
+	Source        string `json:"source,omitempty"`
 }
 
 type LyricsClient struct {
-	httpClient *http.Client
+	ctx       context.Context
+	providers []LyricsProvider
 }
 
 func NewLyricsClient() *LyricsClient {
-	return &LyricsClient{
-		httpClient: newHTTPClient(15 * time.Second),
-	}
+	return NewLyricsClientWithContext(context.Background())
 }
 
-func (c *LyricsClient) FetchLyricsWithMetadata(trackName, artistName, albumName string, duration int) (*LyricsResponse, error) {
-	apiURL := fmt.Sprintf("https://lrclib.net/api/get?artist_name=%s&track_name=%s",
-		url.QueryEscape(artistName),
-		url.QueryEscape(trackName))
-
-	if albumName != "" {
-		apiURL = fmt.Sprintf("%s&album_name=%s", apiURL, url.QueryEscape(albumName))
-	}
-
-	if duration > 0 {
-		apiURL = fmt.Sprintf("%s&duration=%d", apiURL, duration)
-	}
-
-	resp, err := c.httpClient.Get(apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from LRCLIB: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("LRCLIB returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read LRCLIB response: %v", err)
-	}
-
-	var lrcLibResp LRCLibResponse
-	if err := json.Unmarshal(body, &lrcLibResp); err != nil {
-		return nil, fmt.Errorf("failed to parse LRCLIB response: %v", err)
-	}
-
-	if lrcLibResp.SyncedLyrics == "" && lrcLibResp.PlainLyrics == "" {
-		return nil, fmt.Errorf("LRCLIB returned empty lyrics")
-	}
-
-	return c.convertLRCLibToLyricsResponse(&lrcLibResp), nil
+func NewLyricsClientWithContext(ctx context.Context) *LyricsClient {
+	return NewLyricsClientWithProviders(ctx, GetLyricsProviders()...)
 }
 
-func (c *LyricsClient) convertLRCLibToLyricsResponse(lrcLib *LRCLibResponse) *LyricsResponse {
-	resp := &LyricsResponse{
-		Error:    false,
-		SyncType: "LINE_SYNCED",
-		Lines:    []LyricsLine{},
-	}
-
-	lyricsText := lrcLib.SyncedLyrics
-	if lyricsText == "" {
-		lyricsText = lrcLib.PlainLyrics
-		resp.SyncType = "UNSYNCED"
+func NewLyricsClientWithProviders(ctx context.Context, providers ...LyricsProvider) *LyricsClient {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-
-	if lyricsText == "" {
-		resp.Error = true
-		return resp
-	}
-
-	lines := strings.Split(lyricsText, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, "[") && len(line) > 10 {
-			closeBracket := strings.Index(line, "]")
-			if closeBracket > 0 {
-				timestamp := line[1:closeBracket]
-				words := strings.TrimSpace(line[closeBracket+1:])
-
-				ms := lrcTimestampToMs(timestamp)
-				resp.Lines = append(resp.Lines, LyricsLine{
-					StartTimeMs: fmt.Sprintf("%d", ms),
-					Words:       words,
-				})
-				continue
-			}
-		}
-
-		resp.Lines = append(resp.Lines, LyricsLine{
-			StartTimeMs: "",
-			Words:       line,
-		})
+	return &LyricsClient{
+		ctx:       ctx,
+		providers: providers,
 	}
-
-	return resp
 }
 
 func lrcTimestampToMs(timestamp string) int64 {
-	var minutes, seconds, centiseconds int64
-
-	n, _ := fmt.Sscanf(timestamp, "%d:%d.%d", &minutes, &seconds, &centiseconds)
-	if n >= 2 {
-		return minutes*60*1000 + seconds*1000 + centiseconds*10
-	}
-	return 0
-}
-
-func (c *LyricsClient) FetchLyricsFromLRCLibSearch(trackName, artistName string) (*LyricsResponse, error) {
-	apiURL := fmt.Sprintf("https://lrclib.net/api/search?artist_name=%s&track_name=%s",
-		url.QueryEscape(artistName),
-		url.QueryEscape(trackName))
-
-	resp, err := c.httpClient.Get(apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status %d", resp.StatusCode)
-	}
+	var minutes, seconds int64
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read failed: %v", err)
+	clock, fraction, _ := strings.Cut(timestamp, ".")
+	n, _ := fmt.Sscanf(clock, "%d:%d", &minutes, &seconds)
+	if n < 2 {
+		return 0
 	}
 
-	var results []LRCLibResponse
-	if err := json.Unmarshal(body, &results); err != nil {
-		return nil, fmt.Errorf("parse failed: %v", err)
+	ms := minutes*60*1000 + seconds*1000
+	if len(fraction) > 3 {
+		fraction = fraction[:3]
 	}
-
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no results found")
-	}
-
-	var bestSynced *LRCLibResponse
-	var bestPlain *LRCLibResponse
-	for i := range results {
-		if results[i].SyncedLyrics != "" && bestSynced == nil {
-			bestSynced = &results[i]
+	if value, err := strconv.ParseInt(fraction, 10, 64); err == nil {
+		for i := len(fraction); i < 3; i++ {
+			value *= 10
 		}
-		if results[i].PlainLyrics != "" && bestPlain == nil {
-			bestPlain = &results[i]
-		}
-		if bestSynced != nil {
-			break
-		}
-	}
-
-	best := bestSynced
-	if best == nil {
-		best = bestPlain
-	}
-	if best == nil {
-		best = &results[0]
-	}
-
-	if best.SyncedLyrics == "" && best.PlainLyrics == "" {
-		return nil, fmt.Errorf("no lyrics found in search results")
+		ms += value
 	}
-
-	return c.convertLRCLibToLyricsResponse(best), nil
+	return ms
 }
 
 func simplifyTrackName(name string) string {
@@ -240,7 +122,18 @@ func simplifyTrackName(name string) string {
 }
 
 func isSynced(resp *LyricsResponse) bool {
-	return resp != nil && !resp.Error && resp.SyncType == "LINE_SYNCED" && len(resp.Lines) > 0
+	if resp == nil || resp.Error || len(resp.Lines) == 0 {
+		return false
+	}
+	if resp.SyncType != "LINE_SYNCED" && resp.SyncType != "SYLLABLE_SYNCED" {
+		return false
+	}
+	for _, line := range resp.Lines {
+		if line.StartTimeMs != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func hasLyrics(resp *LyricsResponse) bool {
@@ -248,59 +141,32 @@ func hasLyrics(resp *LyricsResponse) bool {
 }
 
 func (c *LyricsClient) FetchLyricsAllSources(spotifyID, trackName, artistName, albumName string, duration int) (*LyricsResponse, string, error) {
+	query := TrackQuery{
+		SpotifyID:   spotifyID,
+		TrackName:   trackName,
+		ArtistName:  artistName,
+		AlbumName:   albumName,
+		DurationSec: duration,
+	}
+
 	var unsyncedFallback *LyricsResponse
 	var unsyncedSource string
 
-	check := func(resp *LyricsResponse, err error, source string) (*LyricsResponse, string, bool) {
-		if err != nil || resp == nil || resp.Error || len(resp.Lines) == 0 {
-			return nil, "", false
+	for _, provider := range c.providers {
+		if c.ctx.Err() != nil {
+			return nil, "", c.ctx.Err()
+		}
+
+		resp, err := provider.Fetch(c.ctx, query)
+		if err != nil || !hasLyrics(resp) {
+			continue
 		}
 		if isSynced(resp) {
-			return resp, source, true
+			return resp, provider.Name(), nil
 		}
 		if unsyncedFallback == nil {
 			unsyncedFallback = resp
-			unsyncedSource = source
-		}
-		return nil, "", false
-	}
-
-	var resp *LyricsResponse
-	var src string
-	var found bool
-
-	resp, _ = c.FetchLyricsWithMetadata(trackName, artistName, albumName, duration)
-	resp, src, found = check(resp, nil, "LRCLIB")
-	if found {
-		return resp, src, nil
-	}
-
-	if albumName != "" {
-		resp, _ = c.FetchLyricsWithMetadata(trackName, artistName, "", duration)
-		resp, src, found = check(resp, nil, "LRCLIB (no album)")
-		if found {
-			return resp, src, nil
-		}
-	}
-
-	resp, _ = c.FetchLyricsFromLRCLibSearch(trackName, artistName)
-	resp, src, found = check(resp, nil, "LRCLIB Search")
-	if found {
-		return resp, src, nil
-	}
-
-	simplifiedTrack := simplifyTrackName(trackName)
-	if simplifiedTrack != trackName {
-		resp, _ = c.FetchLyricsWithMetadata(simplifiedTrack, artistName, albumName, duration)
-		resp, src, found = check(resp, nil, "LRCLIB (simplified)")
-		if found {
-			return resp, src, nil
-		}
-
-		resp, _ = c.FetchLyricsFromLRCLibSearch(simplifiedTrack, artistName)
-		resp, src, found = check(resp, nil, "LRCLIB Search (simplified)")
-		if found {
-			return resp, src, nil
+			unsyncedSource = provider.Name()
 		}
 	}
 
@@ -312,6 +178,10 @@ func (c *LyricsClient) FetchLyricsAllSources(spotifyID, trackName, artistName, a
 }
 
 func (c *LyricsClient) ConvertToLRC(lyrics *LyricsResponse, trackName, artistName string) string {
+	return lyricsToLRC(lyrics, trackName, artistName)
+}
+
+func lyricsToLRC(lyrics *LyricsResponse, trackName, artistName string) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("[ti:%s]\n", trackName))
@@ -326,6 +196,8 @@ func (c *LyricsClient) ConvertToLRC(lyrics *LyricsResponse, trackName, artistNam
 
 		if line.StartTimeMs == "" {
 			sb.WriteString(fmt.Sprintf("%s\n", line.Words))
+		} else if len(line.Syllables) > 0 {
+			sb.WriteString(fmt.Sprintf("%s%s\n", msToLRCTimestamp(line.StartTimeMs), enhancedLRCWords(line)))
 		} else {
 			timestamp := msToLRCTimestamp(line.StartTimeMs)
 			sb.WriteString(fmt.Sprintf("%s%s\n", timestamp, line.Words))
@@ -335,7 +207,25 @@ func (c *LyricsClient) ConvertToLRC(lyrics *LyricsResponse, trackName, artistNam
 	return sb.String()
 }
 
-func msToLRCTimestamp(msStr string) string {
+func enhancedLRCWords(line LyricsLine) string {
+	var sb strings.Builder
+	for _, syllable := range line.Syllables {
+		sb.WriteString(msToEnhancedLRCTimestamp(syllable.StartTimeMs))
+		sb.WriteString(syllable.Words)
+	}
+
+	endTime := line.EndTimeMs
+	if last := line.Syllables[len(line.Syllables)-1]; last.EndTimeMs != "" {
+		endTime = last.EndTimeMs
+	}
+	if endTime != "" {
+		sb.WriteString(msToEnhancedLRCTimestamp(endTime))
+	}
+
+	return strings.TrimRight(sb.String(), " ")
+}
+
+func lrcClock(msStr string) string {
 	var ms int64
 	fmt.Sscanf(msStr, "%d", &ms)
 
@@ -344,62 +234,28 @@ func msToLRCTimestamp(msStr string) string {
 	seconds := totalSeconds % 60
 	centiseconds := (ms % 1000) / 10
 
-	return fmt.Sprintf("[%02d:%02d.%02d]", minutes, seconds, centiseconds)
+	return fmt.Sprintf("%02d:%02d.%02d", minutes, seconds, centiseconds)
 }
 
-func buildLyricsFilename(trackName, artistName, albumName, albumArtist, releaseDate, filenameFormat, isrc string, includeTrackNumber bool, position, discNumber int) string {
-	safeTitle := SanitizeFilename(trackName)
-	safeArtist := SanitizeFilename(artistName)
-	safeAlbum := SanitizeFilename(albumName)
-	safeAlbumArtist := SanitizeFilename(albumArtist)
-	safeISRC := SanitizeOptionalFilename(isrc)
-
-	year := ""
-	if len(releaseDate) >= 4 {
-		year = releaseDate[:4]
-	}
-
-	var filename string
-
-	if strings.Contains(filenameFormat, "{") {
-		filename = filenameFormat
-		filename = strings.ReplaceAll(filename, "{title}", safeTitle)
-		filename = strings.ReplaceAll(filename, "{artist}", safeArtist)
-		filename = strings.ReplaceAll(filename, "{album}", safeAlbum)
-		filename = strings.ReplaceAll(filename, "{album_artist}", safeAlbumArtist)
-		filename = strings.ReplaceAll(filename, "{year}", year)
-		filename = strings.ReplaceAll(filename, "{date}", SanitizeFilename(releaseDate))
-		filename = strings.ReplaceAll(filename, "{isrc}", safeISRC)
-
-		if discNumber > 0 {
-			filename = strings.ReplaceAll(filename, "{disc}", fmt.Sprintf("%d", discNumber))
-		} else {
-			filename = strings.ReplaceAll(filename, "{disc}", "")
-		}
-
-		if position > 0 {
-			filename = strings.ReplaceAll(filename, "{track}", fmt.Sprintf("%02d", position))
-		} else {
-			filename = regexp.MustCompile(`\{track\}\.\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*-\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*`).ReplaceAllString(filename, "")
-		}
-	} else {
-		switch filenameFormat {
-		case "artist-title":
-			filename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
-		case "title":
-			filename = safeTitle
-		default:
-			filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-		}
+func msToLRCTimestamp(msStr string) string {
+	return "[" + lrcClock(msStr) + "]"
+}
 
-		if includeTrackNumber && position > 0 {
-			filename = fmt.Sprintf("%02d. %s", position, filename)
-		}
-	}
+func msToEnhancedLRCTimestamp(msStr string) string {
+	return "<" + lrcClock(msStr) + ">"
+}
 
-	return filename + ".lrc"
+func buildLyricsFilename(trackName, artistName, albumName, albumArtist, releaseDate, filenameFormat, isrc string, includeTrackNumber bool, position, discNumber int) string {
+	return BuildFilenameFromTemplate(filenameFormat, FilenameTemplateData{
+		Title:       trackName,
+		Artist:      artistName,
+		Album:       albumName,
+		AlbumArtist: albumArtist,
+		ReleaseDate: releaseDate,
+		ISRC:        isrc,
+		Track:       position,
+		Disc:        discNumber,
+	}, includeTrackNumber) + ".lrc"
 }
 
 func findAudioFileForLyrics(dir, trackName, artistName string) string {
@@ -495,7 +351,7 @@ func (c *LyricsClient) DownloadLyrics(req LyricsDownloadRequest) (*LyricsDownloa
 		}
 	}
 
-	lyrics, _, err := c.FetchLyricsAllSources(req.SpotifyID, req.TrackName, req.ArtistName, req.AlbumName, audioDuration)
+	lyrics, source, err := c.FetchLyricsAllSources(req.SpotifyID, req.TrackName, req.ArtistName, req.AlbumName, audioDuration)
 	if err != nil {
 		return &LyricsDownloadResponse{
 			Success: false,
@@ -516,5 +372,6 @@ func (c *LyricsClient) DownloadLyrics(req LyricsDownloadRequest) (*LyricsDownloa
 		Success: true,
 		Message: "Lyrics downloaded successfully",
 		File:    filePath,
+		Source:  source,
 	}, nil
 }
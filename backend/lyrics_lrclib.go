@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const lrclibBaseURL = "https://lrclib.net"
+
+type LRCLibProvider struct {
+	BaseURL    string
+	httpClient *http.Client
+}
+
+func NewLRCLibProvider(baseURL string) *LRCLibProvider {
+	if baseURL == "" {
+		baseURL = lrclibBaseURL
+	}
+	return &LRCLibProvider{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: newHTTPClient(15 * time.Second),
+	}
+}
+
+func (p *LRCLibProvider) Name() string {
+	return "LRCLIB"
+}
+
+func (p *LRCLibProvider) Fetch(ctx context.Context, query TrackQuery) (*LyricsResponse, error) {
+	var unsynced *LyricsResponse
+
+	attempt := func(resp *LyricsResponse, err error) (*LyricsResponse, bool) {
+		if err != nil || !hasLyrics(resp) {
+			return nil, false
+		}
+		if isSynced(resp) {
+			return resp, true
+		}
+		if unsynced == nil {
+			unsynced = resp
+		}
+		return nil, false
+	}
+
+	if resp, ok := attempt(p.get(ctx, query.TrackName, query.ArtistName, query.AlbumName, query.DurationSec)); ok {
+		return resp, nil
+	}
+
+	if query.AlbumName != "" {
+		if resp, ok := attempt(p.get(ctx, query.TrackName, query.ArtistName, "", query.DurationSec)); ok {
+			return resp, nil
+		}
+	}
+
+	if resp, ok := attempt(p.search(ctx, query.TrackName, query.ArtistName)); ok {
+		return resp, nil
+	}
+
+	simplifiedTrack := simplifyTrackName(query.TrackName)
+	if simplifiedTrack != query.TrackName {
+		if resp, ok := attempt(p.get(ctx, simplifiedTrack, query.ArtistName, query.AlbumName, query.DurationSec)); ok {
+			return resp, nil
+		}
+		if resp, ok := attempt(p.search(ctx, simplifiedTrack, query.ArtistName)); ok {
+			return resp, nil
+		}
+	}
+
+	if unsynced != nil {
+		return unsynced, nil
+	}
+	return nil, fmt.Errorf("LRCLIB has no lyrics for %s - %s", query.ArtistName, query.TrackName)
+}
+
+func (p *LRCLibProvider) request(ctx context.Context, apiURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from LRCLIB: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LRCLIB returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read LRCLIB response: %v", err)
+	}
+	return body, nil
+}
+
+func (p *LRCLibProvider) get(ctx context.Context, trackName, artistName, albumName string, duration int) (*LyricsResponse, error) {
+	apiURL := fmt.Sprintf("%s/api/get?artist_name=%s&track_name=%s",
+		p.BaseURL,
+		url.QueryEscape(artistName),
+		url.QueryEscape(trackName))
+
+	if albumName != "" {
+		apiURL = fmt.Sprintf("%s&album_name=%s", apiURL, url.QueryEscape(albumName))
+	}
+
+	if duration > 0 {
+		apiURL = fmt.Sprintf("%s&duration=%d", apiURL, duration)
+	}
+
+	body, err := p.request(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var lrcLibResp LRCLibResponse
+	if err := json.Unmarshal(body, &lrcLibResp); err != nil {
+		return nil, fmt.Errorf("failed to parse LRCLIB response: %v", err)
+	}
+
+	if lrcLibResp.SyncedLyrics == "" && lrcLibResp.PlainLyrics == "" {
+		return nil, fmt.Errorf("LRCLIB returned empty lyrics")
+	}
+
+	return convertLRCLibResponse(&lrcLibResp), nil
+}
+
+func (p *LRCLibProvider) search(ctx context.Context, trackName, artistName string) (*LyricsResponse, error) {
+	apiURL := fmt.Sprintf("%s/api/search?artist_name=%s&track_name=%s",
+		p.BaseURL,
+		url.QueryEscape(artistName),
+		url.QueryEscape(trackName))
+
+	body, err := p.request(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []LRCLibResponse
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, fmt.Errorf("parse failed: %v", err)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
+
+	var bestSynced *LRCLibResponse
+	var bestPlain *LRCLibResponse
+	for i := range results {
+		if results[i].SyncedLyrics != "" && bestSynced == nil {
+			bestSynced = &results[i]
+		}
+		if results[i].PlainLyrics != "" && bestPlain == nil {
+			bestPlain = &results[i]
+		}
+		if bestSynced != nil {
+			break
+		}
+	}
+
+	best := bestSynced
+	if best == nil {
+		best = bestPlain
+	}
+	if best == nil {
+		best = &results[0]
+	}
+
+	if best.SyncedLyrics == "" && best.PlainLyrics == "" {
+		return nil, fmt.Errorf("no lyrics found in search results")
+	}
+
+	return convertLRCLibResponse(best), nil
+}
+
+func convertLRCLibResponse(lrcLib *LRCLibResponse) *LyricsResponse {
+	if lrcLib.SyncedLyrics != "" {
+		return parseLRCText(lrcLib.SyncedLyrics, true)
+	}
+	return parseLRCText(lrcLib.PlainLyrics, false)
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLRCLibFetchSynced(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/get" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("duration"); got != "215" {
+			t.Errorf("duration = %q, want 215", got)
+		}
+		w.Write([]byte(`{"syncedLyrics":"[00:01.50]First line\n[01:02.03]Second line","plainLyrics":"First line\nSecond line"}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewLRCLibProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist", AlbumName: "Album", DurationSec: 215})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if resp.SyncType != "LINE_SYNCED" {
+		t.Fatalf("SyncType = %q, want LINE_SYNCED", resp.SyncType)
+	}
+	if len(resp.Lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(resp.Lines))
+	}
+	if resp.Lines[0].StartTimeMs != "1500" || resp.Lines[0].Words != "First line" {
+		t.Errorf("line 0 = %+v", resp.Lines[0])
+	}
+	if resp.Lines[1].StartTimeMs != "62030" {
+		t.Errorf("line 1 start = %q, want 62030", resp.Lines[1].StartTimeMs)
+	}
+}
+
+func TestLRCLibFetchFallsBackToSearch(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/get":
+			http.NotFound(w, r)
+		case "/api/search":
+			w.Write([]byte(`[{"plainLyrics":"Plain only"},{"syncedLyrics":"[00:10.00]Synced result"}]`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := NewLRCLibProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !isSynced(resp) || resp.Lines[0].Words != "Synced result" {
+		t.Fatalf("expected the synced search result, got %+v", resp)
+	}
+	if len(paths) != 2 || paths[0] != "/api/get" || paths[1] != "/api/search" {
+		t.Errorf("requests = %v, want get then search", paths)
+	}
+}
+
+func TestLRCLibFetchNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/search" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	if _, err := NewLRCLibProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist"}); err == nil {
+		t.Fatal("expected an error when LRCLIB has no lyrics")
+	}
+}
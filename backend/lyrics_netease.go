@@ -0,0 +1,277 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const neteaseBaseURL = "https://music.163.com"
+
+type NetEaseProvider struct {
+	BaseURL    string
+	httpClient *http.Client
+}
+
+type neteaseSearchResponse struct {
+	Code   int `json:"code"`
+	Result struct {
+		Songs []neteaseSong `json:"songs"`
+	} `json:"result"`
+}
+
+type neteaseSong struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Duration int    `json:"duration"`
+	Artists  []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+}
+
+type neteaseLyricResponse struct {
+	Code        int  `json:"code"`
+	NoLyric     bool `json:"nolyric"`
+	Uncollected bool `json:"uncollected"`
+	Lrc         struct {
+		Lyric string `json:"lyric"`
+	} `json:"lrc"`
+	Yrc struct {
+		Lyric string `json:"lyric"`
+	} `json:"yrc"`
+}
+
+func NewNetEaseProvider(baseURL string) *NetEaseProvider {
+	if baseURL == "" {
+		baseURL = neteaseBaseURL
+	}
+	return &NetEaseProvider{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: newHTTPClient(15 * time.Second),
+	}
+}
+
+func (p *NetEaseProvider) Name() string {
+	return "NetEase"
+}
+
+func (p *NetEaseProvider) Fetch(ctx context.Context, query TrackQuery) (*LyricsResponse, error) {
+	song, err := p.search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.request(ctx, fmt.Sprintf("%s/api/song/lyric?id=%d&lv=1&kv=1&tv=-1&yv=1", p.BaseURL, song.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	var lyricResp neteaseLyricResponse
+	if err := json.Unmarshal(body, &lyricResp); err != nil {
+		return nil, fmt.Errorf("failed to parse NetEase lyrics: %v", err)
+	}
+	if resp := parseNetEaseYrc(lyricResp.Yrc.Lyric); hasLyrics(resp) {
+		return resp, nil
+	}
+	if lyricResp.NoLyric || lyricResp.Uncollected || strings.TrimSpace(lyricResp.Lrc.Lyric) == "" {
+		return nil, fmt.Errorf("NetEase has no lyrics for %s - %s", query.ArtistName, query.TrackName)
+	}
+
+	text := cleanNetEaseLyrics(lyricResp.Lrc.Lyric)
+	synced := strings.HasPrefix(strings.TrimSpace(text), "[")
+	resp := parseLRCText(text, synced)
+	if !hasLyrics(resp) {
+		return nil, fmt.Errorf("NetEase returned empty lyrics")
+	}
+	return resp, nil
+}
+
+func (p *NetEaseProvider) search(ctx context.Context, query TrackQuery) (*neteaseSong, error) {
+	term := strings.TrimSpace(query.TrackName + " " + GetFirstArtist(query.ArtistName))
+	body, err := p.request(ctx, fmt.Sprintf("%s/api/search/get/web?s=%s&type=1&offset=0&limit=10", p.BaseURL, url.QueryEscape(term)))
+	if err != nil {
+		return nil, err
+	}
+
+	var searchResp neteaseSearchResponse
+	if err := json.Unmarshal(body, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to parse NetEase search: %v", err)
+	}
+	if len(searchResp.Result.Songs) == 0 {
+		return nil, fmt.Errorf("no NetEase results for %s", term)
+	}
+
+	return pickNetEaseSong(searchResp.Result.Songs, query), nil
+}
+
+func pickNetEaseSong(songs []neteaseSong, query TrackQuery) *neteaseSong {
+	title := strings.ToLower(simplifyTrackName(query.TrackName))
+	artist := strings.ToLower(GetFirstArtist(query.ArtistName))
+
+	best := -1
+	bestScore := -1
+	for i, song := range songs {
+		score := 0
+		if strings.ToLower(simplifyTrackName(song.Name)) == title {
+			score += 2
+		}
+		for _, a := range song.Artists {
+			if strings.ToLower(a.Name) == artist {
+				score += 2
+				break
+			}
+		}
+		if query.DurationSec > 0 && song.Duration > 0 {
+			diff := song.Duration/1000 - query.DurationSec
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= 3 {
+				score += 3
+			}
+		}
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+
+	return &songs[best]
+}
+
+func (p *NetEaseProvider) request(ctx context.Context, apiURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", "https://music.163.com/")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from NetEase: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NetEase returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NetEase response: %v", err)
+	}
+	return body, nil
+}
+
+func cleanNetEaseLyrics(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+		if closeBracket := strings.Index(trimmed, "]"); strings.HasPrefix(trimmed, "[") && closeBracket > 0 {
+			words := strings.TrimSpace(trimmed[closeBracket+1:])
+			if words == "" || isNetEaseCreditLine(words) {
+				continue
+			}
+		}
+		kept = append(kept, trimmed)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isNetEaseCreditLine(words string) bool {
+	for _, sep := range []string{":", "："} {
+		if idx := strings.Index(words, sep); idx > 0 && idx < 24 {
+			label := strings.TrimSpace(words[:idx])
+			switch label {
+			case "作词", "作曲", "编曲", "制作人", "词", "曲", "Lyricist", "Composer", "Arranger", "Producer":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseNetEaseYrc(text string) *LyricsResponse {
+	resp := &LyricsResponse{
+		SyncType: "SYLLABLE_SYNCED",
+		Lines:    []LyricsLine{},
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		raw = strings.TrimSpace(raw)
+		if !strings.HasPrefix(raw, "[") {
+			continue
+		}
+		closeBracket := strings.Index(raw, "]")
+		if closeBracket < 0 {
+			continue
+		}
+
+		var lineStart, lineDuration int64
+		if _, err := fmt.Sscanf(raw[1:closeBracket], "%d,%d", &lineStart, &lineDuration); err != nil {
+			continue
+		}
+
+		var syllables []LyricsSyllable
+		rest := raw[closeBracket+1:]
+		for strings.HasPrefix(rest, "(") {
+			closeParen := strings.Index(rest, ")")
+			if closeParen < 0 {
+				break
+			}
+			var start, duration, flag int64
+			if _, err := fmt.Sscanf(rest[1:closeParen], "%d,%d,%d", &start, &duration, &flag); err != nil {
+				break
+			}
+			rest = rest[closeParen+1:]
+
+			next := strings.Index(rest, "(")
+			text := rest
+			if next >= 0 {
+				text = rest[:next]
+				rest = rest[next:]
+			} else {
+				rest = ""
+			}
+			syllables = append(syllables, LyricsSyllable{
+				StartTimeMs: fmt.Sprintf("%d", start),
+				Words:       text,
+				EndTimeMs:   fmt.Sprintf("%d", start+duration),
+			})
+		}
+		if len(syllables) == 0 {
+			continue
+		}
+
+		var sb strings.Builder
+		for _, syllable := range syllables {
+			sb.WriteString(syllable.Words)
+		}
+		words := strings.Join(strings.Fields(sb.String()), " ")
+		if words == "" || isNetEaseCreditLine(words) {
+			continue
+		}
+
+		resp.Lines = append(resp.Lines, LyricsLine{
+			StartTimeMs: fmt.Sprintf("%d", lineStart),
+			Words:       words,
+			EndTimeMs:   fmt.Sprintf("%d", lineStart+lineDuration),
+			Syllables:   syllables,
+		})
+	}
+
+	if len(resp.Lines) == 0 {
+		resp.Error = true
+	}
+	return resp
+}
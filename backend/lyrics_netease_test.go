@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newNetEaseTestServer(t *testing.T, lyricBody string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/search/get/web":
+			w.Write([]byte(`{"code":200,"result":{"songs":[
+				{"id":1,"name":"Other Song","duration":100000,"artists":[{"name":"Someone"}]},
+				{"id":2,"name":"Song","duration":215000,"artists":[{"name":"Artist"}]}
+			]}}`))
+		case "/api/song/lyric":
+			if got := r.URL.Query().Get("id"); got != "2" {
+				t.Errorf("lyric id = %q, want 2", got)
+			}
+			w.Write([]byte(lyricBody))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestNetEaseFetchLrc(t *testing.T) {
+	server := newNetEaseTestServer(t, `{"code":200,"lrc":{"lyric":"[00:00.00] 作词 : Someone\n[00:01.00]First line\n[00:02.50]Second line\n"}}`)
+	defer server.Close()
+
+	resp, err := NewNetEaseProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist", DurationSec: 215})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if resp.SyncType != "LINE_SYNCED" {
+		t.Fatalf("SyncType = %q, want LINE_SYNCED", resp.SyncType)
+	}
+	if len(resp.Lines) != 2 {
+		t.Fatalf("got %d lines, want credit line dropped: %+v", len(resp.Lines), resp.Lines)
+	}
+	if resp.Lines[1].StartTimeMs != "2500" || resp.Lines[1].Words != "Second line" {
+		t.Errorf("line 1 = %+v", resp.Lines[1])
+	}
+}
+
+func TestNetEaseFetchPrefersYrc(t *testing.T) {
+	server := newNetEaseTestServer(t, `{"code":200,"lrc":{"lyric":"[00:01.00]First line"},"yrc":{"lyric":"[1000,900](1000,400,0)First (1400,500,0)line"}}`)
+	defer server.Close()
+
+	resp, err := NewNetEaseProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if resp.SyncType != "SYLLABLE_SYNCED" {
+		t.Fatalf("SyncType = %q, want SYLLABLE_SYNCED", resp.SyncType)
+	}
+	line := resp.Lines[0]
+	if line.Words != "First line" || line.StartTimeMs != "1000" || line.EndTimeMs != "1900" {
+		t.Errorf("line = %+v", line)
+	}
+	if len(line.Syllables) != 2 || line.Syllables[1].StartTimeMs != "1400" || line.Syllables[1].EndTimeMs != "1900" {
+		t.Errorf("syllables = %+v", line.Syllables)
+	}
+}
+
+func TestNetEaseFetchNoLyric(t *testing.T) {
+	server := newNetEaseTestServer(t, `{"code":200,"nolyric":true}`)
+	defer server.Close()
+
+	if _, err := NewNetEaseProvider(server.URL).Fetch(context.Background(), TrackQuery{TrackName: "Song", ArtistName: "Artist"}); err == nil {
+		t.Fatal("expected an error for an instrumental track")
+	}
+}
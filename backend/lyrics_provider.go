@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type TrackQuery struct {
+	SpotifyID   string `json:"spotify_id"`
+	TrackName   string `json:"track_name"`
+	ArtistName  string `json:"artist_name"`
+	AlbumName   string `json:"album_name"`
+	ISRC        string `json:"isrc"`
+	DurationSec int    `json:"duration"`
+}
+
+type LyricsProvider interface {
+	Name() string
+	Fetch(ctx context.Context, query TrackQuery) (*LyricsResponse, error)
+}
+
+var lyricsProviderFactories = map[string]func() LyricsProvider{
+	"lrclib":  func() LyricsProvider { return NewLRCLibProvider("") },
+	"netease": func() LyricsProvider { return NewNetEaseProvider("") },
+}
+
+var DefaultLyricsProviderOrder = []string{"lrclib", "netease"}
+
+func AvailableLyricsProviders() []string {
+	return append([]string(nil), DefaultLyricsProviderOrder...)
+}
+
+func GetLyricsProviderSetting() []string {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return AvailableLyricsProviders()
+	}
+
+	raw, ok := settings["lyricsProviders"].([]interface{})
+	if !ok {
+		return AvailableLyricsProviders()
+	}
+
+	names := make([]string, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, value := range raw {
+		name, ok := value.(string)
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, known := lyricsProviderFactories[name]; !known || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func NewLyricsProvider(name string) (LyricsProvider, error) {
+	factory, ok := lyricsProviderFactories[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown lyrics provider: %s", name)
+	}
+	return factory(), nil
+}
+
+func GetLyricsProviders() []LyricsProvider {
+	names := GetLyricsProviderSetting()
+	providers := make([]LyricsProvider, 0, len(names))
+	for _, name := range names {
+		if provider, err := NewLyricsProvider(name); err == nil {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+func parseLRCText(text string, synced bool) *LyricsResponse {
+	resp := &LyricsResponse{
+		Error:    false,
+		SyncType: "LINE_SYNCED",
+		Lines:    []LyricsLine{},
+	}
+	if !synced {
+		resp.SyncType = "UNSYNCED"
+	}
+
+	if strings.TrimSpace(text) == "" {
+		resp.Error = true
+		return resp
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && len(line) > 10 {
+			closeBracket := strings.Index(line, "]")
+			if closeBracket > 0 {
+				timestamp := line[1:closeBracket]
+				words := strings.TrimSpace(line[closeBracket+1:])
+				if timestamp[0] < '0' || timestamp[0] > '9' {
+					continue
+				}
+
+				ms := lrcTimestampToMs(timestamp)
+				lyricsLine := LyricsLine{
+					StartTimeMs: fmt.Sprintf("%d", ms),
+					Words:       words,
+				}
+				if plain, syllables, endMs := parseEnhancedLRCWords(words); len(syllables) > 0 {
+					lyricsLine.Words = plain
+					lyricsLine.Syllables = syllables
+					lyricsLine.EndTimeMs = endMs
+					resp.SyncType = "SYLLABLE_SYNCED"
+				}
+				resp.Lines = append(resp.Lines, lyricsLine)
+				continue
+			}
+		}
+
+		resp.Lines = append(resp.Lines, LyricsLine{
+			StartTimeMs: "",
+			Words:       line,
+		})
+	}
+
+	return resp
+}
+
+func parseEnhancedLRCWords(words string) (string, []LyricsSyllable, string) {
+	if !strings.Contains(words, "<") {
+		return words, nil, ""
+	}
+
+	var syllables []LyricsSyllable
+	var endMs string
+	rest := words
+	for {
+		open := strings.Index(rest, "<")
+		if open < 0 {
+			break
+		}
+		closeTag := strings.Index(rest[open:], ">")
+		if closeTag < 0 {
+			break
+		}
+		closeTag += open
+
+		tag := rest[open+1 : closeTag]
+		if !strings.Contains(tag, ":") {
+			return words, nil, ""
+		}
+		if len(syllables) == 0 && strings.TrimSpace(rest[:open]) != "" {
+			return words, nil, ""
+		}
+
+		ms := fmt.Sprintf("%d", lrcTimestampToMs(tag))
+		if len(syllables) > 0 && syllables[len(syllables)-1].EndTimeMs == "" {
+			syllables[len(syllables)-1].EndTimeMs = ms
+		}
+
+		rest = rest[closeTag+1:]
+		next := strings.Index(rest, "<")
+		text := rest
+		if next >= 0 {
+			text = rest[:next]
+		}
+		if strings.TrimSpace(text) == "" && next < 0 {
+			endMs = ms
+			break
+		}
+		if len(syllables) == 0 {
+			text = strings.TrimLeft(text, " ")
+		}
+		syllables = append(syllables, LyricsSyllable{StartTimeMs: ms, Words: text})
+		if next < 0 {
+			break
+		}
+		rest = rest[next:]
+	}
+
+	if len(syllables) == 0 {
+		return words, nil, ""
+	}
+
+	var sb strings.Builder
+	for _, syllable := range syllables {
+		sb.WriteString(syllable.Words)
+	}
+	return strings.Join(strings.Fields(sb.String()), " "), syllables, endMs
+}
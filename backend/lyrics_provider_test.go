@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubLyricsProvider struct {
+	name  string
+	resp  *LyricsResponse
+	err   error
+	calls *[]string
+}
+
+func (p stubLyricsProvider) Name() string {
+	return p.name
+}
+
+func (p stubLyricsProvider) Fetch(ctx context.Context, query TrackQuery) (*LyricsResponse, error) {
+	*p.calls = append(*p.calls, p.name)
+	return p.resp, p.err
+}
+
+func TestFetchLyricsAllSourcesOrder(t *testing.T) {
+	synced := parseLRCText("[00:01.00]Synced", true)
+	plain := parseLRCText("Plain", false)
+
+	tests := []struct {
+		name       string
+		providers  func(calls *[]string) []LyricsProvider
+		wantSource string
+		wantCalls  []string
+		wantErr    bool
+	}{
+		{
+			name: "first synced wins",
+			providers: func(calls *[]string) []LyricsProvider {
+				return []LyricsProvider{
+					stubLyricsProvider{name: "A", resp: synced, calls: calls},
+					stubLyricsProvider{name: "B", resp: synced, calls: calls},
+				}
+			},
+			wantSource: "A",
+			wantCalls:  []string{"A"},
+		},
+		{
+			name: "synced beats earlier unsynced",
+			providers: func(calls *[]string) []LyricsProvider {
+				return []LyricsProvider{
+					stubLyricsProvider{name: "A", resp: plain, calls: calls},
+					stubLyricsProvider{name: "B", err: errors.New("down"), calls: calls},
+					stubLyricsProvider{name: "C", resp: synced, calls: calls},
+				}
+			},
+			wantSource: "C",
+			wantCalls:  []string{"A", "B", "C"},
+		},
+		{
+			name: "first unsynced is the fallback",
+			providers: func(calls *[]string) []LyricsProvider {
+				return []LyricsProvider{
+					stubLyricsProvider{name: "A", err: errors.New("down"), calls: calls},
+					stubLyricsProvider{name: "B", resp: plain, calls: calls},
+					stubLyricsProvider{name: "C", resp: parseLRCText("Other", false), calls: calls},
+				}
+			},
+			wantSource: "B (unsynced)",
+			wantCalls:  []string{"A", "B", "C"},
+		},
+		{
+			name: "nothing found",
+			providers: func(calls *[]string) []LyricsProvider {
+				return []LyricsProvider{
+					stubLyricsProvider{name: "A", err: errors.New("down"), calls: calls},
+				}
+			},
+			wantCalls: []string{"A"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			client := NewLyricsClientWithProviders(context.Background(), tt.providers(&calls)...)
+			_, source, err := client.FetchLyricsAllSources("", "Song", "Artist", "", 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestGetLyricsProviderSettingOrder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	if err := SaveConfigSetting("lyricsProviders", []string{"NetEase", "unknown", "netease"}); err != nil {
+		t.Fatalf("SaveConfigSetting: %v", err)
+	}
+	names := GetLyricsProviderSetting()
+	if len(names) != 1 || names[0] != "netease" {
+		t.Fatalf("providers = %v, want [netease]", names)
+	}
+
+	if err := SaveConfigSetting("lyricsProviders", []string{}); err != nil {
+		t.Fatalf("SaveConfigSetting: %v", err)
+	}
+	if names := GetLyricsProviderSetting(); len(names) != 0 {
+		t.Fatalf("providers = %v, want none when all are disabled", names)
+	}
+}
@@ -0,0 +1,348 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	syltFrameID = "SYLT"
+
+	SYLTTimestampMPEGFrames   byte = 1
+	SYLTTimestampMillis       byte = 2
+	SYLTContentTypeLyrics     byte = 1
+	SYLTContentTypeTranscript byte = 2
+)
+
+type SyncedText struct {
+	Text      string
+	Timestamp uint32
+}
+
+type SynchronisedLyricsFrame struct {
+	Encoding          id3v2.Encoding
+	Language          string
+	TimestampFormat   byte
+	ContentType       byte
+	ContentDescriptor string
+	SyncedTexts       []SyncedText
+}
+
+func (f SynchronisedLyricsFrame) UniqueIdentifier() string {
+	return f.Language + f.ContentDescriptor
+}
+
+func (f SynchronisedLyricsFrame) Size() int {
+	return len(f.body())
+}
+
+func (f SynchronisedLyricsFrame) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.body())
+	return int64(n), err
+}
+
+func (f SynchronisedLyricsFrame) body() []byte {
+	var buf bytes.Buffer
+
+	buf.WriteByte(f.Encoding.Key)
+
+	language := f.Language
+	if len(language) != 3 {
+		language = "eng"
+	}
+	buf.WriteString(language)
+	buf.WriteByte(f.TimestampFormat)
+	buf.WriteByte(f.ContentType)
+
+	buf.Write(encodeSYLTText(f.ContentDescriptor, f.Encoding))
+	for _, entry := range f.SyncedTexts {
+		buf.Write(encodeSYLTText(entry.Text, f.Encoding))
+		var timestamp [4]byte
+		binary.BigEndian.PutUint32(timestamp[:], entry.Timestamp)
+		buf.Write(timestamp[:])
+	}
+
+	return buf.Bytes()
+}
+
+func syltTextEncoder(enc id3v2.Encoding) encoding.Encoding {
+	switch enc.Key {
+	case id3v2.EncodingISO.Key:
+		return charmap.ISO8859_1
+	case id3v2.EncodingUTF16.Key:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case id3v2.EncodingUTF16BE.Key:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	default:
+		return nil
+	}
+}
+
+func encodeSYLTText(text string, enc id3v2.Encoding) []byte {
+	encoded := []byte(text)
+	if encoder := syltTextEncoder(enc); encoder != nil {
+		if out, err := encoder.NewEncoder().Bytes([]byte(text)); err == nil {
+			encoded = out
+		}
+	}
+	return append(encoded, enc.TerminationBytes...)
+}
+
+func decodeSYLTText(data []byte, enc id3v2.Encoding) string {
+	var decoder encoding.Encoding
+	switch enc.Key {
+	case id3v2.EncodingISO.Key:
+		decoder = charmap.ISO8859_1
+	case id3v2.EncodingUTF16.Key:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case id3v2.EncodingUTF16BE.Key:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	if decoder == nil {
+		return string(data)
+	}
+	out, err := decoder.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func splitSYLTText(data []byte, enc id3v2.Encoding) (string, []byte, error) {
+	term := enc.TerminationBytes
+	step := len(term)
+	for i := 0; i+step <= len(data); i += step {
+		if bytes.Equal(data[i:i+step], term) {
+			return decodeSYLTText(data[:i], enc), data[i+step:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unterminated SYLT text")
+}
+
+func ParseSynchronisedLyricsFrame(body []byte) (SynchronisedLyricsFrame, error) {
+	var frame SynchronisedLyricsFrame
+	if len(body) < 6 {
+		return frame, fmt.Errorf("SYLT frame too short")
+	}
+
+	switch body[0] {
+	case id3v2.EncodingISO.Key:
+		frame.Encoding = id3v2.EncodingISO
+	case id3v2.EncodingUTF16.Key:
+		frame.Encoding = id3v2.EncodingUTF16
+	case id3v2.EncodingUTF16BE.Key:
+		frame.Encoding = id3v2.EncodingUTF16BE
+	case id3v2.EncodingUTF8.Key:
+		frame.Encoding = id3v2.EncodingUTF8
+	default:
+		return frame, fmt.Errorf("unknown SYLT text encoding: %d", body[0])
+	}
+
+	frame.Language = string(body[1:4])
+	frame.TimestampFormat = body[4]
+	frame.ContentType = body[5]
+
+	descriptor, rest, err := splitSYLTText(body[6:], frame.Encoding)
+	if err != nil {
+		return frame, err
+	}
+	frame.ContentDescriptor = descriptor
+
+	for len(rest) > 0 {
+		text, remaining, err := splitSYLTText(rest, frame.Encoding)
+		if err != nil {
+			return frame, err
+		}
+		if len(remaining) < 4 {
+			return frame, fmt.Errorf("SYLT entry is missing its timestamp")
+		}
+		frame.SyncedTexts = append(frame.SyncedTexts, SyncedText{
+			Text:      text,
+			Timestamp: binary.BigEndian.Uint32(remaining[:4]),
+		})
+		rest = remaining[4:]
+	}
+
+	return frame, nil
+}
+
+func parseLyricsMs(value string) (uint32, bool) {
+	ms, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return uint32(ms), true
+}
+
+func NewSynchronisedLyricsFrame(lyrics *LyricsResponse, enc id3v2.Encoding) (SynchronisedLyricsFrame, bool) {
+	frame := SynchronisedLyricsFrame{
+		Encoding:        enc,
+		Language:        "eng",
+		TimestampFormat: SYLTTimestampMillis,
+		ContentType:     SYLTContentTypeLyrics,
+	}
+	if !isSynced(lyrics) {
+		return frame, false
+	}
+
+	wordLevel := lyrics.SyncType == "SYLLABLE_SYNCED"
+	for _, line := range lyrics.Lines {
+		start, ok := parseLyricsMs(line.StartTimeMs)
+		if !ok {
+			continue
+		}
+
+		if !wordLevel || len(line.Syllables) == 0 {
+			text := line.Words
+			if wordLevel && len(frame.SyncedTexts) > 0 {
+				text = "\n" + text
+			}
+			frame.SyncedTexts = append(frame.SyncedTexts, SyncedText{Text: text, Timestamp: start})
+			continue
+		}
+
+		for i, syllable := range line.Syllables {
+			timestamp, ok := parseLyricsMs(syllable.StartTimeMs)
+			if !ok {
+				timestamp = start
+			}
+			text := syllable.Words
+			if i == 0 && len(frame.SyncedTexts) > 0 {
+				text = "\n" + text
+			}
+			frame.SyncedTexts = append(frame.SyncedTexts, SyncedText{Text: text, Timestamp: timestamp})
+		}
+
+		endTime := line.EndTimeMs
+		if last := line.Syllables[len(line.Syllables)-1]; last.EndTimeMs != "" {
+			endTime = last.EndTimeMs
+		}
+		if end, ok := parseLyricsMs(endTime); ok {
+			frame.SyncedTexts = append(frame.SyncedTexts, SyncedText{Text: "", Timestamp: end})
+		}
+	}
+
+	return frame, len(frame.SyncedTexts) > 0
+}
+
+func (f SynchronisedLyricsFrame) ToLyricsResponse() *LyricsResponse {
+	resp := &LyricsResponse{
+		SyncType: "LINE_SYNCED",
+		Lines:    []LyricsLine{},
+	}
+
+	wordLevel := false
+	for i, entry := range f.SyncedTexts {
+		if i > 0 && strings.HasPrefix(entry.Text, "\n") {
+			wordLevel = true
+			break
+		}
+	}
+
+	toMs := func(timestamp uint32) string {
+		return strconv.FormatUint(uint64(timestamp), 10)
+	}
+
+	if !wordLevel {
+		for _, entry := range f.SyncedTexts {
+			resp.Lines = append(resp.Lines, LyricsLine{
+				StartTimeMs: toMs(entry.Timestamp),
+				Words:       strings.TrimSpace(entry.Text),
+			})
+		}
+		return resp
+	}
+
+	resp.SyncType = "SYLLABLE_SYNCED"
+	var current *LyricsLine
+	flush := func() {
+		if current == nil {
+			return
+		}
+		var sb strings.Builder
+		for _, syllable := range current.Syllables {
+			sb.WriteString(syllable.Words)
+		}
+		current.Words = strings.Join(strings.Fields(sb.String()), " ")
+		if len(current.Syllables) <= 1 && current.EndTimeMs == "" {
+			current.Syllables = nil
+		}
+		resp.Lines = append(resp.Lines, *current)
+		current = nil
+	}
+
+	for _, entry := range f.SyncedTexts {
+		text := entry.Text
+		if strings.HasPrefix(text, "\n") || current == nil {
+			flush()
+			text = strings.TrimPrefix(text, "\n")
+			current = &LyricsLine{StartTimeMs: toMs(entry.Timestamp)}
+		}
+
+		if n := len(current.Syllables); n > 0 && current.Syllables[n-1].EndTimeMs == "" {
+			current.Syllables[n-1].EndTimeMs = toMs(entry.Timestamp)
+		}
+		if text == "" && len(current.Syllables) > 0 {
+			current.EndTimeMs = toMs(entry.Timestamp)
+			continue
+		}
+		current.Syllables = append(current.Syllables, LyricsSyllable{
+			StartTimeMs: toMs(entry.Timestamp),
+			Words:       text,
+		})
+	}
+	flush()
+
+	return resp
+}
+
+func syltEncodingForTag(tag *id3v2.Tag) id3v2.Encoding {
+	if tag.Version() == 4 {
+		return id3v2.EncodingUTF8
+	}
+	return id3v2.EncodingUTF16
+}
+
+func setSynchronisedLyricsFrame(tag *id3v2.Tag, lyrics string) {
+	tag.DeleteFrames(syltFrameID)
+
+	frame, ok := NewSynchronisedLyricsFrame(parseLRCText(lyrics, true), syltEncodingForTag(tag))
+	if ok {
+		tag.AddFrame(syltFrameID, frame)
+	}
+}
+
+func readSynchronisedLyricsFrame(tag *id3v2.Tag) (SynchronisedLyricsFrame, bool) {
+	for _, framer := range tag.GetFrames(syltFrameID) {
+		var frame SynchronisedLyricsFrame
+		switch f := framer.(type) {
+		case SynchronisedLyricsFrame:
+			frame = f
+		case id3v2.UnknownFrame:
+			parsed, err := ParseSynchronisedLyricsFrame(f.Body)
+			if err != nil {
+				continue
+			}
+			frame = parsed
+		default:
+			continue
+		}
+
+		if frame.TimestampFormat != SYLTTimestampMillis || len(frame.SyncedTexts) == 0 {
+			continue
+		}
+		if frame.ContentType == SYLTContentTypeLyrics || frame.ContentType == SYLTContentTypeTranscript {
+			return frame, true
+		}
+	}
+	return SynchronisedLyricsFrame{}, false
+}
@@ -38,6 +38,9 @@ type Metadata struct {
 	ISRC        string
 	UPC         string
 	Genre       string
+	ReplayGain  *ReplayGainInfo
+	Provenance  Provenance
+	MusicBrainz *MusicBrainzTags
 }
 
 func resolveMetadataSeparator(separator string) string {
@@ -126,6 +129,28 @@ func embedFlacMetadata(filePath string, metadata Metadata, coverPath string) err
 		}
 	}
 
+	cmt := buildFlacVorbisComment(metadata)
+	cmtBlock := cmt.Marshal()
+	if cmtIdx < 0 {
+		f.Meta = append(f.Meta, &cmtBlock)
+	} else {
+		f.Meta[cmtIdx] = &cmtBlock
+	}
+
+	if coverPath != "" && fileExists(coverPath) {
+		if err := embedCoverArt(f, coverPath); err != nil {
+			fmt.Printf("Warning: Failed to embed cover art: %v\n", err)
+		}
+	}
+
+	if err := f.Save(filePath); err != nil {
+		return fmt.Errorf("failed to save FLAC file: %w", err)
+	}
+
+	return nil
+}
+
+func buildFlacVorbisComment(metadata Metadata) *flacvorbis.MetaDataBlockVorbisComment {
 	cmt := flacvorbis.New()
 	separator := resolveMetadataSeparator(metadata.Separator)
 
@@ -198,24 +223,11 @@ func embedFlacMetadata(filePath string, metadata Metadata, coverPath string) err
 		_ = cmt.Add("GENRE", metadata.Genre)
 	}
 
-	cmtBlock := cmt.Marshal()
-	if cmtIdx < 0 {
-		f.Meta = append(f.Meta, &cmtBlock)
-	} else {
-		f.Meta[cmtIdx] = &cmtBlock
-	}
-
-	if coverPath != "" && fileExists(coverPath) {
-		if err := embedCoverArt(f, coverPath); err != nil {
-			fmt.Printf("Warning: Failed to embed cover art: %v\n", err)
-		}
-	}
-
-	if err := f.Save(filePath); err != nil {
-		return fmt.Errorf("failed to save FLAC file: %w", err)
-	}
+	addProvenanceVorbisComments(cmt, metadata.Provenance)
+	addMusicBrainzVorbisComments(cmt, metadata.MusicBrainz)
+	addReplayGainVorbisComments(cmt, metadata.ReplayGain)
 
-	return nil
+	return cmt
 }
 
 func embedMp3Metadata(filePath string, metadata Metadata, coverPath string) error {
@@ -224,6 +236,17 @@ func embedMp3Metadata(filePath string, metadata Metadata, coverPath string) erro
 		return fmt.Errorf("failed to open MP3 file: %w", err)
 	}
 	defer tag.Close()
+
+	applyMp3Metadata(tag, metadata, coverPath)
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+
+	return nil
+}
+
+func applyMp3Metadata(tag *id3v2.Tag, metadata Metadata, coverPath string) {
 	separator := resolveMetadataSeparator(metadata.Separator)
 
 	if metadata.Title != "" {
@@ -318,11 +341,9 @@ func embedMp3Metadata(filePath string, metadata Metadata, coverPath string) erro
 	}
 	addMP3TextFrame(tag, "TCON", genreText)
 
-	if err := tag.Save(); err != nil {
-		return fmt.Errorf("failed to save MP3 tags: %w", err)
-	}
-
-	return nil
+	setProvenanceMP3Frames(tag, metadata.Provenance)
+	setMusicBrainzMP3Frames(tag, metadata.MusicBrainz)
+	setReplayGainMP3Frames(tag, metadata.ReplayGain)
 }
 
 func embedCoverArt(f *flac.File, coverPath string) error {
@@ -401,6 +422,10 @@ func setMP3DateFrames(tag *id3v2.Tag, date string) {
 }
 
 func EmbedLyricsOnly(filepath string, lyrics string) error {
+	return EmbedLyricsWithSource(filepath, lyrics, "")
+}
+
+func EmbedLyricsWithSource(filepath string, lyrics string, source string) error {
 	if lyrics == "" {
 		return nil
 	}
@@ -415,17 +440,17 @@ func EmbedLyricsOnly(filepath string, lyrics string) error {
 	ext := strings.ToLower(pathfilepath.Ext(filepath))
 	switch ext {
 	case ".flac":
-		return embedLyricsToFlac(filepath, lyrics)
+		return embedLyricsToFlac(filepath, lyrics, source)
 	case ".mp3":
-		return embedLyricsToMp3(filepath, lyrics)
+		return embedLyricsToMp3(filepath, lyrics, source)
 	case ".m4a":
-		return embedLyricsToM4A(filepath, lyrics)
+		return embedLyricsToM4A(filepath, lyrics, source)
 	default:
 		return fmt.Errorf("unsupported file format for lyrics embedding: %s", ext)
 	}
 }
 
-func embedLyricsToFlac(filepath string, lyrics string) error {
+func embedLyricsToFlac(filepath string, lyrics string, source string) error {
 	f, err := flac.ParseFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse FLAC file: %w", err)
@@ -451,7 +476,7 @@ func embedLyricsToFlac(filepath string, lyrics string) error {
 			parts := strings.SplitN(comment, "=", 2)
 			if len(parts) == 2 {
 				fieldName := strings.ToUpper(parts[0])
-				if fieldName != "LYRICS" && fieldName != "UNSYNCEDLYRICS" && fieldName != "SYNCEDLYRICS" {
+				if fieldName != "LYRICS" && fieldName != "UNSYNCEDLYRICS" && fieldName != "SYNCEDLYRICS" && fieldName != "LYRICS_SOURCE" {
 					_ = cmt.Add(parts[0], parts[1])
 				}
 			}
@@ -459,6 +484,9 @@ func embedLyricsToFlac(filepath string, lyrics string) error {
 	}
 
 	_ = cmt.Add("LYRICS", lyrics)
+	if source != "" {
+		_ = cmt.Add("LYRICS_SOURCE", source)
+	}
 
 	cmtBlock := cmt.Marshal()
 	if cmtIdx < 0 {
@@ -474,7 +502,7 @@ func embedLyricsToFlac(filepath string, lyrics string) error {
 	return nil
 }
 
-func embedLyricsToMp3(filepath string, lyrics string) error {
+func embedLyricsToMp3(filepath string, lyrics string, source string) error {
 	tag, err := id3v2.Open(filepath, id3v2.Options{Parse: true})
 	if err != nil {
 		return fmt.Errorf("failed to open MP3 file: %w", err)
@@ -490,6 +518,11 @@ func embedLyricsToMp3(filepath string, lyrics string) error {
 		Lyrics:            lyrics,
 	}
 	tag.AddUnsynchronisedLyricsFrame(usltFrame)
+	setSynchronisedLyricsFrame(tag, lyrics)
+
+	if source != "" {
+		setUserDefinedTextFrame(tag, "LYRICS_SOURCE", source)
+	}
 
 	if err := tag.Save(); err != nil {
 		return fmt.Errorf("failed to save MP3 tags: %w", err)
@@ -498,7 +531,7 @@ func embedLyricsToMp3(filepath string, lyrics string) error {
 	return nil
 }
 
-func embedLyricsToM4A(filepath string, lyrics string) error {
+func embedLyricsToM4A(filepath string, lyrics string, source string) error {
 
 	ffmpegPath, err := GetFFmpegPath()
 	if err != nil {
@@ -513,17 +546,19 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 		}
 	}()
 
-	cmd := exec.Command(ffmpegPath,
+	args := []string{
 		"-i", filepath,
 		"-map", "0",
 		"-map_metadata", "0",
-		"-metadata", "lyrics-eng="+lyrics,
-		"-metadata", "lyrics="+lyrics,
-		"-codec", "copy",
-		"-f", "ipod",
-		"-y",
-		tmpOutputFile,
-	)
+		"-metadata", "lyrics-eng=" + lyrics,
+		"-metadata", "lyrics=" + lyrics,
+	}
+	if source != "" {
+		args = append(args, "-metadata", "lyrics_source="+source)
+	}
+	args = append(args, "-codec", "copy", "-f", "ipod", "-y", tmpOutputFile)
+
+	cmd := exec.Command(ffmpegPath, args...)
 
 	setHideWindow(cmd)
 
@@ -541,6 +576,21 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 	return nil
 }
 
+func setUserDefinedTextFrame(tag *id3v2.Tag, description, value string) {
+	frames := tag.GetFrames("TXXX")
+	tag.DeleteFrames("TXXX")
+	for _, frame := range frames {
+		if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok && !strings.EqualFold(udtf.Description, description) {
+			tag.AddUserDefinedTextFrame(udtf)
+		}
+	}
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		Description: description,
+		Value:       value,
+	})
+}
+
 func ExtractCoverArt(filePath string) (string, error) {
 	filePath = norm.NFC.String(filePath)
 	ext := strings.ToLower(pathfilepath.Ext(filePath))
@@ -748,20 +798,22 @@ func extractLyricsFromMp3(filePath string) (string, error) {
 	}
 	defer tag.Close()
 
+	var uslt id3v2.UnsynchronisedLyricsFrame
 	usltFrames := tag.GetFrames(tag.CommonID("Unsynchronised lyrics/text transcription"))
-	if len(usltFrames) == 0 {
-		fmt.Printf("[ExtractLyrics] No USLT frames found in MP3: %s\n", filePath)
-		return "", nil
+	if len(usltFrames) > 0 {
+		uslt, _ = usltFrames[0].(id3v2.UnsynchronisedLyricsFrame)
 	}
 
-	uslt, ok := usltFrames[0].(id3v2.UnsynchronisedLyricsFrame)
-	if !ok {
-		fmt.Printf("[ExtractLyrics] USLT frame type assertion failed in MP3: %s\n", filePath)
-		return "", nil
+	if !isSynced(parseLRCText(uslt.Lyrics, true)) {
+		if sylt, ok := readSynchronisedLyricsFrame(tag); ok {
+			lyrics := lyricsToLRC(sylt.ToLyricsResponse(), tag.Title(), tag.Artist())
+			fmt.Printf("[ExtractLyrics] Successfully extracted SYLT lyrics from MP3: %s (%d entries)\n", filePath, len(sylt.SyncedTexts))
+			return lyrics, nil
+		}
 	}
 
 	if uslt.Lyrics == "" {
-		fmt.Printf("[ExtractLyrics] USLT frame has empty lyrics in MP3: %s\n", filePath)
+		fmt.Printf("[ExtractLyrics] No USLT lyrics found in MP3: %s\n", filePath)
 		return "", nil
 	}
 
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -74,58 +75,88 @@ func ShouldSkipMusicBrainzMetadataFetch() bool {
 	return time.Since(musicBrainzLastCheckedAt) <= musicBrainzStatusCheckSkipWindow
 }
 
+type musicBrainzArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+type musicBrainzRelease struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	ReleaseGroup struct {
+		ID               string   `json:"id"`
+		Title            string   `json:"title"`
+		PrimaryType      string   `json:"primary-type"`
+		SecondaryTypes   []string `json:"secondary-types"`
+		FirstReleaseDate string   `json:"first-release-date"`
+	} `json:"release-group"`
+	Date         string                    `json:"date"`
+	Country      string                    `json:"country"`
+	Barcode      string                    `json:"barcode"`
+	TrackCount   int                       `json:"track-count"`
+	ArtistCredit []musicBrainzArtistCredit `json:"artist-credit"`
+	Media        []struct {
+		Position   int    `json:"position"`
+		Format     string `json:"format"`
+		TrackCount int    `json:"track-count"`
+		Track      []struct {
+			ID     string `json:"id"`
+			Number string `json:"number"`
+		} `json:"track"`
+	} `json:"media"`
+	LabelInfo []struct {
+		CatalogNumber string `json:"catalog-number"`
+		Label         struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"label"`
+	} `json:"label-info"`
+}
+
+type musicBrainzRecording struct {
+	ID           string                    `json:"id"`
+	Title        string                    `json:"title"`
+	Length       int                       `json:"length"`
+	Releases     []musicBrainzRelease      `json:"releases"`
+	ArtistCredit []musicBrainzArtistCredit `json:"artist-credit"`
+	Tags         []struct {
+		Count int    `json:"count"`
+		Name  string `json:"name"`
+	} `json:"tags"`
+}
+
 type MusicBrainzRecordingResponse struct {
-	Recordings []struct {
-		ID       string `json:"id"`
-		Title    string `json:"title"`
-		Length   int    `json:"length"`
-		Releases []struct {
-			ID           string `json:"id"`
-			Title        string `json:"title"`
-			Status       string `json:"status"`
-			ReleaseGroup struct {
-				ID          string `json:"id"`
-				Title       string `json:"title"`
-				PrimaryType string `json:"primary-type"`
-			} `json:"release-group"`
-			Date    string `json:"date"`
-			Country string `json:"country"`
-			Media   []struct {
-				Format string `json:"format"`
-			} `json:"media"`
-			LabelInfo []struct {
-				Label struct {
-					Name string `json:"name"`
-				} `json:"label"`
-			} `json:"label-info"`
-		} `json:"releases"`
-		ArtistCredit []struct {
-			Artist struct {
-				ID   string `json:"id"`
-				Name string `json:"name"`
-			} `json:"artist"`
-		} `json:"artist-credit"`
-		Tags []struct {
-			Count int    `json:"count"`
-			Name  string `json:"name"`
-		} `json:"tags"`
-	} `json:"recordings"`
+	Recordings []musicBrainzRecording `json:"recordings"`
 }
 
-func musicBrainzCacheKey(isrc, title, artist string, useSingleGenre bool) string {
+func musicBrainzCacheKey(isrc, title, artist string, useSingleGenre bool, embedGenre bool, fullTagging bool, hint MusicBrainzReleaseHint) string {
 	separator := strings.TrimSpace(GetSeparator())
 	if separator == "" {
 		separator = ";"
 	}
 
+	options := fmt.Sprintf("|%t|%t|%s", useSingleGenre, embedGenre, separator)
+	if fullTagging {
+		options += fmt.Sprintf("|full:%s:%d:%s", strings.ToLower(strings.TrimSpace(hint.Album)), hint.TrackCount, strings.TrimSpace(hint.ReleaseDate))
+	}
+
 	if normalizedISRC := strings.ToUpper(strings.TrimSpace(isrc)); normalizedISRC != "" {
-		return "isrc:" + normalizedISRC + "|" + fmt.Sprintf("%t", useSingleGenre) + "|" + separator
+		return "isrc:" + normalizedISRC + options
 	}
 
-	return "title:" + strings.ToLower(strings.TrimSpace(title)) + "|artist:" + strings.ToLower(strings.TrimSpace(artist)) + "|" + fmt.Sprintf("%t", useSingleGenre) + "|" + separator
+	return "title:" + strings.ToLower(strings.TrimSpace(title)) + "|artist:" + strings.ToLower(strings.TrimSpace(artist)) + options
 }
 
-func waitForMusicBrainzRequestSlot() {
+func waitForMusicBrainzRequestSlot(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	musicBrainzThrottleMu.Lock()
 
 	readyAt := musicBrainzNextRequest
@@ -138,13 +169,34 @@ func waitForMusicBrainzRequestSlot() {
 		readyAt = now
 	}
 
-	musicBrainzNextRequest = readyAt.Add(musicBrainzMinRequestInterval)
+	reserved := readyAt.Add(musicBrainzMinRequestInterval)
+	musicBrainzNextRequest = reserved
 	waitDuration := time.Until(readyAt)
 
 	musicBrainzThrottleMu.Unlock()
 
-	if waitDuration > 0 {
-		time.Sleep(waitDuration)
+	if waitDuration <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(waitDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		releaseMusicBrainzRequestSlot(readyAt, reserved)
+		return ctx.Err()
+	}
+}
+
+func releaseMusicBrainzRequestSlot(readyAt, reserved time.Time) {
+	musicBrainzThrottleMu.Lock()
+	defer musicBrainzThrottleMu.Unlock()
+
+	if musicBrainzNextRequest.Equal(reserved) {
+		musicBrainzNextRequest = readyAt
 	}
 }
 
@@ -174,27 +226,31 @@ func shouldRetryMusicBrainzRequest(err error) bool {
 	return statusErr.StatusCode == http.StatusServiceUnavailable || statusErr.StatusCode >= http.StatusInternalServerError
 }
 
-func FetchMusicBrainzMetadata(isrc, title, artist, album string, useSingleGenre bool, embedGenre bool) (Metadata, error) {
+func FetchMusicBrainzMetadata(ctx context.Context, isrc, title, artist, album string, useSingleGenre bool, embedGenre bool, fullTagging bool, hint MusicBrainzReleaseHint) (Metadata, error) {
 	var meta Metadata
 
-	if !embedGenre {
+	if !embedGenre && !fullTagging {
 		return meta, nil
 	}
 
-	cacheKey := musicBrainzCacheKey(isrc, title, artist, useSingleGenre)
-	if cached, ok := musicBrainzCache.Load(cacheKey); ok {
-		return cached.(Metadata), nil
+	if strings.TrimSpace(hint.Album) == "" {
+		hint.Album = album
 	}
 
-	if ShouldSkipMusicBrainzMetadataFetch() {
-		return meta, fmt.Errorf("skipping MusicBrainz lookup because the latest status check reported offline")
+	cacheKey := musicBrainzCacheKey(isrc, title, artist, useSingleGenre, embedGenre, fullTagging, hint)
+	if cached, ok := musicBrainzCache.Load(cacheKey); ok {
+		return cached.(Metadata), nil
 	}
 
 	musicBrainzInflightMu.Lock()
 	if call, ok := musicBrainzInflight[cacheKey]; ok {
 		musicBrainzInflightMu.Unlock()
-		<-call.done
-		return call.result, call.err
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return meta, ctx.Err()
+		}
 	}
 
 	call := &musicBrainzInflightCall{done: make(chan struct{})}
@@ -227,49 +283,15 @@ func FetchMusicBrainzMetadata(isrc, title, artist, album string, useSingleGenre
 	}
 
 	if len(queries) == 0 {
-		resultErr = fmt.Errorf("no query source available for genre lookup")
+		resultErr = fmt.Errorf("no query source available for MusicBrainz lookup")
 		return meta, resultErr
 	}
 
-	var recording *struct {
-		ID       string `json:"id"`
-		Title    string `json:"title"`
-		Length   int    `json:"length"`
-		Releases []struct {
-			ID           string `json:"id"`
-			Title        string `json:"title"`
-			Status       string `json:"status"`
-			ReleaseGroup struct {
-				ID          string `json:"id"`
-				Title       string `json:"title"`
-				PrimaryType string `json:"primary-type"`
-			} `json:"release-group"`
-			Date    string `json:"date"`
-			Country string `json:"country"`
-			Media   []struct {
-				Format string `json:"format"`
-			} `json:"media"`
-			LabelInfo []struct {
-				Label struct {
-					Name string `json:"name"`
-				} `json:"label"`
-			} `json:"label-info"`
-		} `json:"releases"`
-		ArtistCredit []struct {
-			Artist struct {
-				ID   string `json:"id"`
-				Name string `json:"name"`
-			} `json:"artist"`
-		} `json:"artist-credit"`
-		Tags []struct {
-			Count int    `json:"count"`
-			Name  string `json:"name"`
-		} `json:"tags"`
-	}
+	var recording *musicBrainzRecording
 	var lastLookupErr error
 
 	for _, query := range queries {
-		mbResp, err := queryMusicBrainzRecordings(client, query)
+		mbResp, err := lookupMusicBrainzRecordings(ctx, client, query)
 		if err != nil {
 			lastLookupErr = err
 			break
@@ -289,7 +311,7 @@ func FetchMusicBrainzMetadata(isrc, title, artist, album string, useSingleGenre
 		}
 		if bestIdx >= 0 {
 			recording = &mbResp.Recordings[bestIdx]
-			if len(recording.Tags) > 0 {
+			if len(recording.Tags) > 0 || !embedGenre {
 				break
 			}
 		}
@@ -305,11 +327,25 @@ func FetchMusicBrainzMetadata(isrc, title, artist, album string, useSingleGenre
 		return meta, resultErr
 	}
 
-	if lastLookupErr != nil && len(recording.Tags) == 0 {
+	if lastLookupErr != nil && embedGenre && len(recording.Tags) == 0 {
 		resultErr = lastLookupErr
 		return meta, resultErr
 	}
 
+	meta.Provenance.MusicBrainzRecordingID = recording.ID
+	release := selectMusicBrainzRelease(recording.Releases, hint)
+	if release != nil {
+		meta.Provenance.MusicBrainzReleaseID = release.ID
+	}
+	if fullTagging {
+		meta.MusicBrainz = buildMusicBrainzTags(recording, release)
+	}
+
+	if !embedGenre {
+		musicBrainzCache.Store(cacheKey, meta)
+		return meta, nil
+	}
+
 	var genres []string
 	seen := make(map[string]struct{}, len(recording.Tags))
 
@@ -351,10 +387,41 @@ func escapeMusicBrainzQuery(s string) string {
 	return strings.ReplaceAll(s, "\"", "\\\"")
 }
 
-func queryMusicBrainzRecordings(client *http.Client, query string) (*MusicBrainzRecordingResponse, error) {
+func lookupMusicBrainzRecordings(ctx context.Context, client *http.Client, query string) (*MusicBrainzRecordingResponse, error) {
+	cached, fresh, cacheErr := getCachedMusicBrainzResponse(query)
+	if cacheErr != nil {
+		fmt.Printf("[MusicBrainz] Cache read failed: %v\n", cacheErr)
+	}
+	if cached != nil && fresh {
+		return cached, nil
+	}
+
+	if ShouldSkipMusicBrainzMetadataFetch() {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, fmt.Errorf("skipping MusicBrainz lookup because the latest status check reported offline")
+	}
+
+	mbResp, err := queryMusicBrainzRecordings(ctx, client, query)
+	if err != nil {
+		if cached != nil && ctx.Err() == nil {
+			fmt.Printf("[MusicBrainz] Using stale cached response: %v\n", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if err := putCachedMusicBrainzResponse(query, mbResp); err != nil {
+		fmt.Printf("[MusicBrainz] Cache write failed: %v\n", err)
+	}
+	return mbResp, nil
+}
+
+func queryMusicBrainzRecordings(ctx context.Context, client *http.Client, query string) (*MusicBrainzRecordingResponse, error) {
 	reqURL := fmt.Sprintf("%s/recording?query=%s&fmt=json&inc=releases+artist-credits+tags+media+release-groups+labels", musicBrainzAPIBase, url.QueryEscape(query))
 
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +430,9 @@ func queryMusicBrainzRecordings(client *http.Client, query string) (*MusicBrainz
 
 	var lastErr error
 	for attempt := 0; attempt < musicBrainzRequestRetries; attempt++ {
-		waitForMusicBrainzRequestSlot()
+		if err := waitForMusicBrainzRequestSlot(ctx); err != nil {
+			return nil, err
+		}
 
 		resp, err := client.Do(req)
 		if err == nil && resp != nil && resp.StatusCode == http.StatusOK {
@@ -388,8 +457,16 @@ func queryMusicBrainzRecordings(client *http.Client, query string) (*MusicBrainz
 			resp.Body.Close()
 		}
 
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		if attempt < musicBrainzRequestRetries-1 && shouldRetryMusicBrainzRequest(lastErr) {
-			time.Sleep(musicBrainzRequestRetryWait)
+			select {
+			case <-time.After(musicBrainzRequestRetryWait):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 			continue
 		}
 
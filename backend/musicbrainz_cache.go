@@ -0,0 +1,265 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	musicBrainzCacheDBFile = "musicbrainz_cache.db"
+	musicBrainzCacheBucket = "RecordingQueries"
+
+	musicBrainzCacheTTL         = 30 * 24 * time.Hour
+	musicBrainzNegativeCacheTTL = 3 * 24 * time.Hour
+)
+
+type musicBrainzCacheEntry struct {
+	Query     string          `json:"query"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Empty     bool            `json:"empty"`
+	FetchedAt int64           `json:"fetched_at"`
+	ExpiresAt int64           `json:"expires_at"`
+}
+
+type MusicBrainzCacheEntryInfo struct {
+	Query      string `json:"query"`
+	Recordings int    `json:"recordings"`
+	Empty      bool   `json:"empty"`
+	Expired    bool   `json:"expired"`
+	FetchedAt  int64  `json:"fetched_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+type MusicBrainzCacheStats struct {
+	Path     string                      `json:"path"`
+	Size     int64                       `json:"size"`
+	Entries  int                         `json:"entries"`
+	Negative int                         `json:"negative"`
+	Expired  int                         `json:"expired"`
+	Recent   []MusicBrainzCacheEntryInfo `json:"recent"`
+}
+
+var (
+	musicBrainzCacheDB   *bolt.DB
+	musicBrainzCacheDBMu sync.Mutex
+)
+
+func InitMusicBrainzCacheDB() error {
+	musicBrainzCacheDBMu.Lock()
+	defer musicBrainzCacheDBMu.Unlock()
+
+	if musicBrainzCacheDB != nil {
+		return nil
+	}
+
+	appDir, err := EnsureAppDir()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(appDir, musicBrainzCacheDBFile)
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(musicBrainzCacheBucket))
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+
+	musicBrainzCacheDB = db
+	return nil
+}
+
+func CloseMusicBrainzCacheDB() {
+	musicBrainzCacheDBMu.Lock()
+	defer musicBrainzCacheDBMu.Unlock()
+
+	if musicBrainzCacheDB != nil {
+		_ = musicBrainzCacheDB.Close()
+		musicBrainzCacheDB = nil
+	}
+}
+
+func musicBrainzQueryCacheKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+func getCachedMusicBrainzResponse(query string) (*MusicBrainzRecordingResponse, bool, error) {
+	if err := InitMusicBrainzCacheDB(); err != nil {
+		return nil, false, err
+	}
+
+	var entry *musicBrainzCacheEntry
+	err := musicBrainzCacheDB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(musicBrainzCacheBucket)).Get([]byte(musicBrainzQueryCacheKey(query)))
+		if len(value) == 0 {
+			return nil
+		}
+		entry = &musicBrainzCacheEntry{}
+		return json.Unmarshal(value, entry)
+	})
+	if err != nil || entry == nil {
+		return nil, false, err
+	}
+
+	fresh := time.Now().Unix() < entry.ExpiresAt
+	if entry.Empty {
+		return &MusicBrainzRecordingResponse{}, fresh, nil
+	}
+
+	var resp MusicBrainzRecordingResponse
+	if err := json.Unmarshal(entry.Response, &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached MusicBrainz response: %w", err)
+	}
+	return &resp, fresh, nil
+}
+
+func putCachedMusicBrainzResponse(query string, resp *MusicBrainzRecordingResponse) error {
+	if resp == nil {
+		return nil
+	}
+	if err := InitMusicBrainzCacheDB(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry := musicBrainzCacheEntry{
+		Query:     query,
+		Empty:     len(resp.Recordings) == 0,
+		FetchedAt: now.Unix(),
+		ExpiresAt: now.Add(musicBrainzCacheTTL).Unix(),
+	}
+	if entry.Empty {
+		entry.ExpiresAt = now.Add(musicBrainzNegativeCacheTTL).Unix()
+	} else {
+		raw, err := json.Marshal(resp)
+		if err != nil {
+			return fmt.Errorf("failed to encode MusicBrainz response: %w", err)
+		}
+		entry.Response = raw
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode MusicBrainz cache entry: %w", err)
+	}
+
+	return musicBrainzCacheDB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(musicBrainzCacheBucket)).Put([]byte(musicBrainzQueryCacheKey(query)), payload)
+	})
+}
+
+func GetMusicBrainzCacheStats(recentLimit int) (*MusicBrainzCacheStats, error) {
+	if err := InitMusicBrainzCacheDB(); err != nil {
+		return nil, err
+	}
+
+	stats := &MusicBrainzCacheStats{Path: musicBrainzCacheDB.Path(), Recent: []MusicBrainzCacheEntryInfo{}}
+	if info, err := os.Stat(stats.Path); err == nil {
+		stats.Size = info.Size()
+	}
+
+	now := time.Now().Unix()
+	var infos []MusicBrainzCacheEntryInfo
+	err := musicBrainzCacheDB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(musicBrainzCacheBucket)).ForEach(func(_, value []byte) error {
+			var entry musicBrainzCacheEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return nil
+			}
+
+			info := MusicBrainzCacheEntryInfo{
+				Query:     entry.Query,
+				Empty:     entry.Empty,
+				Expired:   now >= entry.ExpiresAt,
+				FetchedAt: entry.FetchedAt,
+				ExpiresAt: entry.ExpiresAt,
+			}
+			if !entry.Empty {
+				var resp MusicBrainzRecordingResponse
+				if json.Unmarshal(entry.Response, &resp) == nil {
+					info.Recordings = len(resp.Recordings)
+				}
+			}
+
+			stats.Entries++
+			if info.Empty {
+				stats.Negative++
+			}
+			if info.Expired {
+				stats.Expired++
+			}
+			infos = append(infos, info)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].FetchedAt > infos[j].FetchedAt
+	})
+	if recentLimit > 0 && len(infos) > recentLimit {
+		infos = infos[:recentLimit]
+	}
+	stats.Recent = append(stats.Recent, infos...)
+
+	return stats, nil
+}
+
+func PurgeMusicBrainzCache(expiredOnly bool) (int, error) {
+	if err := InitMusicBrainzCacheDB(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	removed := 0
+	err := musicBrainzCacheDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(musicBrainzCacheBucket))
+		var keys [][]byte
+		if err := bucket.ForEach(func(key, value []byte) error {
+			if expiredOnly {
+				var entry musicBrainzCacheEntry
+				if json.Unmarshal(value, &entry) == nil && now < entry.ExpiresAt {
+					return nil
+				}
+			}
+			keys = append(keys, append([]byte(nil), key...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	musicBrainzCache.Range(func(key, _ any) bool {
+		musicBrainzCache.Delete(key)
+		return true
+	})
+
+	fmt.Printf("[MusicBrainz] Purged %d cached responses\n", removed)
+	return removed, nil
+}
@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+)
+
+type MusicBrainzReleaseHint struct {
+	Album       string
+	TrackCount  int
+	ReleaseDate string
+}
+
+type MusicBrainzTags struct {
+	ReleaseTrackID string   `json:"release_track_id,omitempty"`
+	ReleaseGroupID string   `json:"release_group_id,omitempty"`
+	ArtistIDs      []string `json:"artist_ids,omitempty"`
+	AlbumArtistIDs []string `json:"album_artist_ids,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+	CatalogNumbers []string `json:"catalog_numbers,omitempty"`
+	ReleaseCountry string   `json:"release_country,omitempty"`
+	Media          string   `json:"media,omitempty"`
+	ReleaseTypes   []string `json:"release_types,omitempty"`
+	ReleaseStatus  string   `json:"release_status,omitempty"`
+	OriginalDate   string   `json:"original_date,omitempty"`
+}
+
+func GetMusicBrainzFullTaggingSetting() bool {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return false
+	}
+	enabled, _ := settings["musicBrainzFullTagging"].(bool)
+	return enabled
+}
+
+func scoreMusicBrainzRelease(release musicBrainzRelease, hint MusicBrainzReleaseHint) int {
+	score := 0
+
+	switch strings.ToLower(release.Status) {
+	case "official":
+		score += 4
+	case "promotion", "bootleg", "pseudo-release":
+		score -= 2
+	}
+
+	if hint.TrackCount > 0 && release.TrackCount > 0 {
+		if diff := absInt(hint.TrackCount - release.TrackCount); diff == 0 {
+			score += 6
+		} else {
+			score -= min(diff, 4)
+		}
+	}
+
+	hintDate := strings.TrimSpace(hint.ReleaseDate)
+	if hintDate != "" && release.Date != "" {
+		switch {
+		case hintDate == release.Date:
+			score += 4
+		case extractYear(hintDate) == extractYear(release.Date):
+			score += 2
+		}
+	}
+
+	if album := strings.TrimSpace(hint.Album); album != "" && strings.EqualFold(album, strings.TrimSpace(release.Title)) {
+		score += 3
+	}
+
+	return score
+}
+
+func selectMusicBrainzRelease(releases []musicBrainzRelease, hint MusicBrainzReleaseHint) *musicBrainzRelease {
+	var best *musicBrainzRelease
+	bestScore := 0
+	for i := range releases {
+		score := scoreMusicBrainzRelease(releases[i], hint)
+		if best == nil || score > bestScore {
+			best = &releases[i]
+			bestScore = score
+		}
+	}
+	return best
+}
+
+func musicBrainzArtistIDs(credits []musicBrainzArtistCredit) []string {
+	var ids []string
+	for _, credit := range credits {
+		if id := strings.TrimSpace(credit.Artist.ID); id != "" {
+			ids = appendUniqueIDs(ids, id)
+		}
+	}
+	return ids
+}
+
+func musicBrainzOriginalDate(recording *musicBrainzRecording, release *musicBrainzRelease) string {
+	if date := strings.TrimSpace(release.ReleaseGroup.FirstReleaseDate); date != "" {
+		return date
+	}
+
+	earliest := strings.TrimSpace(release.Date)
+	for _, other := range recording.Releases {
+		if other.ReleaseGroup.ID != release.ReleaseGroup.ID || other.Date == "" {
+			continue
+		}
+		if earliest == "" || other.Date < earliest {
+			earliest = other.Date
+		}
+	}
+	return earliest
+}
+
+func buildMusicBrainzTags(recording *musicBrainzRecording, release *musicBrainzRelease) *MusicBrainzTags {
+	tags := &MusicBrainzTags{
+		ArtistIDs: musicBrainzArtistIDs(recording.ArtistCredit),
+	}
+	if release == nil {
+		return tags
+	}
+
+	tags.ReleaseGroupID = release.ReleaseGroup.ID
+	tags.AlbumArtistIDs = musicBrainzArtistIDs(release.ArtistCredit)
+	tags.ReleaseCountry = release.Country
+	tags.ReleaseStatus = strings.ToLower(release.Status)
+	tags.OriginalDate = musicBrainzOriginalDate(recording, release)
+
+	for _, medium := range release.Media {
+		if tags.Media == "" {
+			tags.Media = medium.Format
+		}
+		if tags.ReleaseTrackID == "" && len(medium.Track) > 0 {
+			tags.ReleaseTrackID = medium.Track[0].ID
+		}
+	}
+
+	for _, info := range release.LabelInfo {
+		if name := strings.TrimSpace(info.Label.Name); name != "" {
+			tags.Labels = appendUniqueIDs(tags.Labels, name)
+		}
+		if catalog := strings.TrimSpace(info.CatalogNumber); catalog != "" && !strings.EqualFold(catalog, "[none]") {
+			tags.CatalogNumbers = appendUniqueIDs(tags.CatalogNumbers, catalog)
+		}
+	}
+
+	if primary := strings.TrimSpace(release.ReleaseGroup.PrimaryType); primary != "" {
+		tags.ReleaseTypes = append(tags.ReleaseTypes, strings.ToLower(primary))
+	}
+	for _, secondary := range release.ReleaseGroup.SecondaryTypes {
+		if secondary = strings.TrimSpace(secondary); secondary != "" {
+			tags.ReleaseTypes = appendUniqueIDs(tags.ReleaseTypes, strings.ToLower(secondary))
+		}
+	}
+
+	return tags
+}
+
+func addMusicBrainzVorbisComments(cmt *flacvorbis.MetaDataBlockVorbisComment, tags *MusicBrainzTags) {
+	if tags == nil {
+		return
+	}
+
+	add := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			_ = cmt.Add(key, value)
+		}
+	}
+
+	add("MUSICBRAINZ_RELEASETRACKID", tags.ReleaseTrackID)
+	add("MUSICBRAINZ_RELEASEGROUPID", tags.ReleaseGroupID)
+	addVorbisTagValues(cmt, "MUSICBRAINZ_ARTISTID", tags.ArtistIDs)
+	addVorbisTagValues(cmt, "MUSICBRAINZ_ALBUMARTISTID", tags.AlbumArtistIDs)
+	addVorbisTagValues(cmt, "LABEL", tags.Labels)
+	addVorbisTagValues(cmt, "CATALOGNUMBER", tags.CatalogNumbers)
+	add("RELEASECOUNTRY", tags.ReleaseCountry)
+	add("MEDIA", tags.Media)
+	addVorbisTagValues(cmt, "RELEASETYPE", tags.ReleaseTypes)
+	add("RELEASESTATUS", tags.ReleaseStatus)
+	add("ORIGINALDATE", tags.OriginalDate)
+	add("ORIGINALYEAR", extractYear(tags.OriginalDate))
+}
+
+func setMusicBrainzMP3Frames(tag *id3v2.Tag, tags *MusicBrainzTags) {
+	if tags == nil {
+		return
+	}
+
+	set := func(description, value string) {
+		if value != "" {
+			setUserDefinedTextFrame(tag, description, value)
+		}
+	}
+
+	set("MusicBrainz Release Track Id", strings.TrimSpace(tags.ReleaseTrackID))
+	set("MusicBrainz Release Group Id", strings.TrimSpace(tags.ReleaseGroupID))
+	set("MusicBrainz Artist Id", joinMultiValueText(tags.ArtistIDs, "", true))
+	set("MusicBrainz Album Artist Id", joinMultiValueText(tags.AlbumArtistIDs, "", true))
+	set("CATALOGNUMBER", joinMultiValueText(tags.CatalogNumbers, "", true))
+	set("MusicBrainz Album Release Country", strings.TrimSpace(tags.ReleaseCountry))
+	set("MusicBrainz Album Type", joinMultiValueText(tags.ReleaseTypes, "", true))
+	set("MusicBrainz Album Status", strings.TrimSpace(tags.ReleaseStatus))
+	set("originalyear", extractYear(tags.OriginalDate))
+
+	if tags.Media != "" {
+		addMP3TextFrame(tag, "TMED", tags.Media)
+	}
+	if tags.OriginalDate != "" {
+		addMP3TextFrame(tag, "TDOR", tags.OriginalDate)
+	}
+	if len(tags.Labels) > 0 && tag.GetTextFrame("TPUB").Text == "" {
+		addMP3TextFrame(tag, "TPUB", joinMultiValueText(tags.Labels, "", true))
+	}
+}
@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	partialDownloadBucket = "PartialDownloads"
+	partialFileSuffix     = ".part"
+)
+
+type partialDownloadEntry struct {
+	PartPath   string `json:"part_path"`
+	OutputPath string `json:"output_path"`
+	ItemID     string `json:"item_id"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
+func partialPathFor(outputPath string) string {
+	return outputPath + partialFileSuffix
+}
+
+func registerPartialDownload(partPath, outputPath, itemID string) {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil {
+		return
+	}
+
+	entry := partialDownloadEntry{
+		PartPath:   partPath,
+		OutputPath: outputPath,
+		ItemID:     itemID,
+		UpdatedAt:  time.Now().Unix(),
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err := downloadQueueDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(partialDownloadBucket))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(partPath), payload)
+	}); err != nil {
+		fmt.Printf("[PartialDownload] Failed to register %s: %v\n", partPath, err)
+	}
+}
+
+func unregisterPartialDownload(partPath string) {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil {
+		return
+	}
+
+	_ = downloadQueueDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(partialDownloadBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(partPath))
+	})
+}
+
+func loadPartialDownloads() ([]partialDownloadEntry, error) {
+	downloadQueueDBMu.Lock()
+	defer downloadQueueDBMu.Unlock()
+
+	if downloadQueueDB == nil {
+		return nil, nil
+	}
+
+	var entries []partialDownloadEntry
+	err := downloadQueueDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(partialDownloadBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var entry partialDownloadEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				entry.PartPath = string(k)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+func RecoverPartialDownloads() error {
+	entries, err := loadPartialDownloads()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	jobs, err := loadPersistedDownloadJobs()
+	if err != nil {
+		return err
+	}
+	pendingItems := make(map[string]struct{}, len(jobs))
+	for _, job := range jobs {
+		pendingItems[job.ID] = struct{}{}
+	}
+
+	resumable := 0
+	for _, entry := range entries {
+		info, statErr := os.Stat(entry.PartPath)
+		if statErr != nil {
+			unregisterPartialDownload(entry.PartPath)
+			continue
+		}
+
+		if _, ok := pendingItems[entry.ItemID]; ok && entry.ItemID != "" {
+			resumable++
+			fmt.Printf("[PartialDownload] Will resume %s (%.2f MB on disk)\n", entry.PartPath, float64(info.Size())/(1024*1024))
+			continue
+		}
+
+		if err := os.Remove(entry.PartPath); err != nil {
+			fmt.Printf("[PartialDownload] Failed to remove stale partial file %s: %v\n", entry.PartPath, err)
+			continue
+		}
+		unregisterPartialDownload(entry.PartPath)
+		fmt.Printf("[PartialDownload] Removed stale partial file: %s\n", entry.PartPath)
+	}
+
+	if resumable > 0 {
+		fmt.Printf("[PartialDownload] %d partial download(s) will be resumed by the queue\n", resumable)
+	}
+
+	return nil
+}
+
+func RemovePartialDownload(outputPath string) {
+	partPath := partialPathFor(outputPath)
+	if err := os.Remove(partPath); err == nil {
+		fmt.Printf("Removed partial download: %s\n", partPath)
+	}
+	unregisterPartialDownload(partPath)
+}
+
+func parseContentRange(header string) (start int64, total int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, false
+	}
+
+	spec := strings.TrimPrefix(header, "bytes ")
+	slash := strings.Index(spec, "/")
+	if slash < 0 {
+		return 0, 0, false
+	}
+
+	rangePart := spec[:slash]
+	totalPart := spec[slash+1:]
+
+	total = -1
+	if totalPart != "*" {
+		parsed, err := strconv.ParseInt(totalPart, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = parsed
+	}
+
+	if rangePart == "*" {
+		return 0, total, true
+	}
+
+	dash := strings.Index(rangePart, "-")
+	if dash < 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(rangePart[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return start, total, true
+}
+
+func expectedDownloadSize(resp *http.Response, offset int64) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total > 0 {
+			return total
+		}
+		if resp.ContentLength > 0 {
+			return offset + resp.ContentLength
+		}
+		return -1
+	}
+
+	if resp.ContentLength > 0 {
+		return resp.ContentLength
+	}
+	return -1
+}
@@ -0,0 +1,254 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	playlistSyncBucket = "PlaylistSync"
+
+	SyncRemovedKeep    = "keep"
+	SyncRemovedArchive = "archive"
+	SyncRemovedDelete  = "delete"
+)
+
+type PlaylistSyncTrack struct {
+	SpotifyID  string `json:"spotify_id"`
+	TrackName  string `json:"track_name"`
+	ArtistName string `json:"artist_name"`
+	FilePath   string `json:"file_path"`
+}
+
+type PlaylistSyncState struct {
+	PlaylistID   string              `json:"playlist_id"`
+	URL          string              `json:"url"`
+	Name         string              `json:"name"`
+	Owner        string              `json:"owner"`
+	OutputDir    string              `json:"output_dir"`
+	AudioFormat  string              `json:"audio_format"`
+	Tracks       []PlaylistSyncTrack `json:"tracks"`
+	LastSyncedAt int64               `json:"last_synced_at"`
+}
+
+func SpotifyPlaylistID(spotifyURL string) (string, error) {
+	parsed, err := parseSpotifyURI(spotifyURL)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Type != "playlist" {
+		return "", fmt.Errorf("not a playlist URL: %s", spotifyURL)
+	}
+	return parsed.ID, nil
+}
+
+func GetPlaylistSyncState(playlistID string, appName string) (*PlaylistSyncState, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return nil, err
+		}
+	}
+
+	var state *PlaylistSyncState
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(playlistSyncBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(playlistID))
+		if v == nil {
+			return nil
+		}
+		var item PlaylistSyncState
+		if err := json.Unmarshal(v, &item); err != nil {
+			return err
+		}
+		state = &item
+		return nil
+	})
+	return state, err
+}
+
+func SavePlaylistSyncState(state PlaylistSyncState, appName string) error {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return err
+		}
+	}
+
+	state.LastSyncedAt = time.Now().Unix()
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(playlistSyncBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(state.PlaylistID), buf)
+	})
+}
+
+func GetPlaylistSyncStates(appName string) ([]PlaylistSyncState, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return nil, err
+		}
+	}
+
+	var states []PlaylistSyncState
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(playlistSyncBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var state PlaylistSyncState
+			if err := json.Unmarshal(v, &state); err == nil {
+				states = append(states, state)
+			}
+			return nil
+		})
+	})
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].LastSyncedAt > states[j].LastSyncedAt
+	})
+
+	return states, err
+}
+
+func DeletePlaylistSyncState(playlistID string, appName string) error {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return err
+		}
+	}
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(playlistSyncBucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(playlistID))
+	})
+}
+
+func WriteM3U8File(m3u8Name string, outputDir string, filePaths []string) (string, error) {
+	if len(filePaths) == 0 {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+
+	safeName := SanitizeFilename(m3u8Name)
+	if safeName == "" {
+		safeName = "playlist"
+	}
+
+	m3u8Path := filepath.Join(outputDir, safeName+".m3u8")
+
+	f, err := os.Create(m3u8Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString("#EXTM3U\n"); err != nil {
+		return "", err
+	}
+
+	for _, path := range filePaths {
+		if path == "" {
+			continue
+		}
+
+		relPath, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			relPath = path
+		}
+
+		relPath = filepath.ToSlash(relPath)
+
+		if _, err := f.WriteString(relPath + "\n"); err != nil {
+			return "", err
+		}
+	}
+
+	return m3u8Path, nil
+}
+
+func ApplyRemovedTrackAction(filePath, action, archiveDir string) (string, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return "", nil
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	switch action {
+	case SyncRemovedDelete:
+		if err := os.Remove(filePath); err != nil {
+			return "", fmt.Errorf("failed to delete %s: %v", filePath, err)
+		}
+		return "", nil
+	case SyncRemovedArchive:
+		if archiveDir == "" {
+			return "", fmt.Errorf("archive folder is required")
+		}
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			return "", err
+		}
+		target, _ := ResolveOutputPathForDownload(filepath.Join(archiveDir, filepath.Base(filePath)), true)
+		if err := moveFile(filePath, target); err != nil {
+			return "", fmt.Errorf("failed to archive %s: %v", filePath, err)
+		}
+		return target, nil
+	default:
+		return filePath, nil
+	}
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
@@ -15,6 +15,8 @@ const (
 	StatusCompleted   DownloadStatus = "completed"
 	StatusFailed      DownloadStatus = "failed"
 	StatusSkipped     DownloadStatus = "skipped"
+	StatusPaused      DownloadStatus = "paused"
+	StatusCancelled   DownloadStatus = "cancelled"
 )
 
 type DownloadItem struct {
@@ -31,20 +33,21 @@ type DownloadItem struct {
 	EndTime      int64          `json:"end_time"`
 	ErrorMessage string         `json:"error_message"`
 	FilePath     string         `json:"file_path"`
+	Attempt      int            `json:"attempt"`
+	MaxAttempts  int            `json:"max_attempts"`
+	NextRetryAt  int64          `json:"next_retry_at"`
 }
 
 var (
 	currentProgress     float64
 	currentProgressLock sync.RWMutex
-	isDownloading       bool
+	activeDownloads     int
 	downloadingLock     sync.RWMutex
 	currentSpeed        float64
 	speedLock           sync.RWMutex
 
 	downloadQueue       []DownloadItem
 	downloadQueueLock   sync.RWMutex
-	currentItemID       string
-	currentItemLock     sync.RWMutex
 	totalDownloaded     float64
 	totalDownloadedLock sync.RWMutex
 	sessionStartTime    int64
@@ -58,20 +61,25 @@ type ProgressInfo struct {
 }
 
 type DownloadQueueInfo struct {
-	IsDownloading    bool           `json:"is_downloading"`
-	Queue            []DownloadItem `json:"queue"`
-	CurrentSpeed     float64        `json:"current_speed"`
-	TotalDownloaded  float64        `json:"total_downloaded"`
-	SessionStartTime int64          `json:"session_start_time"`
-	QueuedCount      int            `json:"queued_count"`
-	CompletedCount   int            `json:"completed_count"`
-	FailedCount      int            `json:"failed_count"`
-	SkippedCount     int            `json:"skipped_count"`
+	IsDownloading    bool            `json:"is_downloading"`
+	Queue            []DownloadItem  `json:"queue"`
+	CurrentSpeed     float64         `json:"current_speed"`
+	TotalDownloaded  float64         `json:"total_downloaded"`
+	SessionStartTime int64           `json:"session_start_time"`
+	QueuedCount      int             `json:"queued_count"`
+	DownloadingCount int             `json:"downloading_count"`
+	CompletedCount   int             `json:"completed_count"`
+	FailedCount      int             `json:"failed_count"`
+	SkippedCount     int             `json:"skipped_count"`
+	PausedCount      int             `json:"paused_count"`
+	CancelledCount   int             `json:"cancelled_count"`
+	Workers          int             `json:"workers"`
+	Bandwidth        BandwidthStatus `json:"bandwidth"`
 }
 
 func GetDownloadProgress() ProgressInfo {
 	downloadingLock.RLock()
-	downloading := isDownloading
+	downloading := activeDownloads > 0
 	downloadingLock.RUnlock()
 
 	currentProgressLock.RLock()
@@ -82,6 +90,15 @@ func GetDownloadProgress() ProgressInfo {
 	speed := currentSpeed
 	speedLock.RUnlock()
 
+	downloadQueueLock.RLock()
+	for _, item := range downloadQueue {
+		if item.Status == StatusDownloading {
+			progress += item.Progress
+			speed += item.Speed
+		}
+	}
+	downloadQueueLock.RUnlock()
+
 	return ProgressInfo{
 		IsDownloading: downloading,
 		MBDownloaded:  progress,
@@ -103,13 +120,19 @@ func SetDownloadProgress(mbDownloaded float64) {
 
 func SetDownloading(downloading bool) {
 	downloadingLock.Lock()
-	isDownloading = downloading
+	if downloading {
+		activeDownloads++
+	} else if activeDownloads > 0 {
+		activeDownloads--
+	}
+	idle := activeDownloads == 0
 	downloadingLock.Unlock()
 
-	if !downloading {
+	if idle {
 
 		SetDownloadProgress(0)
 		SetDownloadSpeed(0)
+		emitDownloadProgressEvent("", 0, 0)
 	}
 }
 
@@ -120,6 +143,7 @@ type ProgressWriter struct {
 	startTime   int64
 	lastTime    int64
 	lastBytes   int64
+	lastEmitted int64
 	itemID      string
 }
 
@@ -142,6 +166,14 @@ func NewProgressWriterWithID(writer io.Writer, itemID string) *ProgressWriter {
 	return pw
 }
 
+func NewResumedProgressWriter(writer io.Writer, itemID string, offset int64) *ProgressWriter {
+	pw := NewProgressWriterWithID(writer, itemID)
+	pw.total = offset
+	pw.lastPrinted = offset
+	pw.lastBytes = offset
+	return pw
+}
+
 func getCurrentTimeMillis() int64 {
 	return time.Now().UnixMilli()
 }
@@ -160,16 +192,23 @@ func (pw *ProgressWriter) Write(p []byte) (int, error) {
 		var speedMBps float64
 		if timeDiff > 0 {
 			speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
-			SetDownloadSpeed(speedMBps)
 			fmt.Printf("\rDownloaded: %.2f MB (%.2f MB/s)", mbDownloaded, speedMBps)
 		} else {
 			fmt.Printf("\rDownloaded: %.2f MB", mbDownloaded)
 		}
 
-		SetDownloadProgress(mbDownloaded)
-
 		if pw.itemID != "" {
 			UpdateItemProgress(pw.itemID, mbDownloaded, speedMBps)
+		} else {
+			if timeDiff > 0 {
+				SetDownloadSpeed(speedMBps)
+			}
+			SetDownloadProgress(mbDownloaded)
+		}
+
+		if now-pw.lastEmitted >= progressEventInterval.Milliseconds() {
+			emitDownloadProgressEvent(pw.itemID, mbDownloaded, speedMBps)
+			pw.lastEmitted = now
 		}
 
 		pw.lastPrinted = pw.total
@@ -184,16 +223,7 @@ func (pw *ProgressWriter) GetTotal() int64 {
 	return pw.total
 }
 
-func GetCurrentItemID() string {
-	currentItemLock.RLock()
-	defer currentItemLock.RUnlock()
-	return currentItemID
-}
-
 func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
 	item := DownloadItem{
 		ID:         id,
 		TrackName:  trackName,
@@ -208,31 +238,43 @@ func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
 		EndTime:    0,
 	}
 
+	downloadQueueLock.Lock()
 	downloadQueue = append(downloadQueue, item)
+	downloadQueueLock.Unlock()
 
 	sessionStartLock.Lock()
 	if sessionStartTime == 0 {
 		sessionStartTime = time.Now().Unix()
 	}
 	sessionStartLock.Unlock()
+
+	EmitEvent(EventDownloadItemAdded, item)
 }
 
-func StartDownloadItem(id string) {
+func updateDownloadItem(id string, update func(item *DownloadItem) bool) (DownloadItem, bool) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
 		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusDownloading
-			downloadQueue[i].StartTime = time.Now().Unix()
-			downloadQueue[i].Progress = 0
-			break
+			if !update(&downloadQueue[i]) {
+				return DownloadItem{}, false
+			}
+			return downloadQueue[i], true
 		}
 	}
+	return DownloadItem{}, false
+}
 
-	currentItemLock.Lock()
-	currentItemID = id
-	currentItemLock.Unlock()
+func StartDownloadItem(id string) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusDownloading
+		item.StartTime = time.Now().Unix()
+		item.Progress = 0
+		item.NextRetryAt = 0
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemStarted, item, ok)
 }
 
 func UpdateItemProgress(id string, progress, speed float64) {
@@ -248,59 +290,121 @@ func UpdateItemProgress(id string, progress, speed float64) {
 	}
 }
 
-func CompleteDownloadItem(id, filePath string, finalSize float64) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+func SetDownloadItemAttempt(id string, attempt, maxAttempts int) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.Attempt = attempt
+		item.MaxAttempts = maxAttempts
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
+}
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusCompleted
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			downloadQueue[i].Progress = finalSize
-			downloadQueue[i].Speed = 0
-			break
+func ScheduleDownloadItemRetry(id string, nextRetryAt int64, errorMsg string) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.NextRetryAt = nextRetryAt
+		item.ErrorMessage = errorMsg
+		item.Speed = 0
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
+}
+
+func resetDownloadItemForRetry(id string) bool {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		if item.Status != StatusFailed {
+			return false
 		}
-	}
+		item.Status = StatusQueued
+		item.ErrorMessage = ""
+		item.Progress = 0
+		item.Speed = 0
+		item.StartTime = 0
+		item.EndTime = 0
+		item.Attempt = 0
+		item.NextRetryAt = 0
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
+	return ok
+}
+
+func CompleteDownloadItem(id, filePath string, finalSize float64) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusCompleted
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Progress = finalSize
+		item.Speed = 0
+		return true
+	})
 
 	totalDownloadedLock.Lock()
 	totalDownloaded += finalSize
 	totalDownloadedLock.Unlock()
+
+	emitDownloadItemEvent(EventDownloadItemCompleted, item, ok)
 }
 
 func SkipDownloadItem(id, filePath string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusSkipped
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Speed = 0
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
+}
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusSkipped
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			downloadQueue[i].Speed = 0
-			break
+func FailDownloadItem(id, errorMsg string) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		if item.Status == StatusPaused || item.Status == StatusCancelled {
+			item.Speed = 0
+			return true
 		}
+		item.Status = StatusFailed
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = errorMsg
+		item.Speed = 0
+		item.NextRetryAt = 0
+		return true
+	})
+
+	if item.Status == StatusFailed {
+		emitDownloadItemEvent(EventDownloadItemFailed, item, ok)
+	} else {
+		emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
 	}
 }
 
-func FailDownloadItem(id, errorMsg string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+func GetDownloadItemStatus(id string) (DownloadStatus, bool) {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusFailed
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].ErrorMessage = errorMsg
-			downloadQueue[i].Speed = 0
-			break
+	for _, item := range downloadQueue {
+		if item.ID == id {
+			return item.Status, true
 		}
 	}
+	return "", false
+}
+
+func setDownloadItemStatus(id string, status DownloadStatus, message string) {
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) bool {
+		item.Status = status
+		item.ErrorMessage = message
+		item.Speed = 0
+		if status == StatusCancelled {
+			item.EndTime = time.Now().Unix()
+		}
+		return true
+	})
+	emitDownloadItemEvent(EventDownloadItemUpdated, item, ok)
 }
 
 func GetDownloadQueue() DownloadQueueInfo {
 	downloadingLock.RLock()
-	downloading := isDownloading
+	downloading := activeDownloads > 0
 	downloadingLock.RUnlock()
 
 	speedLock.RLock()
@@ -318,17 +422,24 @@ func GetDownloadQueue() DownloadQueueInfo {
 	downloadQueueLock.RLock()
 	defer downloadQueueLock.RUnlock()
 
-	var queued, completed, failed, skipped int
+	var queued, downloadingCount, completed, failed, skipped, paused, cancelled int
 	for _, item := range downloadQueue {
 		switch item.Status {
 		case StatusQueued:
 			queued++
+		case StatusDownloading:
+			downloadingCount++
+			speed += item.Speed
 		case StatusCompleted:
 			completed++
 		case StatusFailed:
 			failed++
 		case StatusSkipped:
 			skipped++
+		case StatusPaused:
+			paused++
+		case StatusCancelled:
+			cancelled++
 		}
 	}
 
@@ -342,26 +453,41 @@ func GetDownloadQueue() DownloadQueueInfo {
 		TotalDownloaded:  total,
 		SessionStartTime: sessionStart,
 		QueuedCount:      queued,
+		DownloadingCount: downloadingCount,
 		CompletedCount:   completed,
 		FailedCount:      failed,
 		SkippedCount:     skipped,
+		PausedCount:      paused,
+		CancelledCount:   cancelled,
+		Workers:          GetDownloadWorkerCount(),
+		Bandwidth:        GetBandwidthStatus(),
 	}
 }
 
 func ClearDownloadQueue() {
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
 	newQueue := make([]DownloadItem, 0)
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Status == StatusQueued || item.Status == StatusDownloading || item.Status == StatusPaused {
 			newQueue = append(newQueue, item)
 		}
 	}
 	downloadQueue = newQueue
+	downloadQueueLock.Unlock()
+
+	forgetFailedDownloadJobs()
+	emitDownloadQueueSnapshot(EventDownloadQueueCleared)
+}
+
+func emitDownloadQueueSnapshot(name string) {
+	if HasEventListeners() {
+		EmitEvent(name, GetDownloadQueue())
+	}
 }
 
 func ClearAllDownloads() {
+	clearPendingDownloadJobs()
+
 	downloadQueueLock.Lock()
 	downloadQueue = []DownloadItem{}
 	downloadQueueLock.Unlock()
@@ -374,32 +500,37 @@ func ClearAllDownloads() {
 	sessionStartTime = 0
 	sessionStartLock.Unlock()
 
-	currentItemLock.Lock()
-	currentItemID = ""
-	currentItemLock.Unlock()
-
 	SetDownloadProgress(0)
 	SetDownloadSpeed(0)
+
+	emitDownloadQueueSnapshot(EventDownloadQueueCleared)
 }
 
 func CancelAllQueuedItems() {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	clearPendingDownloadJobs()
 
+	downloadQueueLock.Lock()
+	var cancelled []DownloadItem
 	for i := range downloadQueue {
-		if downloadQueue[i].Status == StatusQueued {
-			downloadQueue[i].Status = StatusSkipped
+		if downloadQueue[i].Status == StatusQueued || downloadQueue[i].Status == StatusPaused {
+			downloadQueue[i].Status = StatusCancelled
 			downloadQueue[i].EndTime = time.Now().Unix()
 			downloadQueue[i].ErrorMessage = "Cancelled"
+			cancelled = append(cancelled, downloadQueue[i])
 		}
 	}
+	downloadQueueLock.Unlock()
+
+	for _, item := range cancelled {
+		EmitEvent(EventDownloadItemUpdated, item)
+	}
 }
 
 func ResetSessionIfComplete() {
 	downloadQueueLock.RLock()
 	hasActiveOrQueued := false
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Status == StatusQueued || item.Status == StatusDownloading || item.Status == StatusPaused {
 			hasActiveOrQueued = true
 			break
 		}
@@ -414,5 +545,60 @@ func ResetSessionIfComplete() {
 		totalDownloadedLock.Lock()
 		totalDownloaded = 0
 		totalDownloadedLock.Unlock()
+
+		emitDownloadQueueSnapshot(EventDownloadSessionReset)
+	}
+}
+
+func isFinishedDownloadStatus(status DownloadStatus) bool {
+	switch status {
+	case StatusCompleted, StatusFailed, StatusSkipped, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+func finishedDownloadItems(ids []string) (map[string]DownloadItem, bool) {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	byID := make(map[string]DownloadItem, len(downloadQueue))
+	for _, item := range downloadQueue {
+		byID[item.ID] = item
+	}
+
+	items := make(map[string]DownloadItem, len(ids))
+	for _, id := range ids {
+		item, ok := byID[id]
+		if !ok {
+			items[id] = DownloadItem{ID: id, Status: StatusCancelled}
+			continue
+		}
+		if !isFinishedDownloadStatus(item.Status) && item.Status != StatusPaused {
+			return nil, false
+		}
+		items[id] = item
+	}
+	return items, true
+}
+
+func WaitForDownloadItems(ids []string) map[string]DownloadItem {
+	wake := make(chan struct{}, 1)
+	unsubscribe := SubscribeEvents(func(name string, data interface{}) {
+		if !IsDownloadEvent(name) || name == EventDownloadProgress {
+			return
+		}
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	for {
+		if items, done := finishedDownloadItems(ids); done {
+			return items
+		}
+		<-wake
 	}
 }
@@ -0,0 +1,188 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+)
+
+const (
+	TagSpotifyTrackID         = "SPOTIFY_TRACK_ID"
+	TagSpotifyAlbumID         = "SPOTIFY_ALBUM_ID"
+	TagSpotifyArtistIDs       = "SPOTIFY_ARTIST_IDS"
+	TagMusicBrainzRecordingID = "MUSICBRAINZ_RECORDINGID"
+	TagMusicBrainzReleaseID   = "MUSICBRAINZ_RELEASEID"
+	TagDownloadDate           = "DOWNLOAD_DATE"
+	TagSourceQuality          = "SOURCE_QUALITY"
+
+	ufidOwnerMusicBrainz = "http://musicbrainz.org"
+	ufidOwnerSpotify     = "https://open.spotify.com/track"
+)
+
+type Provenance struct {
+	SpotifyTrackID         string   `json:"spotify_track_id,omitempty"`
+	SpotifyAlbumID         string   `json:"spotify_album_id,omitempty"`
+	SpotifyArtistIDs       []string `json:"spotify_artist_ids,omitempty"`
+	MusicBrainzRecordingID string   `json:"musicbrainz_recording_id,omitempty"`
+	MusicBrainzReleaseID   string   `json:"musicbrainz_release_id,omitempty"`
+	DownloadDate           string   `json:"download_date,omitempty"`
+	SourceQuality          string   `json:"source_quality,omitempty"`
+}
+
+func (p Provenance) IsZero() bool {
+	return p.SpotifyTrackID == "" && p.SpotifyAlbumID == "" && len(p.SpotifyArtistIDs) == 0 &&
+		p.MusicBrainzRecordingID == "" && p.MusicBrainzReleaseID == "" &&
+		p.DownloadDate == "" && p.SourceQuality == ""
+}
+
+func NewDownloadProvenance(trackID string, identifiers SpotifyTrackIdentifiers, sourceQuality string) Provenance {
+	return Provenance{
+		SpotifyTrackID:   strings.TrimSpace(trackID),
+		SpotifyAlbumID:   identifiers.AlbumID,
+		SpotifyArtistIDs: identifiers.ArtistIDs,
+		DownloadDate:     time.Now().UTC().Format(time.RFC3339),
+		SourceQuality:    sourceQuality,
+	}
+}
+
+func DescribeSourceQuality(filePath string) string {
+	format := strings.ToUpper(strings.TrimPrefix(filepath.Ext(filePath), "."))
+
+	if format == "FLAC" {
+		if streamInfo, err := readFlacStreamInfo(filePath); err == nil && streamInfo.SampleRate > 0 {
+			return fmt.Sprintf("FLAC %d-bit/%gkHz", streamInfo.BitDepth, float64(streamInfo.SampleRate)/1000)
+		}
+		return format
+	}
+
+	if meta, err := GetTrackMetadata(filePath); err == nil && meta != nil && meta.Bitrate > 0 {
+		return fmt.Sprintf("%s %dkbps", format, meta.Bitrate/1000)
+	}
+	return format
+}
+
+func splitProvenanceIDs(value string) []string {
+	var ids []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' || r == '\x00' }) {
+		if id := strings.TrimSpace(part); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func appendUniqueIDs(ids []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, id := range ids {
+			if id == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, value)
+		}
+	}
+	return ids
+}
+
+func addProvenanceVorbisComments(cmt *flacvorbis.MetaDataBlockVorbisComment, p Provenance) {
+	add := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			_ = cmt.Add(key, value)
+		}
+	}
+
+	add(TagSpotifyTrackID, p.SpotifyTrackID)
+	add(TagSpotifyAlbumID, p.SpotifyAlbumID)
+	if len(p.SpotifyArtistIDs) > 0 {
+		addVorbisTagValues(cmt, TagSpotifyArtistIDs, p.SpotifyArtistIDs)
+	}
+	add(TagMusicBrainzRecordingID, p.MusicBrainzRecordingID)
+	add(TagMusicBrainzReleaseID, p.MusicBrainzReleaseID)
+	add(TagDownloadDate, p.DownloadDate)
+	add(TagSourceQuality, p.SourceQuality)
+}
+
+func setProvenanceMP3Frames(tag *id3v2.Tag, p Provenance) {
+	set := func(description, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			setUserDefinedTextFrame(tag, description, value)
+		}
+	}
+
+	set(TagSpotifyTrackID, p.SpotifyTrackID)
+	set(TagSpotifyAlbumID, p.SpotifyAlbumID)
+	set(TagSpotifyArtistIDs, joinMultiValueText(p.SpotifyArtistIDs, "", true))
+	set(TagMusicBrainzRecordingID, p.MusicBrainzRecordingID)
+	set(TagMusicBrainzReleaseID, p.MusicBrainzReleaseID)
+	set(TagDownloadDate, p.DownloadDate)
+	set(TagSourceQuality, p.SourceQuality)
+
+	if p.SpotifyTrackID != "" {
+		tag.AddUFIDFrame(id3v2.UFIDFrame{OwnerIdentifier: ufidOwnerSpotify, Identifier: []byte(p.SpotifyTrackID)})
+	}
+	if p.MusicBrainzRecordingID != "" {
+		tag.AddUFIDFrame(id3v2.UFIDFrame{OwnerIdentifier: ufidOwnerMusicBrainz, Identifier: []byte(p.MusicBrainzRecordingID)})
+	}
+}
+
+func applyProvenanceField(p *Provenance, key, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+
+	switch strings.ToUpper(strings.ReplaceAll(key, " ", "_")) {
+	case TagSpotifyTrackID:
+		p.SpotifyTrackID = value
+	case TagSpotifyAlbumID:
+		p.SpotifyAlbumID = value
+	case TagSpotifyArtistIDs:
+		p.SpotifyArtistIDs = appendUniqueIDs(p.SpotifyArtistIDs, splitProvenanceIDs(value)...)
+	case TagMusicBrainzRecordingID, "MUSICBRAINZ_TRACKID":
+		p.MusicBrainzRecordingID = value
+	case TagMusicBrainzReleaseID, "MUSICBRAINZ_ALBUMID", "MUSICBRAINZ_ALBUM_ID":
+		p.MusicBrainzReleaseID = value
+	case TagDownloadDate:
+		p.DownloadDate = value
+	case TagSourceQuality:
+		p.SourceQuality = value
+	default:
+		return false
+	}
+	return true
+}
+
+func readProvenanceMP3Frames(tag *id3v2.Tag) Provenance {
+	var p Provenance
+
+	for _, frame := range tag.GetFrames("TXXX") {
+		if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok {
+			applyProvenanceField(&p, udtf.Description, udtf.Value)
+		}
+	}
+
+	for _, frame := range tag.GetFrames("UFID") {
+		ufid, ok := frame.(id3v2.UFIDFrame)
+		if !ok {
+			continue
+		}
+		identifier := strings.TrimRight(string(ufid.Identifier), "\x00")
+		switch ufid.OwnerIdentifier {
+		case ufidOwnerMusicBrainz:
+			p.MusicBrainzRecordingID = identifier
+		case ufidOwnerSpotify:
+			if p.SpotifyTrackID == "" {
+				p.SpotifyTrackID = identifier
+			}
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,413 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	pathfilepath "path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+const ReplayGainReferenceLUFS = -18.0
+
+type LoudnessResult struct {
+	FilePath       string  `json:"file_path"`
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	TruePeakDBTP   float64 `json:"true_peak_dbtp"`
+	LRA            float64 `json:"lra"`
+	Duration       float64 `json:"duration"`
+}
+
+type ReplayGainInfo struct {
+	TrackGain float64 `json:"track_gain"`
+	TrackPeak float64 `json:"track_peak"`
+	AlbumGain float64 `json:"album_gain,omitempty"`
+	AlbumPeak float64 `json:"album_peak,omitempty"`
+	HasAlbum  bool    `json:"has_album"`
+}
+
+type ReplayGainResult struct {
+	FilePath  string          `json:"file_path"`
+	Album     string          `json:"album,omitempty"`
+	Loudness  *LoudnessResult `json:"loudness,omitempty"`
+	Gain      *ReplayGainInfo `json:"gain,omitempty"`
+	Success   bool            `json:"success"`
+	Error     string          `json:"error,omitempty"`
+	AlbumSize int             `json:"album_size,omitempty"`
+}
+
+func GetReplayGainOnDownloadSetting() bool {
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return false
+	}
+	enabled, _ := settings["replayGainOnDownload"].(bool)
+	return enabled
+}
+
+func MeasureLoudness(ctx context.Context, filePath string) (*LoudnessResult, error) {
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-af", "ebur128=peak=true",
+		"-f", "null",
+		"-",
+	)
+	setHideWindow(cmd)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		return nil, fmt.Errorf("ffmpeg loudness scan failed: %v - %s", err, lastLines(string(output), 5))
+	}
+
+	result, err := parseEBUR128Summary(string(output))
+	if err != nil {
+		return nil, err
+	}
+	result.FilePath = filePath
+
+	if duration, err := GetAudioDuration(filePath); err == nil {
+		result.Duration = duration
+	}
+
+	return result, nil
+}
+
+func parseEBUR128Summary(output string) (*LoudnessResult, error) {
+	idx := strings.LastIndex(output, "Summary:")
+	if idx < 0 {
+		return nil, fmt.Errorf("ebur128 summary not found in ffmpeg output")
+	}
+
+	result := &LoudnessResult{TruePeakDBTP: math.Inf(-1)}
+	found := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(output[idx:]))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			if fields[1] != "-inf" {
+				continue
+			}
+			value = math.Inf(-1)
+		}
+
+		switch fields[0] {
+		case "I:":
+			result.IntegratedLUFS = value
+			found++
+		case "LRA:":
+			result.LRA = value
+		case "Peak:":
+			result.TruePeakDBTP = value
+		}
+	}
+
+	if found == 0 {
+		return nil, fmt.Errorf("integrated loudness missing from ebur128 summary")
+	}
+	if math.IsInf(result.IntegratedLUFS, -1) {
+		return nil, fmt.Errorf("track is silent")
+	}
+
+	return result, nil
+}
+
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func dbToLinear(db float64) float64 {
+	if math.IsInf(db, -1) {
+		return 0
+	}
+	return math.Pow(10, db/20)
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func TrackReplayGain(loudness *LoudnessResult) ReplayGainInfo {
+	return ReplayGainInfo{
+		TrackGain: roundTo(ReplayGainReferenceLUFS-loudness.IntegratedLUFS, 2),
+		TrackPeak: roundTo(dbToLinear(loudness.TruePeakDBTP), 6),
+	}
+}
+
+func AlbumLoudness(tracks []*LoudnessResult) (float64, float64) {
+	var energy, weight, peak float64
+	for _, track := range tracks {
+		w := track.Duration
+		if w <= 0 {
+			w = 1
+		}
+		energy += w * math.Pow(10, track.IntegratedLUFS/10)
+		weight += w
+		peak = math.Max(peak, dbToLinear(track.TruePeakDBTP))
+	}
+	if weight == 0 || energy == 0 {
+		return math.Inf(-1), peak
+	}
+	return 10 * math.Log10(energy/weight), peak
+}
+
+func (rg ReplayGainInfo) vorbisComments() [][2]string {
+	comments := [][2]string{
+		{"REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", rg.TrackGain)},
+		{"REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", rg.TrackPeak)},
+	}
+	if rg.HasAlbum {
+		comments = append(comments,
+			[2]string{"REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", rg.AlbumGain)},
+			[2]string{"REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", rg.AlbumPeak)},
+		)
+	}
+	comments = append(comments, [2]string{"REPLAYGAIN_REFERENCE_LOUDNESS", fmt.Sprintf("%.2f LUFS", ReplayGainReferenceLUFS)})
+	return comments
+}
+
+func isReplayGainField(name string) bool {
+	return strings.HasPrefix(strings.ToUpper(name), "REPLAYGAIN_")
+}
+
+func addReplayGainVorbisComments(cmt *flacvorbis.MetaDataBlockVorbisComment, rg *ReplayGainInfo) {
+	if rg == nil {
+		return
+	}
+	for _, kv := range rg.vorbisComments() {
+		_ = cmt.Add(kv[0], kv[1])
+	}
+}
+
+func setReplayGainMP3Frames(tag *id3v2.Tag, rg *ReplayGainInfo) {
+	if rg == nil {
+		return
+	}
+	for _, kv := range rg.vorbisComments() {
+		setUserDefinedTextFrame(tag, kv[0], kv[1])
+	}
+}
+
+func WriteReplayGainTags(filePath string, rg ReplayGainInfo) error {
+	switch strings.ToLower(pathfilepath.Ext(filePath)) {
+	case ".flac":
+		return writeFlacReplayGain(filePath, rg)
+	case ".mp3":
+		return writeMp3ReplayGain(filePath, rg)
+	case ".m4a":
+		return writeM4AReplayGain(filePath, rg)
+	default:
+		return fmt.Errorf("unsupported file format for ReplayGain: %s", pathfilepath.Ext(filePath))
+	}
+}
+
+func writeFlacReplayGain(filePath string, rg ReplayGainInfo) error {
+	f, err := flac.ParseFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	cmtIdx := -1
+	cmt := flacvorbis.New()
+	for idx, block := range f.Meta {
+		if block.Type != flac.VorbisComment {
+			continue
+		}
+		cmtIdx = idx
+		if existing, err := flacvorbis.ParseFromMetaDataBlock(*block); err == nil {
+			cmt.Vendor = existing.Vendor
+			for _, comment := range existing.Comments {
+				parts := strings.SplitN(comment, "=", 2)
+				if len(parts) == 2 && !isReplayGainField(parts[0]) {
+					_ = cmt.Add(parts[0], parts[1])
+				}
+			}
+		}
+		break
+	}
+
+	addReplayGainVorbisComments(cmt, &rg)
+
+	cmtBlock := cmt.Marshal()
+	if cmtIdx < 0 {
+		f.Meta = append(f.Meta, &cmtBlock)
+	} else {
+		f.Meta[cmtIdx] = &cmtBlock
+	}
+
+	if err := f.Save(filePath); err != nil {
+		return fmt.Errorf("failed to save FLAC file: %w", err)
+	}
+	return nil
+}
+
+func writeMp3ReplayGain(filePath string, rg ReplayGainInfo) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	setReplayGainMP3Frames(tag, &rg)
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+	return nil
+}
+
+func writeM4AReplayGain(filePath string, rg ReplayGainInfo) error {
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	tmpOutputFile := strings.TrimSuffix(filePath, pathfilepath.Ext(filePath)) + ".tmp.m4a"
+	defer os.Remove(tmpOutputFile)
+
+	args := []string{
+		"-i", filePath,
+		"-map", "0",
+		"-map_metadata", "0",
+		"-movflags", "use_metadata_tags",
+	}
+	for _, kv := range rg.vorbisComments() {
+		args = append(args, "-metadata", strings.ToLower(kv[0])+"="+kv[1])
+	}
+	args = append(args, "-codec", "copy", "-f", "ipod", "-y", tmpOutputFile)
+
+	cmd := exec.Command(ffmpegPath, args...)
+	setHideWindow(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed to write ReplayGain: %s - %w", lastLines(string(output), 5), err)
+	}
+
+	if err := os.Rename(tmpOutputFile, filePath); err != nil {
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+	return nil
+}
+
+func replayGainAlbumKey(filePath string) (string, string) {
+	dir := pathfilepath.Dir(filePath)
+	album := ""
+	if metadata, err := ReadAudioMetadata(filePath); err == nil && metadata != nil {
+		album = strings.TrimSpace(metadata.Album)
+		if artist := strings.TrimSpace(metadata.AlbumArtist); artist != "" && album != "" {
+			return dir + "\x00" + strings.ToLower(artist) + "\x00" + strings.ToLower(album), album
+		}
+	}
+	return dir + "\x00" + strings.ToLower(album), album
+}
+
+func ApplyReplayGain(paths []string, albumMode bool) ([]ReplayGainResult, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		audioFiles, err := ListAudioFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, audioFile := range audioFiles {
+			files = append(files, audioFile.Path)
+		}
+	}
+	sort.Strings(files)
+
+	results := make([]ReplayGainResult, len(files))
+	groups := make(map[string][]int)
+	var groupOrder []string
+
+	for i, file := range files {
+		results[i].FilePath = file
+		fmt.Printf("[ReplayGain] Scanning %s\n", pathfilepath.Base(file))
+
+		loudness, err := MeasureLoudness(context.Background(), file)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Loudness = loudness
+		gain := TrackReplayGain(loudness)
+		results[i].Gain = &gain
+
+		if albumMode {
+			key, album := replayGainAlbumKey(file)
+			results[i].Album = album
+			if _, ok := groups[key]; !ok {
+				groupOrder = append(groupOrder, key)
+			}
+			groups[key] = append(groups[key], i)
+		}
+	}
+
+	for _, key := range groupOrder {
+		indexes := groups[key]
+		tracks := make([]*LoudnessResult, 0, len(indexes))
+		for _, i := range indexes {
+			tracks = append(tracks, results[i].Loudness)
+		}
+		albumLUFS, albumPeak := AlbumLoudness(tracks)
+		if math.IsInf(albumLUFS, -1) {
+			continue
+		}
+		for _, i := range indexes {
+			results[i].Gain.AlbumGain = roundTo(ReplayGainReferenceLUFS-albumLUFS, 2)
+			results[i].Gain.AlbumPeak = roundTo(albumPeak, 6)
+			results[i].Gain.HasAlbum = true
+			results[i].AlbumSize = len(indexes)
+		}
+	}
+
+	for i := range results {
+		if results[i].Gain == nil {
+			continue
+		}
+		if err := WriteReplayGainTags(results[i].FilePath, *results[i].Gain); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Success = true
+	}
+
+	return results, nil
+}
@@ -0,0 +1,430 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+const (
+	RetagStatusChanged   = "changed"
+	RetagStatusUpdated   = "updated"
+	RetagStatusUnchanged = "unchanged"
+	RetagStatusSkipped   = "skipped"
+	RetagStatusFailed    = "failed"
+)
+
+type RetagOptions struct {
+	Preview             bool   `json:"preview"`
+	Separator           string `json:"separator,omitempty"`
+	EmbedGenre          bool   `json:"embed_genre"`
+	UseSingleGenre      bool   `json:"use_single_genre"`
+	UseAlbumTrackNumber bool   `json:"use_album_track_number"`
+}
+
+type TagChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type RetagResult struct {
+	Path      string      `json:"path"`
+	SpotifyID string      `json:"spotify_id,omitempty"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Changes   []TagChange `json:"changes,omitempty"`
+}
+
+func RetagFiles(ctx context.Context, paths []string, options RetagOptions, appName string) []RetagResult {
+	results := make([]RetagResult, 0, len(paths))
+
+	var historyIDs map[string]string
+	lookupHistory := func(path string) string {
+		if historyIDs == nil {
+			historyIDs = make(map[string]string)
+			if items, err := GetHistoryItems(appName); err == nil {
+				for _, item := range items {
+					if item.Path != "" && item.SpotifyID != "" {
+						historyIDs[filepath.Clean(item.Path)] = item.SpotifyID
+					}
+				}
+			}
+		}
+		return historyIDs[filepath.Clean(path)]
+	}
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			results = append(results, RetagResult{Path: path, Status: RetagStatusSkipped, Error: context.Cause(ctx).Error()})
+			continue
+		}
+
+		result := retagFile(ctx, path, options, lookupHistory)
+		switch result.Status {
+		case RetagStatusFailed:
+			fmt.Printf("[Retag] %s: %s\n", filepath.Base(path), result.Error)
+		case RetagStatusUpdated:
+			fmt.Printf("[Retag] %s: %d tag(s) updated\n", filepath.Base(path), len(result.Changes))
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func retagFile(ctx context.Context, path string, options RetagOptions, lookupHistory func(string) string) RetagResult {
+	result := RetagResult{Path: path}
+	fail := func(err error) RetagResult {
+		result.Status = RetagStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".flac" && ext != ".mp3" {
+		return fail(fmt.Errorf("unsupported file format: %s", ext))
+	}
+
+	current, err := ReadAudioMetadata(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	trackID := current.SpotifyID
+	if trackID == "" {
+		trackID = lookupHistory(path)
+	}
+	if trackID == "" {
+		result.Status = RetagStatusSkipped
+		result.Error = "no Spotify track ID in tags or download history"
+		return result
+	}
+	result.SpotifyID = trackID
+
+	metadata, err := resolveRetagMetadata(ctx, trackID, path, current, options)
+	if err != nil {
+		return fail(err)
+	}
+
+	var comments []string
+	switch ext {
+	case ".flac":
+		result.Changes, comments, err = planFlacRetag(path, metadata)
+	case ".mp3":
+		result.Changes, err = planMp3Retag(path, metadata)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if len(result.Changes) == 0 {
+		result.Status = RetagStatusUnchanged
+		return result
+	}
+	if options.Preview {
+		result.Status = RetagStatusChanged
+		return result
+	}
+
+	if ext == ".flac" {
+		err = writeFlacComments(path, comments)
+	} else {
+		err = EmbedMetadata(path, metadata, "")
+	}
+	if err != nil {
+		return fail(err)
+	}
+	AddLibraryFile(path)
+
+	result.Status = RetagStatusUpdated
+	return result
+}
+
+func resolveRetagMetadata(ctx context.Context, trackID, path string, current *AudioMetadata, options RetagOptions) (Metadata, error) {
+	separator := options.Separator
+	if separator == "" {
+		separator = GetSeparator()
+	}
+
+	trackURL := fmt.Sprintf("https://open.spotify.com/track/%s", trackID)
+	data, err := GetFilteredSpotifyData(ctx, trackURL, false, 0, separator, nil)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to fetch Spotify metadata: %w", err)
+	}
+	trackResp, ok := data.(TrackResponse)
+	if !ok {
+		return Metadata{}, fmt.Errorf("unexpected Spotify response for track %s", trackID)
+	}
+	track := trackResp.Track
+
+	isrc := NormalizeISRC(current.ISRC)
+	if isrc == "" {
+		isrc = ResolveTrackISRC(trackID)
+	}
+
+	trackNumber := current.TrackNumber
+	if options.UseAlbumTrackNumber || trackNumber == 0 {
+		trackNumber = track.TrackNumber
+	}
+	if trackNumber == 0 {
+		trackNumber = 1
+	}
+
+	var mbMeta Metadata
+	fullTagging := GetMusicBrainzFullTaggingSetting()
+	if (options.EmbedGenre || fullTagging) && isrc != "" {
+		hint := MusicBrainzReleaseHint{Album: track.AlbumName, TrackCount: track.TotalTracks, ReleaseDate: track.ReleaseDate}
+		fetched, err := FetchMusicBrainzMetadata(ctx, isrc, track.Name, track.Artists, track.AlbumName, options.UseSingleGenre, options.EmbedGenre, fullTagging, hint)
+		if err != nil {
+			fmt.Printf("[Retag] Warning: Failed to fetch MusicBrainz metadata: %v\n", err)
+			mbMeta.Provenance = fetched.Provenance
+			mbMeta.MusicBrainz = fetched.MusicBrainz
+		} else {
+			mbMeta = fetched
+		}
+	}
+
+	metadata := buildTrackMetadata(trackTagInput{
+		TrackID:     trackID,
+		TrackName:   track.Name,
+		ArtistName:  track.Artists,
+		AlbumName:   track.AlbumName,
+		AlbumArtist: track.AlbumArtist,
+		ReleaseDate: track.ReleaseDate,
+		TrackNumber: trackNumber,
+		TotalTracks: track.TotalTracks,
+		DiscNumber:  track.DiscNumber,
+		TotalDiscs:  track.TotalDiscs,
+		Copyright:   track.Copyright,
+		Publisher:   track.Publisher,
+		Composer:    track.Composer,
+		Separator:   separator,
+		ISRC:        isrc,
+	}, mbMeta, path)
+
+	metadata.Provenance.DownloadDate = current.Provenance.DownloadDate
+	if metadata.Genre == "" && current.Genre != "" {
+		metadata.Genre = strings.Join(strings.Split(current.Genre, "; "), separator)
+	}
+
+	return metadata, nil
+}
+
+func readFlacComments(path string) ([]string, error) {
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+	for _, block := range f.Meta {
+		if block.Type != flac.VorbisComment {
+			continue
+		}
+		cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse FLAC comments: %w", err)
+		}
+		return cmt.Comments, nil
+	}
+	return nil, nil
+}
+
+func vorbisCommentKey(comment string) string {
+	key, _, _ := strings.Cut(comment, "=")
+	return strings.ToUpper(key)
+}
+
+var retagManagedVorbisKeys = map[string]bool{
+	"TITLE":             true,
+	"ARTIST":            true,
+	"ALBUM":             true,
+	"ALBUMARTIST":       true,
+	"DATE":              true,
+	"YEAR":              true,
+	"TRACKNUMBER":       true,
+	"TOTALTRACKS":       true,
+	"DISCNUMBER":        true,
+	"TOTALDISCS":        true,
+	"COPYRIGHT":         true,
+	"PUBLISHER":         true,
+	"COMPOSER":          true,
+	"ISRC":              true,
+	"GENRE":             true,
+	"BARCODE":           true,
+	preferredUPCTagKey:  true,
+	TagSpotifyTrackID:   true,
+	TagSpotifyAlbumID:   true,
+	TagSpotifyArtistIDs: true,
+}
+
+var retagMusicBrainzIDVorbisKeys = map[string]bool{
+	TagMusicBrainzRecordingID: true,
+	TagMusicBrainzReleaseID:   true,
+	"MUSICBRAINZ_TRACKID":     true,
+	"MUSICBRAINZ_ALBUMID":     true,
+	"MUSICBRAINZ_ALBUM_ID":    true,
+}
+
+var retagMusicBrainzReleaseVorbisKeys = map[string]bool{
+	"MUSICBRAINZ_RELEASETRACKID": true,
+	"MUSICBRAINZ_RELEASEGROUPID": true,
+	"MUSICBRAINZ_ARTISTID":       true,
+	"MUSICBRAINZ_ALBUMARTISTID":  true,
+	"LABEL":                      true,
+	"CATALOGNUMBER":              true,
+	"RELEASECOUNTRY":             true,
+	"MEDIA":                      true,
+	"RELEASETYPE":                true,
+	"RELEASESTATUS":              true,
+	"ORIGINALDATE":               true,
+	"ORIGINALYEAR":               true,
+}
+
+func isRetagManagedVorbisKey(key string, metadata Metadata) bool {
+	switch {
+	case retagManagedVorbisKeys[key]:
+		return true
+	case retagMusicBrainzIDVorbisKeys[key]:
+		return metadata.Provenance.MusicBrainzRecordingID != "" || metadata.Provenance.MusicBrainzReleaseID != ""
+	case retagMusicBrainzReleaseVorbisKeys[key]:
+		return metadata.MusicBrainz != nil
+	}
+	return false
+}
+
+func planFlacRetag(path string, metadata Metadata) ([]TagChange, []string, error) {
+	previous, err := readFlacComments(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := buildFlacVorbisComment(metadata).Comments
+	written := make(map[string]bool, len(next))
+	for _, comment := range next {
+		written[vorbisCommentKey(comment)] = true
+	}
+
+	after := append([]string(nil), next...)
+	for _, comment := range previous {
+		key := vorbisCommentKey(comment)
+		if !written[key] && !isRetagManagedVorbisKey(key, metadata) {
+			after = append(after, comment)
+		}
+	}
+
+	return diffTagSnapshots(vorbisTagSnapshot(previous), vorbisTagSnapshot(after)), after, nil
+}
+
+func writeFlacComments(path string, comments []string) error {
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	cmt := flacvorbis.New()
+	cmt.Comments = comments
+	cmtBlock := cmt.Marshal()
+
+	replaced := false
+	for idx, block := range f.Meta {
+		if block.Type == flac.VorbisComment {
+			f.Meta[idx] = &cmtBlock
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		f.Meta = append(f.Meta, &cmtBlock)
+	}
+
+	if err := f.Save(path); err != nil {
+		return fmt.Errorf("failed to save FLAC file: %w", err)
+	}
+	return nil
+}
+
+func planMp3Retag(path string, metadata Metadata) ([]TagChange, error) {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	before := id3TagSnapshot(tag)
+	applyMp3Metadata(tag, metadata, "")
+	return diffTagSnapshots(before, id3TagSnapshot(tag)), nil
+}
+
+func vorbisTagSnapshot(comments []string) map[string]string {
+	snapshot := make(map[string]string)
+	for _, comment := range comments {
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		if existing, ok := snapshot[key]; ok {
+			snapshot[key] = existing + "; " + value
+		} else {
+			snapshot[key] = value
+		}
+	}
+	return snapshot
+}
+
+func id3TagSnapshot(tag *id3v2.Tag) map[string]string {
+	snapshot := make(map[string]string)
+	add := func(key, value string) {
+		value = strings.ReplaceAll(strings.TrimRight(value, "\x00"), "\x00", "; ")
+		if existing, ok := snapshot[key]; ok {
+			snapshot[key] = existing + "; " + value
+		} else {
+			snapshot[key] = value
+		}
+	}
+
+	for id, frames := range tag.AllFrames() {
+		for _, frame := range frames {
+			switch f := frame.(type) {
+			case id3v2.TextFrame:
+				add(id, f.Text)
+			case id3v2.UserDefinedTextFrame:
+				add("TXXX:"+f.Description, f.Value)
+			case id3v2.CommentFrame:
+				add("COMM:"+f.Description, f.Text)
+			case id3v2.UFIDFrame:
+				add("UFID:"+f.OwnerIdentifier, string(f.Identifier))
+			case id3v2.UnsynchronisedLyricsFrame:
+				add("USLT:"+f.ContentDescriptor, f.Lyrics)
+			}
+		}
+	}
+	return snapshot
+}
+
+func diffTagSnapshots(before, after map[string]string) []TagChange {
+	keys := make(map[string]bool, len(before)+len(after))
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	var changes []TagChange
+	for key := range keys {
+		if before[key] != after[key] {
+			changes = append(changes, TagChange{Field: key, Old: before[key], New: after[key]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
@@ -0,0 +1,170 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 2 * time.Second
+	defaultRetryMaxDelay    = 60 * time.Second
+	maxRetryAttempts        = 10
+)
+
+var ErrIncompleteDownload = errors.New("incomplete download")
+
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("status %d", e.StatusCode)
+}
+
+func newHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultRetryMaxAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+	}
+}
+
+func GetRetryPolicySetting() RetryPolicy {
+	policy := DefaultRetryPolicy()
+
+	settings, err := LoadConfigSettings()
+	if err != nil || settings == nil {
+		return policy
+	}
+
+	if attempts, ok := settings["retryMaxAttempts"].(float64); ok {
+		policy.MaxAttempts = int(attempts)
+	}
+	if base, ok := settings["retryBaseDelaySeconds"].(float64); ok && base > 0 {
+		policy.BaseDelay = time.Duration(base * float64(time.Second))
+	}
+	if maxDelay, ok := settings["retryMaxDelaySeconds"].(float64); ok && maxDelay > 0 {
+		policy.MaxDelay = time.Duration(maxDelay * float64(time.Second))
+	}
+
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.MaxAttempts > maxRetryAttempts {
+		policy.MaxAttempts = maxRetryAttempts
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+
+	return policy
+}
+
+func (p RetryPolicy) Delay(attempt int, err error) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	var retryAfter time.Duration
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+		delay *= 2
+		retryAfter = statusErr.RetryAfter
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	if half > 0 {
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+
+	if retryAfter > delay {
+		delay = retryAfter
+		if delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+	return delay
+}
+
+func IsRetryableDownloadError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrDownloadPaused) || errors.Is(err, ErrDownloadCancelled) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return true
+		case statusErr.StatusCode == http.StatusRequestTimeout:
+			return true
+		case statusErr.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, ErrIncompleteDownload) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,473 @@
+package backend
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	spectrogramMarginLeft   = 70
+	spectrogramMarginRight  = 90
+	spectrogramMarginTop    = 40
+	spectrogramMarginBottom = 50
+	spectrogramMinLogFreq   = 20.0
+)
+
+type SpectrogramOptions struct {
+	ColorMap       string `json:"color_map"`
+	FreqScale      string `json:"freq_scale"`
+	WindowSize     int    `json:"window_size"`
+	WindowFunction string `json:"window_function"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	OutputPath     string `json:"output_path,omitempty"`
+	OutputDir      string `json:"output_dir,omitempty"`
+}
+
+type SpectrogramResult struct {
+	InputFile  string `json:"input_file"`
+	OutputFile string `json:"output_file,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+func DefaultSpectrogramOptions() SpectrogramOptions {
+	return SpectrogramOptions{
+		ColorMap:       "spek",
+		FreqScale:      "linear",
+		WindowSize:     4096,
+		WindowFunction: "hann",
+		Width:          1100,
+		Height:         600,
+	}
+}
+
+func (o SpectrogramOptions) withDefaults() (SpectrogramOptions, error) {
+	defaults := DefaultSpectrogramOptions()
+
+	o.ColorMap = strings.ToLower(strings.TrimSpace(o.ColorMap))
+	switch o.ColorMap {
+	case "":
+		o.ColorMap = defaults.ColorMap
+	case "spek", "viridis", "hot", "cool", "grayscale":
+	default:
+		return o, fmt.Errorf("unsupported color map: %s", o.ColorMap)
+	}
+
+	o.FreqScale = strings.ToLower(strings.TrimSpace(o.FreqScale))
+	switch o.FreqScale {
+	case "":
+		o.FreqScale = defaults.FreqScale
+	case "linear", "log2":
+	default:
+		return o, fmt.Errorf("unsupported frequency scale: %s", o.FreqScale)
+	}
+
+	o.WindowFunction = strings.ToLower(strings.TrimSpace(o.WindowFunction))
+	switch o.WindowFunction {
+	case "":
+		o.WindowFunction = defaults.WindowFunction
+	case "hann", "hamming", "blackman", "rectangular":
+	default:
+		return o, fmt.Errorf("unsupported window function: %s", o.WindowFunction)
+	}
+
+	if o.WindowSize == 0 {
+		o.WindowSize = defaults.WindowSize
+	}
+	if o.WindowSize < 256 || o.WindowSize > 16384 || o.WindowSize&(o.WindowSize-1) != 0 {
+		return o, fmt.Errorf("window size must be a power of two between 256 and 16384, got %d", o.WindowSize)
+	}
+
+	if o.Width == 0 {
+		o.Width = defaults.Width
+	}
+	if o.Height == 0 {
+		o.Height = defaults.Height
+	}
+	if o.Width < 300 || o.Height < 200 || o.Width > 8000 || o.Height > 4000 {
+		return o, fmt.Errorf("image size %dx%d is out of range", o.Width, o.Height)
+	}
+
+	return o, nil
+}
+
+func SpectrogramOutputPath(audioFilePath string, outputDir string) string {
+	ext := filepath.Ext(audioFilePath)
+	baseName := strings.TrimSuffix(filepath.Base(audioFilePath), ext)
+	if outputDir == "" {
+		outputDir = filepath.Dir(audioFilePath)
+	}
+	return filepath.Join(outputDir, baseName+".png")
+}
+
+func spectrogramWindow(name string, size int) []float64 {
+	window := make([]float64, size)
+	for i := range window {
+		phase := 2 * math.Pi * float64(i) / float64(size-1)
+		switch name {
+		case "hamming":
+			window[i] = 0.54 - 0.46*math.Cos(phase)
+		case "blackman":
+			window[i] = 0.42 - 0.5*math.Cos(phase) + 0.08*math.Cos(2*phase)
+		case "rectangular":
+			window[i] = 1
+		default:
+			window[i] = 0.5 * (1 - math.Cos(phase))
+		}
+	}
+	return window
+}
+
+func computeSTFT(samples []float64, columns int, opts SpectrogramOptions) [][]float64 {
+	size := opts.WindowSize
+	window := spectrogramWindow(opts.WindowFunction, size)
+	buf := make([]complex128, size)
+	scale := 1 / float64(size*size)
+
+	span := len(samples) - size
+	if span < 0 {
+		span = 0
+	}
+
+	frames := make([][]float64, columns)
+	for col := range frames {
+		start := 0
+		if columns > 1 {
+			start = int(float64(span) * float64(col) / float64(columns-1))
+		}
+
+		for i := range buf {
+			v := 0.0
+			if start+i < len(samples) {
+				v = samples[start+i] * window[i]
+			}
+			buf[i] = complex(v, 0)
+		}
+		fft(buf)
+
+		magnitudes := make([]float64, size/2)
+		for i := range magnitudes {
+			mag := cmplx.Abs(buf[i])
+			power := mag * mag * scale
+			if power > 1e-12 {
+				magnitudes[i] = 10 * math.Log10(power)
+			} else {
+				magnitudes[i] = -120
+			}
+		}
+		frames[col] = magnitudes
+	}
+
+	return frames
+}
+
+func spectrogramColor(name string, t float64) color.RGBA {
+	t = clamp01(t)
+	switch name {
+	case "viridis":
+		return gradientColor(viridisStops, t)
+	case "hot":
+		switch {
+		case t < 0.33:
+			return color.RGBA{uint8(t * 3 * 255), 0, 0, 255}
+		case t < 0.66:
+			return color.RGBA{255, uint8((t - 0.33) * 3 * 255), 0, 255}
+		default:
+			return color.RGBA{255, 255, uint8(math.Min(1, (t-0.66)*3) * 255), 255}
+		}
+	case "cool":
+		return color.RGBA{uint8(t * 255), uint8((1 - t) * 255), 255, 255}
+	case "grayscale":
+		gray := uint8(t * 255)
+		return color.RGBA{gray, gray, gray, 255}
+	default:
+		return gradientColor(spekStops, t)
+	}
+}
+
+var spekStops = [][3]float64{
+	{0, 0, 0}, {0, 0, 25}, {0, 0, 50}, {0, 0, 80}, {20, 0, 120}, {50, 0, 150},
+	{80, 0, 180}, {120, 0, 120}, {150, 0, 80}, {180, 0, 40}, {210, 0, 0}, {240, 30, 0},
+	{255, 60, 0}, {255, 100, 0}, {255, 140, 0}, {255, 180, 0}, {255, 210, 0}, {255, 235, 0},
+	{255, 250, 50}, {255, 255, 100}, {255, 255, 150}, {255, 255, 200}, {255, 255, 255},
+}
+
+var viridisStops = [][3]float64{
+	{68, 1, 84}, {70, 20, 100}, {72, 40, 120}, {67, 62, 133}, {62, 74, 137}, {55, 89, 140},
+	{49, 104, 142}, {43, 117, 142}, {38, 130, 142}, {35, 144, 140}, {31, 158, 137}, {42, 171, 129},
+	{53, 183, 121}, {81, 194, 105}, {109, 205, 89}, {144, 214, 67}, {180, 222, 44}, {216, 227, 41},
+	{253, 231, 37},
+}
+
+func gradientColor(stops [][3]float64, t float64) color.RGBA {
+	scaled := t * float64(len(stops)-1)
+	idx := int(scaled)
+	if idx >= len(stops)-1 {
+		last := stops[len(stops)-1]
+		return color.RGBA{uint8(last[0]), uint8(last[1]), uint8(last[2]), 255}
+	}
+	frac := scaled - float64(idx)
+	c1, c2 := stops[idx], stops[idx+1]
+	return color.RGBA{
+		uint8(math.Round(c1[0] + (c2[0]-c1[0])*frac)),
+		uint8(math.Round(c1[1] + (c2[1]-c1[1])*frac)),
+		uint8(math.Round(c1[2] + (c2[2]-c1[2])*frac)),
+		255,
+	}
+}
+
+func freqProgressForRow(progress float64, scale string, maxFreq float64) float64 {
+	if scale != "log2" || maxFreq <= spectrogramMinLogFreq {
+		return progress
+	}
+	octaves := math.Log2(maxFreq / spectrogramMinLogFreq)
+	return spectrogramMinLogFreq * math.Pow(2, progress*octaves) / maxFreq
+}
+
+func rowForFreq(freq float64, scale string, maxFreq float64, plotHeight int) int {
+	progress := freq / maxFreq
+	if scale == "log2" {
+		progress = math.Log2(freq/spectrogramMinLogFreq) / math.Log2(maxFreq/spectrogramMinLogFreq)
+	}
+	return spectrogramMarginTop + int(float64(plotHeight-1)*(1-progress))
+}
+
+func drawSpectrogramText(img *image.RGBA, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func drawSpectrogramTextRight(img *image.RGBA, x, y int, text string) {
+	width := font.MeasureString(basicfont.Face7x13, text).Round()
+	drawSpectrogramText(img, x-width, y, text)
+}
+
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	draw.Draw(img, rect, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func renderSpectrogramImage(frames [][]float64, sampleRate int, duration float64, title string, opts SpectrogramOptions) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	fillRect(img, img.Bounds(), color.Black)
+
+	plotWidth := opts.Width - spectrogramMarginLeft - spectrogramMarginRight
+	plotHeight := opts.Height - spectrogramMarginTop - spectrogramMarginBottom
+	maxFreq := float64(sampleRate) / 2
+
+	minMag, maxMag := math.Inf(1), math.Inf(-1)
+	for _, frame := range frames {
+		for _, mag := range frame {
+			minMag = math.Min(minMag, mag)
+			maxMag = math.Max(maxMag, mag)
+		}
+	}
+	magRange := maxMag - minMag
+	if magRange <= 0 || math.IsInf(magRange, 0) {
+		minMag, magRange = -120, 120
+	}
+
+	for x := 0; x < plotWidth && x < len(frames); x++ {
+		frame := frames[x]
+		bins := len(frame)
+		for y := 0; y < plotHeight; y++ {
+			progress := float64(plotHeight-1-y) / float64(plotHeight-1)
+			pos := freqProgressForRow(progress, opts.FreqScale, maxFreq) * float64(bins-1)
+			idx := int(pos)
+			next := min(idx+1, bins-1)
+			frac := pos - float64(idx)
+			mag := frame[idx]*(1-frac) + frame[next]*frac
+			img.SetRGBA(spectrogramMarginLeft+x, spectrogramMarginTop+y, spectrogramColor(opts.ColorMap, (mag-minMag)/magRange))
+		}
+	}
+
+	white := color.RGBA{255, 255, 255, 255}
+	plotBottom := spectrogramMarginTop + plotHeight
+
+	if duration > 0 {
+		step := niceStep(duration, plotWidth/90)
+		for t := 0.0; t <= duration+1e-9; t += step {
+			x := spectrogramMarginLeft + int(t/duration*float64(plotWidth-1))
+			fillRect(img, image.Rect(x, plotBottom, x+1, plotBottom+5), white)
+			label := fmt.Sprintf("%gs", t)
+			if step >= 60 {
+				label = fmt.Sprintf("%dm%02ds", int(t)/60, int(t)%60)
+			}
+			width := font.MeasureString(basicfont.Face7x13, label).Round()
+			drawSpectrogramText(img, x-width/2, plotBottom+20, label)
+		}
+	}
+
+	var freqs []float64
+	if opts.FreqScale == "log2" {
+		for f := spectrogramMinLogFreq; f <= maxFreq; f *= 2 {
+			freqs = append(freqs, f)
+		}
+	} else {
+		step := niceStep(maxFreq, plotHeight/40)
+		for f := 0.0; f <= maxFreq; f += step {
+			freqs = append(freqs, f)
+		}
+	}
+	for _, f := range freqs {
+		y := rowForFreq(f, opts.FreqScale, maxFreq, plotHeight)
+		fillRect(img, image.Rect(spectrogramMarginLeft-5, y, spectrogramMarginLeft, y+1), white)
+		label := fmt.Sprintf("%.0f", f)
+		if f >= 1000 {
+			label = fmt.Sprintf("%.1fk", f/1000)
+		}
+		drawSpectrogramTextRight(img, spectrogramMarginLeft-8, y+4, label)
+	}
+
+	barX := opts.Width - spectrogramMarginRight + 30
+	for y := 0; y < plotHeight; y++ {
+		c := spectrogramColor(opts.ColorMap, float64(plotHeight-1-y)/float64(plotHeight-1))
+		fillRect(img, image.Rect(barX, spectrogramMarginTop+y, barX+20, spectrogramMarginTop+y+1), c)
+	}
+	drawSpectrogramText(img, barX+24, spectrogramMarginTop+10, fmt.Sprintf("%.0f", maxMag))
+	drawSpectrogramText(img, barX+24, plotBottom, fmt.Sprintf("%.0f", minMag))
+	drawSpectrogramText(img, barX, plotBottom+20, "dB")
+
+	drawSpectrogramText(img, spectrogramMarginLeft, 25, title)
+	drawSpectrogramTextRight(img, opts.Width-20, 25, fmt.Sprintf("Sample Rate: %d Hz", sampleRate))
+
+	return img
+}
+
+func niceStep(span float64, maxTicks int) float64 {
+	if maxTicks < 1 {
+		maxTicks = 1
+	}
+	raw := span / float64(maxTicks)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if m*magnitude >= raw {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func RenderSpectrogram(filePath string, options SpectrogramOptions) (string, error) {
+	opts, err := options.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := GetTrackMetadata(filePath)
+	if err != nil {
+		return "", err
+	}
+	if metadata.SampleRate == 0 {
+		return "", fmt.Errorf("unknown sample rate for %s", filePath)
+	}
+
+	pcm, err := extractAnalysisPCM(filePath)
+	if err != nil {
+		return "", err
+	}
+	samples := pcm16ToFloat(pcm)
+	if len(samples) == 0 {
+		return "", fmt.Errorf("no audio samples decoded from %s", filePath)
+	}
+
+	duration := metadata.Duration
+	if duration <= 0 {
+		duration = float64(len(samples)) / float64(metadata.SampleRate)
+	}
+
+	plotWidth := opts.Width - spectrogramMarginLeft - spectrogramMarginRight
+	frames := computeSTFT(samples, plotWidth, opts)
+	img := renderSpectrogramImage(frames, int(metadata.SampleRate), duration, filepath.Base(filePath), opts)
+
+	outPath := opts.OutputPath
+	if outPath == "" {
+		outPath = SpectrogramOutputPath(filePath, opts.OutputDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", outPath, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		os.Remove(outPath)
+		return "", fmt.Errorf("failed to encode PNG: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	return outPath, nil
+}
+
+func RenderSpectrograms(paths []string, options SpectrogramOptions) ([]SpectrogramResult, error) {
+	if _, err := options.withDefaults(); err != nil {
+		return nil, err
+	}
+
+	type job struct {
+		input  string
+		output string
+	}
+
+	var jobs []job
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			jobs = append(jobs, job{input: path, output: SpectrogramOutputPath(path, options.OutputDir)})
+			continue
+		}
+		audioFiles, err := ListAudioFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, audioFile := range audioFiles {
+			outputDir := ""
+			if options.OutputDir != "" {
+				rel, err := filepath.Rel(path, filepath.Dir(audioFile.Path))
+				if err != nil {
+					rel = ""
+				}
+				outputDir = filepath.Join(options.OutputDir, rel)
+			}
+			jobs = append(jobs, job{input: audioFile.Path, output: SpectrogramOutputPath(audioFile.Path, outputDir)})
+		}
+	}
+
+	results := make([]SpectrogramResult, 0, len(jobs))
+	for _, j := range jobs {
+		result := SpectrogramResult{InputFile: j.input}
+		options.OutputPath = j.output
+		outPath, err := RenderSpectrogram(j.input, options)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.OutputFile = outPath
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	spectrumFFTSize   = 4096
+	spectrumMaxFrames = 600
+	spectrumSilenceDB = -60.0
+
+	TranscodeVerdictGenuine      = "genuine"
+	TranscodeVerdictTranscode    = "likely_transcode"
+	TranscodeVerdictUpsampled    = "likely_upsampled"
+	TranscodeVerdictInconclusive = "inconclusive"
+	TranscodeVerdictLossyFormat  = "lossy_format"
+)
+
+type TranscodeAnalysis struct {
+	Verdict    string  `json:"verdict"`
+	Confidence float64 `json:"confidence"`
+	CutoffHz   float64 `json:"cutoff_hz"`
+	NyquistHz  float64 `json:"nyquist_hz"`
+	CliffDB    float64 `json:"cliff_db"`
+	Lossless   bool    `json:"lossless"`
+	Frames     int     `json:"frames"`
+}
+
+func pcm16ToFloat(pcm []byte) []float64 {
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768.0
+	}
+	return samples
+}
+
+func fft(x []complex128) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even := x[start+k]
+				odd := w * x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+func frameLevelDB(frame []float64) float64 {
+	var sum float64
+	for _, v := range frame {
+		sum += v * v
+	}
+	return powerToDB(sum / float64(len(frame)))
+}
+
+func powerToDB(power float64) float64 {
+	if power <= 1e-20 {
+		return -200
+	}
+	return 10 * math.Log10(power)
+}
+
+func averageSpectrumDB(samples []float64, fftSize, maxFrames int) ([]float64, int) {
+	if len(samples) < fftSize {
+		return nil, 0
+	}
+
+	totalFrames := len(samples) / fftSize
+	stride := 1
+	if totalFrames > maxFrames {
+		stride = totalFrames / maxFrames
+	}
+
+	window := spectrogramWindow("hann", fftSize)
+	power := make([]float64, fftSize/2)
+	buf := make([]complex128, fftSize)
+	used := 0
+
+	for frame := 0; frame < totalFrames; frame += stride {
+		chunk := samples[frame*fftSize : (frame+1)*fftSize]
+		if frameLevelDB(chunk) < spectrumSilenceDB {
+			continue
+		}
+
+		for i, v := range chunk {
+			buf[i] = complex(v*window[i], 0)
+		}
+		fft(buf)
+		for i := range power {
+			mag := cmplx.Abs(buf[i])
+			power[i] += mag * mag
+		}
+		used++
+	}
+
+	if used == 0 {
+		return nil, 0
+	}
+
+	spectrum := make([]float64, len(power))
+	for i, p := range power {
+		spectrum[i] = powerToDB(p / float64(used))
+	}
+	return spectrum, used
+}
+
+func smoothSpectrum(spectrum []float64, radius int) []float64 {
+	smoothed := make([]float64, len(spectrum))
+	for i := range spectrum {
+		lo := max(0, i-radius)
+		hi := min(len(spectrum)-1, i+radius)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			sum += spectrum[j]
+		}
+		smoothed[i] = sum / float64(hi-lo+1)
+	}
+	return smoothed
+}
+
+func meanRange(values []float64, lo, hi int) (float64, bool) {
+	lo = max(lo, 0)
+	hi = min(hi, len(values)-1)
+	if hi < lo {
+		return 0, false
+	}
+	var sum float64
+	for i := lo; i <= hi; i++ {
+		sum += values[i]
+	}
+	return sum / float64(hi-lo+1), true
+}
+
+func quantizationNoiseDB(fftSize int) float64 {
+	lsb := 1.0 / 32768.0
+	return powerToDB(lsb * lsb / 12 * float64(fftSize) * 0.375)
+}
+
+func findSpectralCliff(spectrum []float64, start, gap, width int) (int, float64) {
+	bestBin := -1
+	bestDrop := 0.0
+	for i := max(start, gap+width); i+gap+width < len(spectrum); i++ {
+		below, _ := meanRange(spectrum, i-gap-width, i-gap)
+		above, _ := meanRange(spectrum, i+gap, i+gap+width)
+		if drop := below - above; drop > bestDrop {
+			bestBin = i
+			bestDrop = drop
+		}
+	}
+	return bestBin, bestDrop
+}
+
+func isLosslessPath(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac", ".wav", ".aiff", ".aif", ".alac":
+		return true
+	default:
+		return false
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func analyzeTranscodePCM(samples []float64, sampleRate int, lossless bool) *TranscodeAnalysis {
+	nyquist := float64(sampleRate) / 2
+	result := &TranscodeAnalysis{
+		Verdict:   TranscodeVerdictInconclusive,
+		NyquistHz: nyquist,
+		Lossless:  lossless,
+	}
+	if sampleRate <= 0 {
+		return result
+	}
+
+	spectrum, frames := averageSpectrumDB(samples, spectrumFFTSize, spectrumMaxFrames)
+	result.Frames = frames
+	if spectrum == nil {
+		return result
+	}
+
+	binHz := nyquist / float64(len(spectrum))
+	hzToBin := func(hz float64) int { return int(hz / binHz) }
+
+	smoothed := smoothSpectrum(spectrum, max(1, hzToBin(100)))
+
+	cliffBin, cliffDB := findSpectralCliff(smoothed, hzToBin(10000), hzToBin(200), hzToBin(1000))
+	cutoffBin := len(smoothed) - 1
+	if cliffDB >= 15 {
+		above, _ := meanRange(smoothed, cliffBin+hzToBin(200), len(smoothed)-1)
+		cutoffBin = min(len(smoothed)-1, cliffBin+hzToBin(1000))
+		for ; cutoffBin > cliffBin-hzToBin(1000); cutoffBin-- {
+			if smoothed[cutoffBin] > above+10 {
+				break
+			}
+		}
+		result.CliffDB = math.Round(cliffDB*10) / 10
+	} else {
+		threshold := quantizationNoiseDB(spectrumFFTSize) + 12
+		low := hzToBin(1000)
+		for ; cutoffBin > low; cutoffBin-- {
+			if smoothed[cutoffBin] > threshold {
+				break
+			}
+		}
+		if cutoffBin <= low {
+			result.CutoffHz = 0
+			return result
+		}
+	}
+	result.CutoffHz = math.Round(float64(cutoffBin) * binHz)
+
+	if !lossless {
+		result.Verdict = TranscodeVerdictLossyFormat
+		result.Confidence = 1
+		return result
+	}
+
+	steepness := clamp01((result.CliffDB - 15) / 30)
+	switch {
+	case result.CutoffHz < 20500 && result.CliffDB >= 15:
+		result.Verdict = TranscodeVerdictTranscode
+		result.Confidence = 0.5 + 0.3*steepness + 0.2*clamp01((20500-result.CutoffHz)/4500)
+	case nyquist > 24000 && result.CutoffHz < 24000 && result.CliffDB >= 15:
+		result.Verdict = TranscodeVerdictUpsampled
+		result.Confidence = 0.5 + 0.5*steepness
+	case result.CutoffHz >= 20500 || result.CutoffHz >= nyquist*0.95:
+		result.Verdict = TranscodeVerdictGenuine
+		result.Confidence = 0.6 + 0.4*clamp01(result.CutoffHz/nyquist)
+		if result.CutoffHz < nyquist*0.9 {
+			result.Confidence -= 0.2 * steepness
+		}
+	default:
+		result.Confidence = 0.3
+	}
+	result.Confidence = math.Round(clamp01(result.Confidence)*100) / 100
+
+	return result
+}
+
+func levelStats(samples []float64) (peakDB, rmsDB float64) {
+	var peak, sum float64
+	for _, v := range samples {
+		if abs := math.Abs(v); abs > peak {
+			peak = abs
+		}
+		sum += v * v
+	}
+	if len(samples) == 0 {
+		return -200, -200
+	}
+	peakDB = 20 * math.Log10(math.Max(peak, 1e-10))
+	rmsDB = powerToDB(sum / float64(len(samples)))
+	return math.Round(peakDB*100) / 100, math.Round(rmsDB*100) / 100
+}
+
+func AnalyzeTrackQuality(filePath string) (*AnalysisResult, error) {
+	result, err := GetTrackMetadata(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	pcm, err := extractAnalysisPCM(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := pcm16ToFloat(pcm)
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no audio samples decoded from %s", filePath)
+	}
+
+	result.PeakAmplitude, result.RMSLevel = levelStats(samples)
+	result.DynamicRange = math.Round((result.PeakAmplitude-result.RMSLevel)*100) / 100
+	result.Transcode = analyzeTranscodePCM(samples, int(result.SampleRate), isLosslessPath(filePath))
+
+	fmt.Printf("[Analysis] %s: cutoff %.0f Hz, cliff %.1f dB, verdict %s (%.2f)\n",
+		filepath.Base(filePath), result.Transcode.CutoffHz, result.Transcode.CliffDB, result.Transcode.Verdict, result.Transcode.Confidence)
+
+	return result, nil
+}
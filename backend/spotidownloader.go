@@ -18,7 +18,9 @@ const (
 )
 
 type SpotiDownloader struct {
+	ctx          context.Context
 	sessionToken string
+	itemID       string
 	httpClient   *http.Client
 }
 
@@ -35,11 +37,21 @@ type DownloadResponse struct {
 
 func NewSpotiDownloader(sessionToken string) *SpotiDownloader {
 	return &SpotiDownloader{
+		ctx:          context.Background(),
 		sessionToken: sessionToken,
 		httpClient:   newHTTPClient(60 * time.Second),
 	}
 }
 
+func NewSpotiDownloaderForItem(ctx context.Context, sessionToken, itemID string) *SpotiDownloader {
+	downloader := NewSpotiDownloader(sessionToken)
+	downloader.itemID = itemID
+	if ctx != nil {
+		downloader.ctx = ctx
+	}
+	return downloader
+}
+
 func (s *SpotiDownloader) GetDownloadLink(trackID string, flac bool) (*DownloadResponse, error) {
 	reqBody := DownloadRequest{ID: trackID, Flac: flac}
 	jsonData, err := json.Marshal(reqBody)
@@ -47,7 +59,7 @@ func (s *SpotiDownloader) GetDownloadLink(trackID string, flac bool) (*DownloadR
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", spotidownloaderAPIBase+"/download", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(s.ctx, "POST", spotidownloaderAPIBase+"/download", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +76,7 @@ func (s *SpotiDownloader) GetDownloadLink(trackID string, flac bool) (*DownloadR
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API returned %w", newHTTPStatusError(resp))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -95,7 +106,20 @@ func (s *SpotiDownloader) GetDownloadLink(trackID string, flac bool) (*DownloadR
 }
 
 func (s *SpotiDownloader) DownloadFile(downloadURL, outputPath string) error {
-	req, err := http.NewRequest("GET", downloadURL, nil)
+	dir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	partPath := partialPathFor(outputPath)
+	registerPartialDownload(partPath, outputPath, s.itemID)
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, "GET", downloadURL, nil)
 	if err != nil {
 		return err
 	}
@@ -103,6 +127,10 @@ func (s *SpotiDownloader) DownloadFile(downloadURL, outputPath string) error {
 	req.Header.Set("Authorization", "Bearer "+s.sessionToken)
 	req.Header.Set("Referer", "https://spotidownloader.com/")
 	req.Header.Set("Origin", "https://spotidownloader.com")
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -110,31 +138,67 @@ func (s *SpotiDownloader) DownloadFile(downloadURL, outputPath string) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: status %d", resp.StatusCode)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			os.Remove(partPath)
+			return fmt.Errorf("server resumed at unexpected offset (requested %d, got %q)", offset, resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+		fmt.Printf("Resuming download at %.2f MB\n", float64(offset)/(1024*1024))
+	case http.StatusOK:
+		if offset > 0 {
+			fmt.Println("Server does not support resume, restarting download")
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if ok && total > 0 && total == offset {
+			return finalizePartialDownload(partPath, outputPath)
+		}
+		os.Remove(partPath)
+		return fmt.Errorf("failed to resume download: %w", newHTTPStatusError(resp))
+	default:
+		return fmt.Errorf("failed to download file: %w", newHTTPStatusError(resp))
 	}
 
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	expected := expectedDownloadSize(resp, offset)
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
 		return err
 	}
 
-	out, err := os.Create(outputPath)
+	progressWriter := NewResumedProgressWriter(out, s.itemID, offset)
+
+	_, err = io.Copy(progressWriter, throttleResponseBody(resp))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	progressWriter := NewProgressWriter(out)
+	written := progressWriter.GetTotal()
+	if expected > 0 && written != expected {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrIncompleteDownload, written, expected)
+	}
 
-	_, err = io.Copy(progressWriter, resp.Body)
+	mbDownloaded := float64(written) / (1024 * 1024)
+	fmt.Printf("\rDownloaded: %.2f MB - Complete\n", mbDownloaded)
 
-	if err == nil {
-		mbDownloaded := float64(progressWriter.GetTotal()) / (1024 * 1024)
-		fmt.Printf("\rDownloaded: %.2f MB - Complete\n", mbDownloaded)
-	}
+	return finalizePartialDownload(partPath, outputPath)
+}
 
-	return err
+func finalizePartialDownload(partPath, outputPath string) error {
+	if err := os.Rename(partPath, outputPath); err != nil {
+		return fmt.Errorf("failed to move completed download into place: %w", err)
+	}
+	unregisterPartialDownload(partPath)
+	return nil
 }
 
 func (s *SpotiDownloader) DownloadTrack(
@@ -166,6 +230,9 @@ func (s *SpotiDownloader) DownloadTrack(
 	useFirstArtistOnly bool,
 	useSingleGenre bool,
 	embedGenre bool,
+	upcOverride string,
+	explicit bool,
+	duration int,
 ) (string, error) {
 
 	outputDir = NormalizePath(outputDir)
@@ -173,7 +240,7 @@ func (s *SpotiDownloader) DownloadTrack(
 	requestFlac := audioFormat == "flac"
 	downloadResp, err := s.GetDownloadLink(trackID, requestFlac)
 	if err != nil {
-		return "", fmt.Errorf("failed to get download link: %v", err)
+		return "", fmt.Errorf("failed to get download link: %w", err)
 	}
 
 	var downloadURL string
@@ -202,7 +269,23 @@ func (s *SpotiDownloader) DownloadTrack(
 		filenameAlbumArtist = GetFirstArtist(albumArtist)
 	}
 
-	filename := BuildFilenameWithISRC(trackName, filenameArtist, albumName, filenameAlbumArtist, releaseDate, discNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber, playlistName, playlistOwner, isrcOverride)
+	filename := BuildFilenameFromTemplate(filenameFormat, FilenameTemplateData{
+		Title:       trackName,
+		Artist:      filenameArtist,
+		Album:       albumName,
+		AlbumArtist: filenameAlbumArtist,
+		ReleaseDate: releaseDate,
+		Playlist:    playlistName,
+		Creator:     playlistOwner,
+		ISRC:        isrcOverride,
+		UPC:         upcOverride,
+		SpotifyID:   trackID,
+		Track:       position,
+		Disc:        discNumber,
+		TotalTracks: totalTracks,
+		Duration:    duration,
+		Explicit:    explicit,
+	}, includeTrackNumber)
 	filename = SanitizeFilename(filename) + fileExt
 
 	outputPath := filepath.Join(outputDir, filename)
@@ -218,25 +301,26 @@ func (s *SpotiDownloader) DownloadTrack(
 		Metadata Metadata
 	}
 
+	fullTagging := GetMusicBrainzFullTaggingSetting()
+	releaseHint := MusicBrainzReleaseHint{Album: albumName, TrackCount: totalTracks, ReleaseDate: releaseDate}
+
 	metaChan := make(chan mbResult, 1)
-	if embedGenre {
+	if embedGenre || fullTagging {
 		go func() {
 			client := NewSongLinkClient()
 			res := mbResult{}
 			if val, err := client.GetISRCDirect(trackID); err == nil {
 				res.ISRC = val
-				if val != "" {
-					if ShouldSkipMusicBrainzMetadataFetch() {
-						fmt.Println("Skipping MusicBrainz metadata fetch because status check is offline.")
+				if val != "" && s.ctx.Err() == nil {
+					fmt.Println("Fetching MusicBrainz metadata...")
+
+					if fetchedMeta, err := FetchMusicBrainzMetadata(s.ctx, val, trackName, artistName, albumName, useSingleGenre, embedGenre, fullTagging, releaseHint); err == nil {
+						res.Metadata = fetchedMeta
+						fmt.Println("✓ MusicBrainz metadata fetched")
 					} else {
-						fmt.Println("Fetching MusicBrainz metadata...")
-
-						if fetchedMeta, err := FetchMusicBrainzMetadata(val, trackName, artistName, albumName, useSingleGenre, embedGenre); err == nil {
-							res.Metadata = fetchedMeta
-							fmt.Println("✓ MusicBrainz metadata fetched")
-						} else {
-							fmt.Printf("Warning: Failed to fetch MusicBrainz metadata: %v\n", err)
-						}
+						res.Metadata.Provenance = fetchedMeta.Provenance
+						res.Metadata.MusicBrainz = fetchedMeta.MusicBrainz
+						fmt.Printf("Warning: Failed to fetch MusicBrainz metadata: %v\n", err)
 					}
 				}
 			} else {
@@ -249,7 +333,7 @@ func (s *SpotiDownloader) DownloadTrack(
 	}
 
 	if err := s.DownloadFile(downloadURL, outputPath); err != nil {
-		return "", fmt.Errorf("failed to download file: %v", err)
+		return "", fmt.Errorf("failed to download file: %w", err)
 	}
 
 	if fileExt == ".mp3" {
@@ -266,14 +350,78 @@ func (s *SpotiDownloader) DownloadTrack(
 			coverPath = ""
 		}
 	}
+	if coverPath != "" {
+		defer os.Remove(coverPath)
+	}
 
-	result := <-metaChan
+	var result mbResult
+	select {
+	case result = <-metaChan:
+	case <-s.ctx.Done():
+		os.Remove(outputPath)
+		return "", context.Cause(s.ctx)
+	}
 	isrc := strings.TrimSpace(isrcOverride)
 	if isrc == "" {
 		isrc = result.ISRC
 	}
-	mbMeta := result.Metadata
-	resolvedReleaseDate := strings.TrimSpace(releaseDate)
+	metadata := buildTrackMetadata(trackTagInput{
+		TrackID:     trackID,
+		TrackName:   trackName,
+		ArtistName:  artistName,
+		AlbumName:   albumName,
+		AlbumArtist: albumArtist,
+		ReleaseDate: releaseDate,
+		TrackNumber: actualTrackNumber,
+		TotalTracks: totalTracks,
+		DiscNumber:  discNumber,
+		TotalDiscs:  totalDiscs,
+		Copyright:   copyright,
+		Publisher:   publisher,
+		Composer:    composer,
+		Separator:   metadataSeparator,
+		ISRC:        isrc,
+	}, result.Metadata, outputPath)
+
+	if GetReplayGainOnDownloadSetting() {
+		if loudness, err := MeasureLoudness(s.ctx, outputPath); err != nil {
+			fmt.Printf("[ReplayGain] Warning: loudness scan failed: %v\n", err)
+		} else {
+			gain := TrackReplayGain(loudness)
+			metadata.ReplayGain = &gain
+			fmt.Printf("[ReplayGain] %.2f LUFS, track gain %.2f dB, peak %.6f\n", loudness.IntegratedLUFS, gain.TrackGain, gain.TrackPeak)
+		}
+	}
+
+	if err := EmbedMetadata(outputPath, metadata, coverPath); err != nil {
+		fmt.Printf("Warning: Failed to embed metadata: %v\n", err)
+	}
+
+	return outputPath, nil
+}
+
+type trackTagInput struct {
+	TrackID     string
+	TrackName   string
+	ArtistName  string
+	AlbumName   string
+	AlbumArtist string
+	ReleaseDate string
+	TrackNumber int
+	TotalTracks int
+	DiscNumber  int
+	TotalDiscs  int
+	Copyright   string
+	Publisher   string
+	Composer    string
+	Separator   string
+	ISRC        string
+}
+
+func buildTrackMetadata(input trackTagInput, mbMeta Metadata, filePath string) Metadata {
+	trackID := input.TrackID
+	isrc := input.ISRC
+	resolvedReleaseDate := strings.TrimSpace(input.ReleaseDate)
 	resolvedGenre := strings.TrimSpace(mbMeta.Genre)
 
 	if resolvedReleaseDate == "" {
@@ -288,45 +436,42 @@ func (s *SpotiDownloader) DownloadTrack(
 	}
 
 	upc := ""
-	if identifiers, err := GetSpotifyTrackIdentifiersDirect(trackID); err == nil || identifiers.ISRC != "" || identifiers.UPC != "" {
+	identifiers, identifiersErr := GetSpotifyTrackIdentifiersDirect(trackID)
+	if identifiersErr == nil || identifiers.ISRC != "" || identifiers.UPC != "" {
 		if strings.TrimSpace(isrc) == "" && strings.TrimSpace(identifiers.ISRC) != "" {
 			isrc = strings.TrimSpace(identifiers.ISRC)
 		}
 		upc = strings.TrimSpace(identifiers.UPC)
 	}
 
+	provenance := NewDownloadProvenance(trackID, identifiers, DescribeSourceQuality(filePath))
+	provenance.MusicBrainzRecordingID = mbMeta.Provenance.MusicBrainzRecordingID
+	provenance.MusicBrainzReleaseID = mbMeta.Provenance.MusicBrainzReleaseID
+
 	spotifyTrackURL := fmt.Sprintf("https://open.spotify.com/track/%s", trackID)
-	metadata := Metadata{
-		Title:       trackName,
-		Artist:      artistName,
-		Album:       albumName,
-		AlbumArtist: albumArtist,
+	return Metadata{
+		Title:       input.TrackName,
+		Artist:      input.ArtistName,
+		Album:       input.AlbumName,
+		AlbumArtist: input.AlbumArtist,
 		Date:        resolvedReleaseDate,
-		TrackNumber: actualTrackNumber,
-		TotalTracks: totalTracks,
-		DiscNumber:  discNumber,
-		TotalDiscs:  totalDiscs,
+		TrackNumber: input.TrackNumber,
+		TotalTracks: input.TotalTracks,
+		DiscNumber:  input.DiscNumber,
+		TotalDiscs:  input.TotalDiscs,
 		URL:         spotifyTrackURL,
 		Comment:     spotifyTrackURL,
-		Copyright:   copyright,
-		Publisher:   publisher,
-		Composer:    composer,
-		Separator:   metadataSeparator,
+		Copyright:   input.Copyright,
+		Publisher:   input.Publisher,
+		Composer:    input.Composer,
+		Separator:   input.Separator,
 		Description: "https://github.com/spotbye/SpotiDownloader",
 		ISRC:        isrc,
 		UPC:         upc,
 		Genre:       resolvedGenre,
+		Provenance:  provenance,
+		MusicBrainz: mbMeta.MusicBrainz,
 	}
-
-	if err := EmbedMetadata(outputPath, metadata, coverPath); err != nil {
-		fmt.Printf("Warning: Failed to embed metadata: %v\n", err)
-	}
-
-	if coverPath != "" {
-		os.Remove(coverPath)
-	}
-
-	return outputPath, nil
 }
 
 func (s *SpotiDownloader) downloadCoverImage(coverURL, outputDir string, embedMaxQualityCover bool) (string, error) {
@@ -338,7 +483,12 @@ func (s *SpotiDownloader) downloadCoverImage(coverURL, outputDir string, embedMa
 		coverURL = coverClient.getMaxResolutionURL(coverURL)
 	}
 
-	resp, err := s.httpClient.Get(coverURL)
+	req, err := http.NewRequestWithContext(s.ctx, "GET", coverURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -348,19 +498,18 @@ func (s *SpotiDownloader) downloadCoverImage(coverURL, outputDir string, embedMa
 		return "", fmt.Errorf("failed to download cover: status %d", resp.StatusCode)
 	}
 
-	coverPath := filepath.Join(outputDir, ".temp_cover.jpg")
-	out, err := os.Create(coverPath)
+	out, err := os.CreateTemp(outputDir, ".cover-*.jpg")
 	if err != nil {
 		return "", err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
+	if _, err := io.Copy(out, throttleResponseBody(resp)); err != nil {
+		os.Remove(out.Name())
 		return "", err
 	}
 
-	return coverPath, nil
+	return out.Name(), nil
 }
 
 func fetchTrackTaggingMetadata(trackID string) string {
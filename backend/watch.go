@@ -0,0 +1,474 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	watchBucket          = "Watches"
+	watchRunBucket       = "WatchRuns"
+	defaultWatchInterval = 24
+	maxWatchRunsPerWatch = 50
+	watchCheckInterval   = time.Minute
+)
+
+type Watch struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Type          string   `json:"type"`
+	Name          string   `json:"name"`
+	IntervalHours int      `json:"interval_hours"`
+	Enabled       bool     `json:"enabled"`
+	CreatedAt     int64    `json:"created_at"`
+	LastRunAt     int64    `json:"last_run_at"`
+	NextRunAt     int64    `json:"next_run_at"`
+	LastError     string   `json:"last_error,omitempty"`
+	BaselineDone  bool     `json:"baseline_done"`
+	SeenIDs       []string `json:"seen_ids"`
+}
+
+type WatchRunItem struct {
+	SpotifyID  string `json:"spotify_id"`
+	TrackName  string `json:"track_name"`
+	ArtistName string `json:"artist_name"`
+	AlbumName  string `json:"album_name"`
+	ItemID     string `json:"item_id,omitempty"`
+}
+
+type WatchRun struct {
+	ID         string         `json:"id"`
+	WatchID    string         `json:"watch_id"`
+	StartedAt  int64          `json:"started_at"`
+	FinishedAt int64          `json:"finished_at"`
+	Baseline   bool           `json:"baseline"`
+	Checked    int            `json:"checked"`
+	NewItems   []WatchRunItem `json:"new_items"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type WatchFetcher func(watch Watch) (string, []DownloadJob, error)
+
+var (
+	watchMu      sync.Mutex
+	watchFetcher WatchFetcher
+	watchStop    chan struct{}
+	watchRunning = make(map[string]bool)
+)
+
+func watchKey(watchType, id string) string {
+	return watchType + ":" + id
+}
+
+func AddWatch(spotifyURL string, intervalHours int) (*Watch, error) {
+	parsed, err := parseSpotifyURI(spotifyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	watchType := parsed.Type
+	if watchType == "artist_discography" {
+		watchType = "artist"
+	}
+	if watchType != "artist" && watchType != "playlist" {
+		return nil, fmt.Errorf("only artist and playlist URLs can be watched")
+	}
+
+	if intervalHours < 1 {
+		intervalHours = defaultWatchInterval
+	}
+
+	now := time.Now().Unix()
+	watch := Watch{
+		ID:            watchKey(watchType, parsed.ID),
+		URL:           strings.TrimSpace(spotifyURL),
+		Type:          watchType,
+		IntervalHours: intervalHours,
+		Enabled:       true,
+		CreatedAt:     now,
+		NextRunAt:     now,
+	}
+
+	if existing, err := GetWatch(watch.ID); err == nil && existing != nil {
+		existing.URL = watch.URL
+		existing.IntervalHours = intervalHours
+		existing.Enabled = true
+		if err := saveWatch(*existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	if err := saveWatch(watch); err != nil {
+		return nil, err
+	}
+	return &watch, nil
+}
+
+func saveWatch(watch Watch) error {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiDownloader"); err != nil {
+			return err
+		}
+	}
+
+	buf, err := json.Marshal(watch)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(watchBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(watch.ID), buf)
+	})
+}
+
+func GetWatch(id string) (*Watch, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiDownloader"); err != nil {
+			return nil, err
+		}
+	}
+
+	var watch *Watch
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		var item Watch
+		if err := json.Unmarshal(v, &item); err != nil {
+			return err
+		}
+		watch = &item
+		return nil
+	})
+	return watch, err
+}
+
+func ListWatches() ([]Watch, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiDownloader"); err != nil {
+			return nil, err
+		}
+	}
+
+	watches := []Watch{}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var watch Watch
+			if err := json.Unmarshal(v, &watch); err == nil {
+				watches = append(watches, watch)
+			}
+			return nil
+		})
+	})
+
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].CreatedAt < watches[j].CreatedAt
+	})
+
+	return watches, err
+}
+
+func RemoveWatch(id string) error {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiDownloader"); err != nil {
+			return err
+		}
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(watchBucket)); b != nil {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		if b := tx.Bucket([]byte(watchRunBucket)); b != nil {
+			prefix := []byte(id + "/")
+			var keys [][]byte
+			c := b.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			for _, k := range keys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func SetWatchEnabled(id string, enabled bool) error {
+	watch, err := GetWatch(id)
+	if err != nil {
+		return err
+	}
+	if watch == nil {
+		return fmt.Errorf("watch not found: %s", id)
+	}
+
+	watch.Enabled = enabled
+	if enabled && watch.NextRunAt < time.Now().Unix() {
+		watch.NextRunAt = time.Now().Unix()
+	}
+	return saveWatch(*watch)
+}
+
+func addWatchRun(run WatchRun) error {
+	buf, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(watchRunBucket))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(run.ID), buf); err != nil {
+			return err
+		}
+
+		prefix := []byte(run.WatchID + "/")
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for len(keys) > maxWatchRunsPerWatch {
+			if err := b.Delete(keys[0]); err != nil {
+				return err
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+}
+
+func GetWatchRuns(watchID string, limit int) ([]WatchRun, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiDownloader"); err != nil {
+			return nil, err
+		}
+	}
+
+	runs := []WatchRun{}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchRunBucket))
+		if b == nil {
+			return nil
+		}
+		prefix := []byte(watchID + "/")
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = c.Next() {
+			var run WatchRun
+			if err := json.Unmarshal(v, &run); err == nil {
+				runs = append(runs, run)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt > runs[j].StartedAt
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	return runs, err
+}
+
+func RunWatch(id string) (*WatchRun, error) {
+	watchMu.Lock()
+	fetcher := watchFetcher
+	if fetcher == nil {
+		watchMu.Unlock()
+		return nil, fmt.Errorf("watch scheduler is not running")
+	}
+	if watchRunning[id] {
+		watchMu.Unlock()
+		return nil, fmt.Errorf("watch %s is already running", id)
+	}
+	watchRunning[id] = true
+	watchMu.Unlock()
+
+	defer func() {
+		watchMu.Lock()
+		delete(watchRunning, id)
+		watchMu.Unlock()
+	}()
+
+	watch, err := GetWatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if watch == nil {
+		return nil, fmt.Errorf("watch not found: %s", id)
+	}
+
+	started := time.Now()
+	run := WatchRun{
+		ID:        fmt.Sprintf("%s/%020d", watch.ID, started.UnixNano()),
+		WatchID:   watch.ID,
+		StartedAt: started.Unix(),
+		Baseline:  !watch.BaselineDone,
+		NewItems:  []WatchRunItem{},
+	}
+
+	name, jobs, fetchErr := fetcher(*watch)
+	if fetchErr != nil {
+		run.Error = fetchErr.Error()
+		watch.LastError = run.Error
+	} else {
+		if name != "" {
+			watch.Name = name
+		}
+		watch.LastError = ""
+
+		seen := make(map[string]struct{}, len(watch.SeenIDs))
+		for _, spotifyID := range watch.SeenIDs {
+			seen[spotifyID] = struct{}{}
+		}
+
+		var newJobs []DownloadJob
+		for _, job := range jobs {
+			if job.SpotifyID == "" {
+				continue
+			}
+			if _, ok := seen[job.SpotifyID]; ok {
+				continue
+			}
+			seen[job.SpotifyID] = struct{}{}
+			watch.SeenIDs = append(watch.SeenIDs, job.SpotifyID)
+			newJobs = append(newJobs, job)
+		}
+		run.Checked = len(jobs)
+		watch.BaselineDone = true
+
+		var ids []string
+		if !run.Baseline && len(newJobs) > 0 {
+			ids = EnqueueDownloadJobs(newJobs)
+		}
+		for i, job := range newJobs {
+			item := WatchRunItem{
+				SpotifyID:  job.SpotifyID,
+				TrackName:  job.TrackName,
+				ArtistName: job.ArtistName,
+				AlbumName:  job.AlbumName,
+			}
+			if i < len(ids) {
+				item.ItemID = ids[i]
+			}
+			run.NewItems = append(run.NewItems, item)
+		}
+	}
+
+	finished := time.Now()
+	run.FinishedAt = finished.Unix()
+	watch.LastRunAt = finished.Unix()
+	watch.NextRunAt = finished.Add(time.Duration(watch.IntervalHours) * time.Hour).Unix()
+
+	if err := saveWatch(*watch); err != nil {
+		return &run, err
+	}
+	if err := addWatchRun(run); err != nil {
+		fmt.Printf("[Watch] Failed to record run for %s: %v\n", watch.ID, err)
+	}
+
+	switch {
+	case run.Error != "":
+		fmt.Printf("[Watch] %s failed: %s\n", watch.ID, run.Error)
+	case run.Baseline:
+		fmt.Printf("[Watch] %s baseline recorded (%d items)\n", watch.ID, run.Checked)
+	default:
+		fmt.Printf("[Watch] %s found %d new item(s)\n", watch.ID, len(run.NewItems))
+	}
+
+	if fetchErr != nil {
+		return &run, fetchErr
+	}
+	return &run, nil
+}
+
+func StartWatchScheduler(fetcher WatchFetcher) {
+	watchMu.Lock()
+	if watchStop != nil {
+		watchMu.Unlock()
+		return
+	}
+	watchFetcher = fetcher
+	stop := make(chan struct{})
+	watchStop = stop
+	watchMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(watchCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			runDueWatches(stop)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func StopWatchScheduler() {
+	watchMu.Lock()
+	stop := watchStop
+	watchStop = nil
+	watchFetcher = nil
+	watchMu.Unlock()
+
+	if stop != nil {
+		close(stop)
+	}
+}
+
+func runDueWatches(stop chan struct{}) {
+	watches, err := ListWatches()
+	if err != nil {
+		fmt.Printf("[Watch] Failed to list watches: %v\n", err)
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, watch := range watches {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if !watch.Enabled || watch.NextRunAt > now {
+			continue
+		}
+		if _, err := RunWatch(watch.ID); err != nil {
+			fmt.Printf("[Watch] Run for %s failed: %v\n", watch.ID, err)
+		}
+	}
+}
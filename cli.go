@@ -0,0 +1,810 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/afkarxyz/SpotiDownloader/backend"
+)
+
+const (
+	cliExitOK      = 0
+	cliExitFailure = 1
+	cliExitUsage   = 2
+)
+
+var cliCommands = map[string]func(*cliContext, []string) int{
+	"fetch":       runCLIFetch,
+	"download":    runCLIDownload,
+	"lyrics":      runCLILyrics,
+	"cover":       runCLICover,
+	"convert":     runCLIConvert,
+	"history":     runCLIHistory,
+	"sync":        runCLISync,
+	"spectrogram": runCLISpectrogram,
+	"verify":      runCLIVerify,
+	"duplicates":  runCLIDuplicates,
+	"retag":       runCLIRetag,
+	"serve":       runCLIServe,
+}
+
+type cliContext struct {
+	app *App
+	out io.Writer
+}
+
+type cliEvent struct {
+	Event     string  `json:"event"`
+	ItemID    string  `json:"item_id,omitempty"`
+	SpotifyID string  `json:"spotify_id,omitempty"`
+	Track     string  `json:"track,omitempty"`
+	Artist    string  `json:"artist,omitempty"`
+	File      string  `json:"file,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	MB        float64 `json:"mb,omitempty"`
+	SpeedMBps float64 `json:"speed_mbps,omitempty"`
+}
+
+type cliSummary struct {
+	Event     string      `json:"event"`
+	Total     int         `json:"total"`
+	Succeeded int         `json:"succeeded"`
+	Skipped   int         `json:"skipped"`
+	Failed    int         `json:"failed"`
+	Results   interface{} `json:"results"`
+}
+
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return true
+	}
+	_, ok := cliCommands[args[0]]
+	return ok
+}
+
+func runCLI(args []string) int {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	command, ok := cliCommands[args[0]]
+	if !ok {
+		printCLIUsage(os.Stderr)
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			return cliExitOK
+		}
+		return cliExitUsage
+	}
+
+	if err := backend.InitHistoryDB("SpotiDownloader"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init history DB: %v\n", err)
+	}
+	if err := backend.InitISRCCacheDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init ISRC cache DB: %v\n", err)
+	}
+	if err := backend.InitLibraryIndexDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init library index DB: %v\n", err)
+	}
+	if err := backend.InitMusicBrainzCacheDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init MusicBrainz cache DB: %v\n", err)
+	}
+
+	app := NewApp()
+	app.ctx = context.Background()
+	defer func() {
+		app.tasks.Wait()
+		backend.CloseHistoryDB()
+		backend.CloseISRCCacheDB()
+		backend.CloseLibraryIndexDB()
+		backend.CloseMusicBrainzCacheDB()
+	}()
+
+	return command(&cliContext{app: app, out: out}, args[1:])
+}
+
+func printCLIUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: SpotiDownloader <command> [options]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  fetch <url>             Print Spotify metadata as JSON")
+	fmt.Fprintln(w, "  download <url>...       Download tracks, albums, playlists or discographies")
+	fmt.Fprintln(w, "  lyrics <url>...         Save .lrc files for every track")
+	fmt.Fprintln(w, "  cover <url>...          Save cover images for every track")
+	fmt.Fprintln(w, "  convert <file>...       Convert audio files with FFmpeg")
+	fmt.Fprintln(w, "  history                 Print download history as JSON")
+	fmt.Fprintln(w, "  sync <playlist-url>     Download new playlist tracks and refresh its .m3u8")
+	fmt.Fprintln(w, "  spectrogram <path>...   Render spectrogram PNGs for audio files or folders")
+	fmt.Fprintln(w, "  verify <folder>         Re-hash downloads and compare against history checksums")
+	fmt.Fprintln(w, "  duplicates [folder]     Report duplicate recordings by ISRC or acoustic fingerprint")
+	fmt.Fprintln(w, "  retag <path>...         Rewrite tags from current Spotify metadata without re-downloading")
+	fmt.Fprintln(w, "  serve                   Run the download queue and the local REST/WebSocket API")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Defaults are read from config.json. Run '<command> -h' for options.")
+	fmt.Fprintln(w, "Exit codes: 0 = success, 1 = one or more items failed, 2 = usage or fetch error.")
+}
+
+func (c *cliContext) emit(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode output: %v\n", err)
+		return
+	}
+	fmt.Fprintln(c.out, string(data))
+}
+
+func (c *cliContext) emitIndented(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode output: %v\n", err)
+		return
+	}
+	fmt.Fprintln(c.out, string(data))
+}
+
+func newCLIFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func fetchCLIMetadata(spotifyURL string, separator string, timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return backend.GetFilteredSpotifyData(ctx, spotifyURL, false, time.Second, separator, nil)
+}
+
+func fetchCLICollections(urls []string, defaults downloadDefaults, timeout time.Duration) ([]*downloadCollection, error) {
+	collections := make([]*downloadCollection, 0, len(urls))
+	for _, spotifyURL := range urls {
+		data, err := fetchCLIMetadata(spotifyURL, defaults.Separator, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch metadata for %s: %v", spotifyURL, err)
+		}
+
+		collection, err := buildDownloadCollection(data, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spotifyURL, err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+func runCLIFetch(c *cliContext, args []string) int {
+	fs := newCLIFlagSet("fetch")
+	separator := fs.String("separator", "", "artist separator (defaults to config.json)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "metadata fetch timeout")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "fetch requires exactly one Spotify URL")
+		return cliExitUsage
+	}
+
+	sep := *separator
+	if sep == "" {
+		sep = loadDownloadDefaults().Separator
+	}
+
+	data, err := fetchCLIMetadata(fs.Arg(0), sep, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to fetch metadata: %v\n", err)
+		return cliExitUsage
+	}
+
+	c.emitIndented(data)
+	return cliExitOK
+}
+
+func runCLIDownload(c *cliContext, args []string) int {
+	defaults := loadDownloadDefaults()
+
+	fs := newCLIFlagSet("download")
+	fs.StringVar(&defaults.OutputDir, "output", defaults.OutputDir, "output directory")
+	fs.StringVar(&defaults.AudioFormat, "format", defaults.AudioFormat, "audio format (mp3 or flac)")
+	fs.StringVar(&defaults.FilenameFormat, "filename-format", defaults.FilenameFormat, "filename template")
+	fs.BoolVar(&defaults.TrackNumber, "track-number", defaults.TrackNumber, "prefix filenames with the track number")
+	fs.BoolVar(&defaults.EmbedLyrics, "lyrics", defaults.EmbedLyrics, "embed lyrics")
+	fs.BoolVar(&defaults.EmbedMaxQualityCover, "max-cover", defaults.EmbedMaxQualityCover, "embed the highest resolution cover")
+	fs.BoolVar(&defaults.EmbedGenre, "genre", defaults.EmbedGenre, "embed genre from MusicBrainz")
+	fs.BoolVar(&defaults.CreatePlaylistFolder, "playlist-folder", defaults.CreatePlaylistFolder, "create a folder per playlist")
+	timeout := fs.Duration("timeout", 5*time.Minute, "metadata fetch timeout")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "download requires at least one Spotify URL")
+		return cliExitUsage
+	}
+	if err := validateDownloadTemplates(newDownloadRequest(defaults)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+	if defaults.AudioFormat != "mp3" && defaults.AudioFormat != "flac" {
+		fmt.Fprintf(os.Stderr, "unsupported audio format: %s\n", defaults.AudioFormat)
+		return cliExitUsage
+	}
+
+	collections, err := fetchCLICollections(fs.Args(), defaults, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary"}
+	results := []DownloadResponse{}
+	for _, collection := range collections {
+		for _, req := range collection.Requests {
+			summary.Total++
+			resp := c.downloadTrack(req)
+			results = append(results, resp)
+			switch {
+			case !resp.Success:
+				summary.Failed++
+			case resp.AlreadyExists:
+				summary.Skipped++
+			default:
+				summary.Succeeded++
+			}
+		}
+	}
+	summary.Results = results
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func (c *cliContext) downloadTrack(req DownloadRequest) DownloadResponse {
+	token, err := backend.FetchSessionToken()
+	if err != nil {
+		resp := DownloadResponse{Success: false, Error: fmt.Sprintf("failed to fetch session token: %v", err)}
+		c.emit(cliEvent{Event: "failed", SpotifyID: req.SpotifyID, Track: req.TrackName, Artist: req.ArtistName, Error: resp.Error})
+		return resp
+	}
+	req.SessionToken = token
+
+	c.emit(cliEvent{Event: "started", SpotifyID: req.SpotifyID, Track: req.TrackName, Artist: req.ArtistName})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress := backend.GetDownloadProgress()
+				if progress.IsDownloading && progress.MBDownloaded > 0 {
+					c.emit(cliEvent{Event: "progress", SpotifyID: req.SpotifyID, MB: progress.MBDownloaded, SpeedMBps: progress.SpeedMBps})
+				}
+			}
+		}
+	}()
+
+	resp, err := c.app.DownloadTrack(req)
+	close(done)
+
+	event := cliEvent{Event: "completed", ItemID: resp.ItemID, SpotifyID: req.SpotifyID, Track: req.TrackName, Artist: req.ArtistName, File: resp.File}
+	if err != nil || !resp.Success {
+		event.Event = "failed"
+		event.Error = resp.Error
+		if event.Error == "" && err != nil {
+			event.Error = err.Error()
+		}
+		resp.Success = false
+		resp.Error = event.Error
+	} else if resp.AlreadyExists {
+		event.Event = "skipped"
+	}
+	c.emit(event)
+
+	return resp
+}
+
+func runCLILyrics(c *cliContext, args []string) int {
+	defaults := loadDownloadDefaults()
+
+	fs := newCLIFlagSet("lyrics")
+	fs.StringVar(&defaults.OutputDir, "output", defaults.OutputDir, "output directory")
+	fs.StringVar(&defaults.FilenameFormat, "filename-format", defaults.FilenameFormat, "filename template")
+	fs.BoolVar(&defaults.TrackNumber, "track-number", defaults.TrackNumber, "prefix filenames with the track number")
+	timeout := fs.Duration("timeout", 5*time.Minute, "metadata fetch timeout")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "lyrics requires at least one Spotify URL")
+		return cliExitUsage
+	}
+	if err := validateDownloadTemplates(newDownloadRequest(defaults)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	collections, err := fetchCLICollections(fs.Args(), defaults, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	client := backend.NewLyricsClient()
+	summary := cliSummary{Event: "summary"}
+	results := []backend.LyricsDownloadResponse{}
+	for _, collection := range collections {
+		for _, req := range collection.Requests {
+			summary.Total++
+			resp, err := client.DownloadLyrics(backend.LyricsDownloadRequest{
+				SpotifyID:      req.SpotifyID,
+				TrackName:      req.TrackName,
+				ArtistName:     req.ArtistName,
+				AlbumName:      req.AlbumName,
+				AlbumArtist:    req.AlbumArtist,
+				ReleaseDate:    req.ReleaseDate,
+				OutputDir:      req.OutputDir,
+				FilenameFormat: req.FilenameFormat,
+				TrackNumber:    req.TrackNumber,
+				Position:       req.Position,
+				DiscNumber:     req.DiscNumber,
+			})
+			if resp == nil {
+				resp = &backend.LyricsDownloadResponse{}
+			}
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+
+			event := cliEvent{Event: "completed", SpotifyID: req.SpotifyID, Track: req.TrackName, Artist: req.ArtistName, File: resp.File}
+			switch {
+			case !resp.Success:
+				summary.Failed++
+				event.Event = "failed"
+				event.Error = resp.Error
+			case resp.AlreadyExists:
+				summary.Skipped++
+				event.Event = "skipped"
+			default:
+				summary.Succeeded++
+			}
+			c.emit(event)
+			results = append(results, *resp)
+		}
+	}
+	summary.Results = results
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLICover(c *cliContext, args []string) int {
+	defaults := loadDownloadDefaults()
+
+	fs := newCLIFlagSet("cover")
+	fs.StringVar(&defaults.OutputDir, "output", defaults.OutputDir, "output directory")
+	fs.StringVar(&defaults.FilenameFormat, "filename-format", defaults.FilenameFormat, "filename template")
+	fs.BoolVar(&defaults.TrackNumber, "track-number", defaults.TrackNumber, "prefix filenames with the track number")
+	timeout := fs.Duration("timeout", 5*time.Minute, "metadata fetch timeout")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "cover requires at least one Spotify URL")
+		return cliExitUsage
+	}
+	if err := validateDownloadTemplates(newDownloadRequest(defaults)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	collections, err := fetchCLICollections(fs.Args(), defaults, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	client := backend.NewCoverClient()
+	summary := cliSummary{Event: "summary"}
+	results := []backend.CoverDownloadResponse{}
+	for _, collection := range collections {
+		for _, req := range collection.Requests {
+			summary.Total++
+			resp, err := client.DownloadCover(backend.CoverDownloadRequest{
+				CoverURL:       req.CoverURL,
+				TrackName:      req.TrackName,
+				ArtistName:     req.ArtistName,
+				AlbumName:      req.AlbumName,
+				AlbumArtist:    req.AlbumArtist,
+				ReleaseDate:    req.ReleaseDate,
+				OutputDir:      req.OutputDir,
+				FilenameFormat: req.FilenameFormat,
+				TrackNumber:    req.TrackNumber,
+				Position:       req.Position,
+				DiscNumber:     req.DiscNumber,
+			})
+			if resp == nil {
+				resp = &backend.CoverDownloadResponse{}
+			}
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+
+			event := cliEvent{Event: "completed", SpotifyID: req.SpotifyID, Track: req.TrackName, Artist: req.ArtistName, File: resp.File}
+			switch {
+			case !resp.Success:
+				summary.Failed++
+				event.Event = "failed"
+				event.Error = resp.Error
+			case resp.AlreadyExists:
+				summary.Skipped++
+				event.Event = "skipped"
+			default:
+				summary.Succeeded++
+			}
+			c.emit(event)
+			results = append(results, *resp)
+		}
+	}
+	summary.Results = results
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLIConvert(c *cliContext, args []string) int {
+	fs := newCLIFlagSet("convert")
+	outputFormat := fs.String("format", "mp3", "output format (mp3, m4a, flac, ...)")
+	bitrate := fs.String("bitrate", "320k", "output bitrate")
+	codec := fs.String("codec", "", "output codec")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "convert requires at least one input file")
+		return cliExitUsage
+	}
+
+	results, err := backend.ConvertAudio(backend.ConvertAudioRequest{
+		InputFiles:   fs.Args(),
+		OutputFormat: strings.ToLower(*outputFormat),
+		Bitrate:      *bitrate,
+		Codec:        *codec,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conversion failed: %v\n", err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary", Total: len(results), Results: results}
+	for _, result := range results {
+		if result.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLIHistory(c *cliContext, args []string) int {
+	fs := newCLIFlagSet("history")
+	limit := fs.Int("limit", 0, "maximum number of entries to print (0 = all)")
+	clear := fs.Bool("clear", false, "clear the download history")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+
+	if *clear {
+		if err := backend.ClearHistory("SpotiDownloader"); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clear history: %v\n", err)
+			return cliExitFailure
+		}
+		c.emit(map[string]bool{"cleared": true})
+		return cliExitOK
+	}
+
+	items, err := backend.GetHistoryItems("SpotiDownloader")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read history: %v\n", err)
+		return cliExitFailure
+	}
+	if items == nil {
+		items = []backend.HistoryItem{}
+	}
+	if *limit > 0 && len(items) > *limit {
+		items = items[:*limit]
+	}
+
+	c.emitIndented(items)
+	return cliExitOK
+}
+
+func runCLISync(c *cliContext, args []string) int {
+	var req SyncPlaylistRequest
+
+	fs := newCLIFlagSet("sync")
+	fs.StringVar(&req.OutputDir, "output", "", "output directory (defaults to the last sync or config.json)")
+	fs.StringVar(&req.AudioFormat, "format", "", "audio format (mp3 or flac)")
+	fs.StringVar(&req.RemovedAction, "removed", backend.SyncRemovedKeep, "what to do with removed tracks: keep, archive or delete")
+	fs.StringVar(&req.ArchiveDir, "archive", "", "archive folder for removed tracks")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "sync requires exactly one playlist URL")
+		return cliExitUsage
+	}
+	switch req.RemovedAction {
+	case backend.SyncRemovedKeep, backend.SyncRemovedArchive, backend.SyncRemovedDelete:
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported removed action: %s\n", req.RemovedAction)
+		return cliExitUsage
+	}
+	req.URL = fs.Arg(0)
+
+	plan, err := c.app.planPlaylistSync(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	failed := 0
+	for _, r := range plan.pending {
+		resp := c.downloadTrack(r)
+		if !resp.Success {
+			failed++
+			continue
+		}
+		if resp.File != "" {
+			plan.setTrackPath(r.SpotifyID, resp.File)
+		}
+	}
+
+	if err := c.app.finishPlaylistSync(plan, failed); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save sync state: %v\n", err)
+		return cliExitFailure
+	}
+	c.emitIndented(plan.result)
+
+	if failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLISpectrogram(c *cliContext, args []string) int {
+	options := backend.DefaultSpectrogramOptions()
+
+	fs := newCLIFlagSet("spectrogram")
+	fs.StringVar(&options.ColorMap, "colors", options.ColorMap, "color map (spek, viridis, hot, cool, grayscale)")
+	fs.StringVar(&options.FreqScale, "scale", options.FreqScale, "frequency axis scale (linear or log2)")
+	fs.IntVar(&options.WindowSize, "window", options.WindowSize, "FFT window size (power of two)")
+	fs.StringVar(&options.WindowFunction, "window-function", options.WindowFunction, "window function (hann, hamming, blackman, rectangular)")
+	fs.IntVar(&options.Width, "width", options.Width, "image width in pixels")
+	fs.IntVar(&options.Height, "height", options.Height, "image height in pixels")
+	fs.StringVar(&options.OutputDir, "output", "", "output directory (defaults to next to each audio file)")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "spectrogram requires at least one file or folder")
+		return cliExitUsage
+	}
+
+	results, err := backend.RenderSpectrograms(fs.Args(), options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary", Total: len(results), Results: results}
+	for _, result := range results {
+		if result.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLIVerify(c *cliContext, args []string) int {
+	fs := newCLIFlagSet("verify")
+	strict := fs.Bool("strict", false, "treat untracked files and tag edits as failures")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "verify requires exactly one folder")
+		return cliExitUsage
+	}
+
+	results, err := backend.VerifyLibrary(fs.Arg(0), "SpotiDownloader")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary", Total: len(results), Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case backend.VerifyStatusOK:
+			summary.Succeeded++
+		case backend.VerifyStatusUntracked, backend.VerifyStatusTagsEdited:
+			if *strict {
+				summary.Failed++
+			} else {
+				summary.Skipped++
+			}
+		default:
+			summary.Failed++
+		}
+	}
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLIDuplicates(c *cliContext, args []string) int {
+	fs := newCLIFlagSet("duplicates")
+	fingerprint := fs.Bool("fingerprint", false, "also compare Chromaprint acoustic fingerprints (slower)")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "duplicates accepts at most one folder")
+		return cliExitUsage
+	}
+
+	root := backend.GetLibraryRootSetting()
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+
+	groups, err := backend.FindLibraryDuplicates(root, *fingerprint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary", Total: len(groups), Results: groups}
+	c.emit(summary)
+	return cliExitOK
+}
+
+func runCLIRetag(c *cliContext, args []string) int {
+	defaults := loadDownloadDefaults()
+	options := backend.RetagOptions{EmbedGenre: defaults.EmbedGenre, UseSingleGenre: defaults.UseSingleGenre}
+
+	fs := newCLIFlagSet("retag")
+	fs.BoolVar(&options.Preview, "preview", false, "only report tag changes, do not write files")
+	fs.BoolVar(&options.EmbedGenre, "genre", options.EmbedGenre, "embed genre from MusicBrainz")
+	fs.BoolVar(&options.UseSingleGenre, "single-genre", options.UseSingleGenre, "keep only the first MusicBrainz genre")
+	fs.BoolVar(&options.UseAlbumTrackNumber, "album-numbers", false, "replace track numbers with the Spotify album track number")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "retag requires at least one file or folder")
+		return cliExitUsage
+	}
+
+	var paths []string
+	for _, path := range fs.Args() {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+		audioFiles, err := backend.ListAudioFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return cliExitUsage
+		}
+		for _, audioFile := range audioFiles {
+			paths = append(paths, audioFile.Path)
+		}
+	}
+
+	results, err := c.app.RetagFiles(paths, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	summary := cliSummary{Event: "summary", Total: len(results), Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case backend.RetagStatusUpdated, backend.RetagStatusChanged:
+			summary.Succeeded++
+		case backend.RetagStatusFailed:
+			summary.Failed++
+		default:
+			summary.Skipped++
+		}
+	}
+	c.emit(summary)
+
+	if summary.Failed > 0 {
+		return cliExitFailure
+	}
+	return cliExitOK
+}
+
+func runCLIServe(c *cliContext, args []string) int {
+	config := loadAPIServerConfig()
+
+	fs := newCLIFlagSet("serve")
+	fs.StringVar(&config.Bind, "bind", config.Bind, "address to bind the API server to")
+	fs.IntVar(&config.Port, "port", config.Port, "port to listen on")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+
+	server, err := newAPIServer(c.app, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitUsage
+	}
+
+	if err := backend.InitDownloadQueueDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init download queue DB: %v\n", err)
+		return cliExitFailure
+	}
+	defer backend.CloseDownloadQueueDB()
+
+	if err := backend.RecoverPartialDownloads(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to recover partial downloads: %v\n", err)
+	}
+	if err := backend.StartDownloadQueue(c.app.runQueuedDownload, backend.GetDownloadWorkerSetting()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start download queue: %v\n", err)
+		return cliExitFailure
+	}
+	defer backend.StopDownloadQueue()
+
+	if err := server.start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cliExitFailure
+	}
+	defer server.stop()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	return cliExitOK
+}
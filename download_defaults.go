@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/afkarxyz/SpotiDownloader/backend"
+)
+
+type downloadDefaults struct {
+	OutputDir            string
+	AudioFormat          string
+	FilenameFormat       string
+	FolderTemplate       string
+	TrackNumber          bool
+	EmbedLyrics          bool
+	EmbedMaxQualityCover bool
+	CreatePlaylistFolder bool
+	PlaylistOwnerFolder  bool
+	UseFirstArtistOnly   bool
+	UseSingleGenre       bool
+	EmbedGenre           bool
+	Separator            string
+}
+
+func loadDownloadDefaults() downloadDefaults {
+	defaults := downloadDefaults{
+		OutputDir:            backend.GetDefaultMusicPath(),
+		AudioFormat:          "mp3",
+		FilenameFormat:       "{title} - {artist}",
+		CreatePlaylistFolder: true,
+		Separator:            "; ",
+	}
+
+	settings, err := backend.LoadConfigSettings()
+	if err != nil || settings == nil {
+		return defaults
+	}
+
+	if path, ok := settings["downloadPath"].(string); ok && strings.TrimSpace(path) != "" {
+		defaults.OutputDir = path
+	}
+	if format, ok := settings["audioFormat"].(string); ok && (format == "mp3" || format == "flac") {
+		defaults.AudioFormat = format
+	}
+	if template, ok := settings["filenameTemplate"].(string); ok && strings.TrimSpace(template) != "" {
+		defaults.FilenameFormat = template
+	}
+	if template, ok := settings["folderTemplate"].(string); ok {
+		defaults.FolderTemplate = strings.TrimSpace(template)
+	}
+	if sep, ok := settings["separator"].(string); ok {
+		if sep == "comma" {
+			defaults.Separator = ", "
+		} else if sep == "semicolon" {
+			defaults.Separator = "; "
+		}
+	}
+
+	defaults.TrackNumber, _ = settings["trackNumber"].(bool)
+	defaults.EmbedLyrics, _ = settings["embedLyrics"].(bool)
+	defaults.EmbedMaxQualityCover, _ = settings["embedMaxQualityCover"].(bool)
+	defaults.UseFirstArtistOnly, _ = settings["useFirstArtistOnly"].(bool)
+	defaults.UseSingleGenre, _ = settings["useSingleGenre"].(bool)
+	defaults.EmbedGenre, _ = settings["embedGenre"].(bool)
+	if createFolder, ok := settings["createPlaylistFolder"].(bool); ok {
+		defaults.CreatePlaylistFolder = createFolder
+	}
+	defaults.PlaylistOwnerFolder, _ = settings["playlistOwnerFolderName"].(bool)
+
+	return defaults
+}
+
+type downloadCollection struct {
+	Type     string            `json:"type"`
+	Name     string            `json:"name"`
+	Owner    string            `json:"owner,omitempty"`
+	Requests []DownloadRequest `json:"requests"`
+}
+
+func buildDownloadCollection(data interface{}, defaults downloadDefaults) (*downloadCollection, error) {
+	switch payload := data.(type) {
+	case backend.TrackResponse:
+		return buildTrackCollection(payload, defaults), nil
+	case *backend.TrackResponse:
+		if payload != nil {
+			return buildTrackCollection(*payload, defaults), nil
+		}
+	case backend.AlbumResponsePayload:
+		return buildAlbumCollection(payload, defaults), nil
+	case *backend.AlbumResponsePayload:
+		if payload != nil {
+			return buildAlbumCollection(*payload, defaults), nil
+		}
+	case backend.PlaylistResponsePayload:
+		return buildPlaylistCollection(payload, defaults), nil
+	case *backend.PlaylistResponsePayload:
+		if payload != nil {
+			return buildPlaylistCollection(*payload, defaults), nil
+		}
+	case backend.ArtistDiscographyPayload:
+		return buildArtistCollection(payload, defaults), nil
+	case *backend.ArtistDiscographyPayload:
+		if payload != nil {
+			return buildArtistCollection(*payload, defaults), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported metadata payload: %T", data)
+}
+
+func buildTrackCollection(payload backend.TrackResponse, defaults downloadDefaults) *downloadCollection {
+	track := payload.Track
+	req := newDownloadRequest(defaults)
+	req.TrackID = track.SpotifyID
+	req.SpotifyID = track.SpotifyID
+	req.TrackName = track.Name
+	req.ArtistName = track.Artists
+	req.AlbumName = track.AlbumName
+	req.AlbumArtist = track.AlbumArtist
+	req.ReleaseDate = track.ReleaseDate
+	req.CoverURL = track.Images
+	req.AlbumTrackNumber = track.TrackNumber
+	req.DiscNumber = track.DiscNumber
+	req.TotalTracks = track.TotalTracks
+	req.SpotifyTotalDiscs = track.TotalDiscs
+	req.Copyright = track.Copyright
+	req.Publisher = track.Publisher
+	req.Composer = track.Composer
+	req.Duration = track.DurationMS / 1000
+	req.UPC = track.UPC
+	req.Explicit = track.IsExplicit
+	if req.AlbumArtist == "" {
+		req.AlbumArtist = req.ArtistName
+	}
+	useAlbumTrackNumber(&req)
+
+	return &downloadCollection{
+		Type:     "track",
+		Name:     track.Name,
+		Requests: []DownloadRequest{req},
+	}
+}
+
+func buildAlbumCollection(payload backend.AlbumResponsePayload, defaults downloadDefaults) *downloadCollection {
+	collection := &downloadCollection{
+		Type: "album",
+		Name: payload.AlbumInfo.Name,
+	}
+	for i, track := range payload.TrackList {
+		req := newDownloadRequestFromAlbumTrack(track, defaults, i+1)
+		req.IsAlbum = true
+		if defaults.CreatePlaylistFolder {
+			req.PlaylistName = collection.Name
+		}
+		collection.Requests = append(collection.Requests, req)
+	}
+	return collection
+}
+
+func buildPlaylistCollection(payload backend.PlaylistResponsePayload, defaults downloadDefaults) *downloadCollection {
+	collection := &downloadCollection{
+		Type:  "playlist",
+		Name:  payload.PlaylistInfo.Owner.Name,
+		Owner: payload.PlaylistInfo.Owner.DisplayName,
+	}
+	for i, track := range payload.TrackList {
+		req := newDownloadRequestFromAlbumTrack(track, defaults, i+1)
+		if defaults.CreatePlaylistFolder {
+			req.PlaylistName = playlistFolderName(collection.Name, collection.Owner, defaults.PlaylistOwnerFolder)
+			req.PlaylistOwner = collection.Owner
+		}
+		collection.Requests = append(collection.Requests, req)
+	}
+	return collection
+}
+
+func buildArtistCollection(payload backend.ArtistDiscographyPayload, defaults downloadDefaults) *downloadCollection {
+	collection := &downloadCollection{
+		Type: "artist",
+		Name: payload.ArtistInfo.Name,
+	}
+	for i, track := range payload.TrackList {
+		req := newDownloadRequestFromAlbumTrack(track, defaults, i+1)
+		req.IsAlbum = true
+		if defaults.CreatePlaylistFolder {
+			req.PlaylistName = collection.Name
+		}
+		collection.Requests = append(collection.Requests, req)
+	}
+	return collection
+}
+
+func newDownloadRequest(defaults downloadDefaults) DownloadRequest {
+	return DownloadRequest{
+		OutputDir:            defaults.OutputDir,
+		AudioFormat:          defaults.AudioFormat,
+		FilenameFormat:       defaults.FilenameFormat,
+		FolderTemplate:       defaults.FolderTemplate,
+		TrackNumber:          defaults.TrackNumber,
+		EmbedLyrics:          defaults.EmbedLyrics,
+		EmbedMaxQualityCover: defaults.EmbedMaxQualityCover,
+		UseFirstArtistOnly:   defaults.UseFirstArtistOnly,
+		UseSingleGenre:       defaults.UseSingleGenre,
+		EmbedGenre:           defaults.EmbedGenre,
+		Separator:            defaults.Separator,
+	}
+}
+
+func newDownloadRequestFromAlbumTrack(track backend.AlbumTrackMetadata, defaults downloadDefaults, position int) DownloadRequest {
+	req := newDownloadRequest(defaults)
+	req.TrackID = track.SpotifyID
+	req.SpotifyID = track.SpotifyID
+	req.TrackName = track.Name
+	req.ArtistName = track.Artists
+	req.AlbumName = track.AlbumName
+	req.AlbumArtist = track.AlbumArtist
+	req.ReleaseDate = track.ReleaseDate
+	req.CoverURL = track.Images
+	req.AlbumTrackNumber = track.TrackNumber
+	req.DiscNumber = track.DiscNumber
+	req.TotalTracks = track.TotalTracks
+	req.SpotifyTotalDiscs = track.TotalDiscs
+	req.Position = position
+	req.Duration = track.DurationMS / 1000
+	req.UPC = track.UPC
+	req.Explicit = track.IsExplicit
+	if req.AlbumArtist == "" {
+		req.AlbumArtist = req.ArtistName
+	}
+	useAlbumTrackNumber(&req)
+	return req
+}
+
+func useAlbumTrackNumber(req *DownloadRequest) {
+	if req.FolderTemplate == "" {
+		return
+	}
+	req.UseAlbumTrackNumber = true
+	if req.AlbumTrackNumber > 0 {
+		req.Position = req.AlbumTrackNumber
+	}
+}
+
+func downloadTemplateData(req DownloadRequest) backend.FilenameTemplateData {
+	artistName := req.ArtistName
+	albumArtist := req.AlbumArtist
+	if req.UseFirstArtistOnly {
+		artistName = backend.GetFirstArtist(artistName)
+		albumArtist = backend.GetFirstArtist(albumArtist)
+	}
+
+	spotifyID := req.TrackID
+	if spotifyID == "" {
+		spotifyID = req.SpotifyID
+	}
+
+	return backend.FilenameTemplateData{
+		Title:       req.TrackName,
+		Artist:      artistName,
+		Album:       req.AlbumName,
+		AlbumArtist: albumArtist,
+		ReleaseDate: req.ReleaseDate,
+		Playlist:    req.PlaylistName,
+		Creator:     req.PlaylistOwner,
+		ISRC:        req.ISRC,
+		UPC:         req.UPC,
+		SpotifyID:   spotifyID,
+		Track:       req.Position,
+		Disc:        req.DiscNumber,
+		TotalTracks: req.TotalTracks,
+		Duration:    req.Duration,
+		Explicit:    req.Explicit,
+	}
+}
+
+func downloadTemplatesUse(req DownloadRequest, token string) bool {
+	return backend.TemplateUsesToken(req.FilenameFormat, token) || backend.TemplateUsesToken(req.FolderTemplate, token)
+}
+
+type FolderPathResult struct {
+	BaseDir      string `json:"base_dir"`
+	OutputDir    string `json:"output_dir"`
+	RelativePath string `json:"relative_path,omitempty"`
+}
+
+func playlistFolderName(name, owner string, includeOwner bool) string {
+	name = strings.TrimSpace(name)
+	owner = strings.TrimSpace(owner)
+	if name == "" || !includeOwner || owner == "" || strings.EqualFold(owner, name) {
+		return name
+	}
+	return name + ", " + owner
+}
+
+func playlistFolderDir(outputDir, playlistName, folderTemplate string, isAlbum bool) string {
+	if playlistName == "" || backend.TemplateUsesToken(folderTemplate, "playlist") {
+		return outputDir
+	}
+	if isAlbum && (backend.TemplateUsesToken(folderTemplate, "album") || backend.TemplateUsesToken(folderTemplate, "album_artist")) {
+		return outputDir
+	}
+	return filepath.Join(outputDir, backend.SanitizeFilename(playlistName))
+}
+
+func resolveFolderPath(outputDir, playlistName, folderTemplate string, isAlbum bool, data backend.FilenameTemplateData) FolderPathResult {
+	result := FolderPathResult{BaseDir: playlistFolderDir(outputDir, playlistName, folderTemplate, isAlbum)}
+	if folderTemplate != "" {
+		if data.AlbumArtist == "" {
+			data.AlbumArtist = data.Artist
+		}
+		result.RelativePath = backend.BuildFolderPath(folderTemplate, data)
+	}
+	result.OutputDir = filepath.Join(result.BaseDir, result.RelativePath)
+	return result
+}
+
+func resolveDownloadDir(req DownloadRequest) string {
+	if req.OutputDir == "" {
+		return "."
+	}
+	return backend.SanitizeFolderPath(resolveFolderPath(req.OutputDir, req.PlaylistName, req.FolderTemplate, req.IsAlbum, downloadTemplateData(req)).OutputDir)
+}
+
+func validateDownloadTemplates(req DownloadRequest) error {
+	if err := backend.ValidateFilenameTemplate(req.FilenameFormat); err != nil {
+		return fmt.Errorf("filename template: %w", err)
+	}
+	if err := backend.ValidateFolderTemplate(req.FolderTemplate); err != nil {
+		return fmt.Errorf("folder template: %w", err)
+	}
+	return nil
+}
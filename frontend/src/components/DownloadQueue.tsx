@@ -1,48 +1,22 @@
-import { useEffect, useState } from "react";
-import { X, Download, CheckCircle2, XCircle, Clock, FileCheck, Trash2, HardDrive, Zap, Timer, FileDown, } from "lucide-react";
+import { useState } from "react";
+import { X, Download, CheckCircle2, XCircle, Clock, FileCheck, Trash2, HardDrive, Zap, Timer, Gauge, FileDown, Pause, Play, Ban, RotateCcw, } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Dialog, DialogContent, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
 import { Badge } from "@/components/ui/badge";
-import { GetDownloadQueue, ClearCompletedDownloads, ClearAllDownloads, ExportFailedDownloads, } from "../../wailsjs/go/main/App";
+import { ClearCompletedDownloads, ClearAllDownloads, ExportFailedDownloads, RetryFailedDownloads, PauseDownloadItem, ResumeDownloadItem, CancelDownloadItem, } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
-import { backend } from "../../wailsjs/go/models";
+import { useDownloadQueueData } from "@/hooks/useDownloadQueueData";
+import { refreshDownloadQueue } from "@/lib/download-events";
 interface DownloadQueueProps {
     isOpen: boolean;
     onClose: () => void;
 }
 export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
-    const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
-        is_downloading: false,
-        queue: [],
-        current_speed: 0,
-        total_downloaded: 0,
-        session_start_time: 0,
-        queued_count: 0,
-        completed_count: 0,
-        failed_count: 0,
-        skipped_count: 0,
-    }));
-    useEffect(() => {
-        if (!isOpen)
-            return;
-        const fetchQueue = async () => {
-            try {
-                const info = await GetDownloadQueue();
-                setQueueInfo(info);
-            }
-            catch (error) {
-                console.error("Failed to get download queue:", error);
-            }
-        };
-        fetchQueue();
-        const interval = setInterval(fetchQueue, 500);
-        return () => clearInterval(interval);
-    }, [isOpen]);
+    const queueInfo = useDownloadQueueData();
     const handleClearHistory = async () => {
         try {
             await ClearCompletedDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
+            await refreshDownloadQueue();
         }
         catch (error) {
             console.error("Failed to clear history:", error);
@@ -51,8 +25,7 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
     const handleReset = async () => {
         try {
             await ClearAllDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
+            await refreshDownloadQueue();
             toast.success("Download queue reset");
         }
         catch (error) {
@@ -74,6 +47,32 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             toast.error(`Failed to export: ${error}`);
         }
     };
+    const handleRetryFailed = async () => {
+        try {
+            const count = await RetryFailedDownloads();
+            await refreshDownloadQueue();
+            if (count > 0) {
+                toast.success(`Retrying ${count} failed download${count === 1 ? "" : "s"}`);
+            }
+            else {
+                toast.info("No failed downloads can be retried");
+            }
+        }
+        catch (error) {
+            console.error("Failed to retry downloads:", error);
+            toast.error(`Failed to retry downloads: ${error}`);
+        }
+    };
+    const handleItemAction = async (action: (id: string) => Promise<void>, id: string, label: string) => {
+        try {
+            await action(id);
+            await refreshDownloadQueue();
+        }
+        catch (error) {
+            console.error(`Failed to ${label} download:`, error);
+            toast.error(`Failed to ${label} download: ${error}`);
+        }
+    };
     const getStatusIcon = (status: string) => {
         switch (status) {
             case "downloading":
@@ -86,6 +85,10 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                 return <FileCheck className="h-4 w-4 text-yellow-500"/>;
             case "queued":
                 return <Clock className="h-4 w-4 text-muted-foreground"/>;
+            case "paused":
+                return <Pause className="h-4 w-4 text-orange-500"/>;
+            case "cancelled":
+                return <Ban className="h-4 w-4 text-muted-foreground"/>;
             default:
                 return null;
         }
@@ -97,6 +100,8 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             failed: "destructive",
             skipped: "secondary",
             queued: "outline",
+            paused: "secondary",
+            cancelled: "outline",
         };
         return (<Badge variant={variants[status] || "outline"} className="text-xs">
         {status}
@@ -139,10 +144,15 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             <div className="flex items-center gap-2">
               {(queueInfo.completed_count > 0 ||
             queueInfo.failed_count > 0 ||
-            queueInfo.skipped_count > 0) && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleClearHistory}>
+            queueInfo.skipped_count > 0 ||
+            queueInfo.cancelled_count > 0) && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleClearHistory}>
                   <Trash2 className="h-3 w-3"/>
                   Clear History
                 </Button>)}
+              {queueInfo.failed_count > 0 && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleRetryFailed}>
+                  <RotateCcw className="h-3 w-3"/>
+                  Retry Failed
+                </Button>)}
               {queueInfo.failed_count > 0 && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleExportFailed}>
                   <FileDown className="h-3 w-3"/>
                   Export Failures
@@ -159,6 +169,11 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
               <span className="text-muted-foreground">Queued:</span>
               <span className="font-semibold">{queueInfo.queued_count}</span>
             </div>
+            {queueInfo.paused_count > 0 && (<div className={`flex items-center gap-1.5 cursor-pointer hover:opacity-80 transition-all select-none ${filterStatus === "paused" ? "bg-orange-500/10 px-2 py-0.5 rounded-md ring-1 ring-orange-500/20" : ""}`} onClick={() => toggleFilter("paused")}>
+                <Pause className="h-3.5 w-3.5 text-orange-500"/>
+                <span className="text-muted-foreground">Paused:</span>
+                <span className="font-semibold">{queueInfo.paused_count}</span>
+              </div>)}
             <div className={`flex items-center gap-1.5 cursor-pointer hover:opacity-80 transition-all select-none ${filterStatus === "completed" ? "bg-green-500/10 px-2 py-0.5 rounded-md ring-1 ring-green-500/20" : ""}`} onClick={() => toggleFilter("completed")}>
               <CheckCircle2 className="h-3.5 w-3.5 text-green-500"/>
               <span className="text-muted-foreground">Completed:</span>
@@ -204,6 +219,17 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             : "—"}
               </span>
             </div>
+            <div className="flex items-center gap-1.5">
+              <Gauge className="h-3.5 w-3.5 text-muted-foreground"/>
+              <span className="text-muted-foreground">Limit:</span>
+              <span className="font-semibold font-mono">
+                {queueInfo.bandwidth && !queueInfo.bandwidth.window_open
+            ? `Paused until ${new Date(queueInfo.bandwidth.next_window_at * 1000).toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" })}`
+            : queueInfo.bandwidth?.limit_kbps
+                ? `${queueInfo.bandwidth.limit_kbps} KB/s`
+                : "Unlimited"}
+              </span>
+            </div>
           </div>
         </DialogHeader>
 
@@ -232,7 +258,18 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                             {item.album_name && ` • ${item.album_name}`}
                           </p>
                         </div>
-                        {getStatusBadge(item.status)}
+                        <div className="flex items-center gap-1">
+                          {(item.status === "queued" || item.status === "downloading") && (<Button variant="ghost" size="icon" className="h-6 w-6" title="Pause" onClick={() => handleItemAction(PauseDownloadItem, item.id, "pause")}>
+                              <Pause className="h-3 w-3"/>
+                            </Button>)}
+                          {item.status === "paused" && (<Button variant="ghost" size="icon" className="h-6 w-6" title="Resume" onClick={() => handleItemAction(ResumeDownloadItem, item.id, "resume")}>
+                              <Play className="h-3 w-3"/>
+                            </Button>)}
+                          {(item.status === "queued" || item.status === "downloading" || item.status === "paused") && (<Button variant="ghost" size="icon" className="h-6 w-6" title="Cancel" onClick={() => handleItemAction(CancelDownloadItem, item.id, "cancel")}>
+                              <X className="h-3 w-3"/>
+                            </Button>)}
+                          {getStatusBadge(item.status)}
+                        </div>
                       </div>
 
                       {item.status === "downloading" && (<div className="flex items-center gap-3 mt-1.5 text-xs text-muted-foreground font-mono">
@@ -251,6 +288,12 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                         ? `${queueInfo.current_speed.toFixed(2)} MB/s`
                         : "—"}
                           </span>
+                          {item.max_attempts > 1 && item.attempt > 0 && (<span>
+                              Attempt {item.attempt}/{item.max_attempts}
+                            </span>)}
+                          {item.next_retry_at > 0 && (<span className="text-orange-500">
+                              Retrying at {new Date(item.next_retry_at * 1000).toLocaleTimeString()}
+                            </span>)}
                         </div>)}
 
                       {item.status === "completed" && (<div className="flex items-center gap-3 mt-1.5 text-xs text-muted-foreground">
@@ -4,7 +4,7 @@ import { Label } from "@/components/ui/label";
 import { InputWithContext } from "@/components/ui/input-with-context";
 import { Checkbox } from "@/components/ui/checkbox";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
-import { FolderOpen, RefreshCw, FileMusic, ChevronRight, ChevronDown, Pencil, Eye, Folder, Info, RotateCcw, FileText, Image, Copy, Check, } from "lucide-react";
+import { FolderOpen, RefreshCw, FileMusic, ChevronRight, ChevronDown, Pencil, Eye, Folder, Info, RotateCcw, FileText, Image, Copy, Check, Volume2, Tags, } from "lucide-react";
 import { Tooltip, TooltipTrigger, TooltipContent } from "@/components/ui/tooltip";
 import { Spinner } from "@/components/ui/spinner";
 import { Badge } from "@/components/ui/badge";
@@ -20,6 +20,8 @@ const ReadFileMetadata = (path: string): Promise<backend.AudioMetadata> => (wind
 const ReadTextFile = (path: string): Promise<string> => (window as any)['go']['main']['App']['ReadTextFile'](path);
 const RenameFileTo = (oldPath: string, newName: string): Promise<void> => (window as any)['go']['main']['App']['RenameFileTo'](oldPath, newName);
 const ReadImageAsBase64 = (path: string): Promise<string> => (window as any)['go']['main']['App']['ReadImageAsBase64'](path);
+const ApplyReplayGain = (paths: string[], albumMode: boolean): Promise<backend.ReplayGainResult[]> => (window as any)['go']['main']['App']['ApplyReplayGain'](paths, albumMode);
+const RetagFiles = (paths: string[], options: backend.RetagOptions): Promise<backend.RetagResult[]> => (window as any)['go']['main']['App']['RetagFiles'](paths, options);
 interface FileNode {
     name: string;
     path: string;
@@ -38,6 +40,14 @@ interface FileMetadata {
     year: string;
     upc?: string;
     isrc?: string;
+    spotify_id?: string;
+    provenance?: {
+        spotify_album_id?: string;
+        musicbrainz_recording_id?: string;
+        musicbrainz_release_id?: string;
+        download_date?: string;
+        source_quality?: string;
+    };
 }
 type TabType = "track" | "lyric" | "cover";
 const FORMAT_PRESETS: Record<string, {
@@ -126,6 +136,11 @@ export function FileManagerPage() {
     const [manualRenameFile, setManualRenameFile] = useState("");
     const [manualRenameName, setManualRenameName] = useState("");
     const [manualRenaming, setManualRenaming] = useState(false);
+    const [applyingReplayGain, setApplyingReplayGain] = useState(false);
+    const [showRetagPreview, setShowRetagPreview] = useState(false);
+    const [retagPreview, setRetagPreview] = useState<backend.RetagResult[]>([]);
+    const [loadingRetag, setLoadingRetag] = useState(false);
+    const [retagging, setRetagging] = useState(false);
     useEffect(() => {
         try {
             localStorage.setItem(STORAGE_KEY, JSON.stringify({ formatPreset, customFormat }));
@@ -326,7 +341,7 @@ export function FileManagerPage() {
         }
     };
     const getPlainLyrics = (content: string) => {
-        return content.split('\n').map(line => line.replace(/^\[[\d:.]+\]\s*/, '')).filter(line => !line.startsWith('[') || line.includes(']')).map(line => line.startsWith('[') ? '' : line).join('\n').trim();
+        return content.split('\n').map(line => line.replace(/^\[[\d:.]+\]\s*/, '').replace(/<[\d:.]+>/g, '')).filter(line => !line.startsWith('[') || line.includes(']')).map(line => line.startsWith('[') ? '' : line).join('\n').trim();
     };
     const formatTimestamp = (timestamp: string): string => {
         const match = timestamp.match(/\[(\d+):(\d+)(?:\.(\d+))?\]/);
@@ -346,7 +361,7 @@ export function FileManagerPage() {
             const match = line.match(/^(\[[\d:.]+\])(.*)$/);
             if (match) {
                 const timestamp = match[1];
-                const text = match[2].trim();
+                const text = match[2].replace(/<[\d:.]+>/g, '').trim();
                 if (!text)
                     return null;
                 return (<div key={index} className="flex items-center gap-2 py-1">
@@ -422,6 +437,74 @@ export function FileManagerPage() {
             setRenaming(false);
         }
     };
+    const handleApplyReplayGain = async () => {
+        if (selectedFiles.size === 0)
+            return;
+        setApplyingReplayGain(true);
+        try {
+            const result = await ApplyReplayGain(Array.from(selectedFiles), true);
+            const successCount = result.filter((r: backend.ReplayGainResult) => r.success).length;
+            const failCount = result.length - successCount;
+            if (successCount > 0)
+                toast.success("ReplayGain Applied", { description: `${successCount} file(s) tagged${failCount > 0 ? `, ${failCount} failed` : ""}` });
+            else
+                toast.error("ReplayGain Failed", { description: result[0]?.error || `All ${failCount} file(s) failed` });
+        }
+        catch (err) {
+            toast.error("ReplayGain Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setApplyingReplayGain(false);
+        }
+    };
+    const retagOptions = (preview: boolean): backend.RetagOptions => {
+        const settings = getSettings();
+        return { preview, embed_genre: settings.embedGenre, use_single_genre: settings.useSingleGenre } as backend.RetagOptions;
+    };
+    const handleRetagPreview = async () => {
+        if (selectedFiles.size === 0)
+            return;
+        setLoadingRetag(true);
+        try {
+            const result = await RetagFiles(Array.from(selectedFiles), retagOptions(true));
+            if (!result.some((r: backend.RetagResult) => r.status === "changed")) {
+                const failed = result.find((r: backend.RetagResult) => r.error);
+                if (failed)
+                    toast.error("Retag Failed", { description: failed.error });
+                else
+                    toast.info("Tags are already up to date");
+                return;
+            }
+            setRetagPreview(result);
+            setShowRetagPreview(true);
+        }
+        catch (err) {
+            toast.error("Retag Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setLoadingRetag(false);
+        }
+    };
+    const handleRetag = async () => {
+        const paths = retagPreview.filter((r) => r.status === "changed").map((r) => r.path);
+        setRetagging(true);
+        try {
+            const result = await RetagFiles(paths, retagOptions(false));
+            const successCount = result.filter((r: backend.RetagResult) => r.status === "updated").length;
+            const failCount = result.filter((r: backend.RetagResult) => r.status === "failed").length;
+            if (successCount > 0)
+                toast.success("Retag Complete", { description: `${successCount} file(s) updated${failCount > 0 ? `, ${failCount} failed` : ""}` });
+            else
+                toast.error("Retag Failed", { description: result.find((r: backend.RetagResult) => r.error)?.error || "No files were updated" });
+            setShowRetagPreview(false);
+        }
+        catch (err) {
+            toast.error("Retag Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setRetagging(false);
+        }
+    };
     const renderTrackTree = (nodes: FileNode[], depth = 0) => {
         return nodes.map((node) => (<div key={node.path}>
       <div className={`flex items-center gap-2 py-1.5 px-2 rounded hover:bg-muted/50 cursor-pointer ${selectedFiles.has(node.path) ? "bg-primary/10" : ""}`} style={{ paddingLeft: `${depth * 16 + 8}px` }} onClick={() => (node.is_dir ? toggleExpand(node.path) : toggleSelect(node.path))}>
@@ -551,7 +634,7 @@ export function FileManagerPage() {
             <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
           </TooltipTrigger>
           <TooltipContent side="right">
-            <p className="text-xs whitespace-nowrap">Variables: {"{title}"}, {"{artist}"}, {"{album}"}, {"{album_artist}"}, {"{track}"}, {"{disc}"}, {"{year}"}, {"{date}"}, {"{isrc}"}</p>
+            <p className="text-xs whitespace-nowrap">Variables: {"{title}"}, {"{artist}"}, {"{album}"}, {"{album_artist}"}, {"{track}"}, {"{disc}"}, {"{year}"}, {"{date}"}, {"{isrc}"}, {"{upc}"}, {"{spotify_id}"}</p>
           </TooltipContent>
         </Tooltip>
       </div>
@@ -587,6 +670,14 @@ export function FileManagerPage() {
           <span className="text-sm text-muted-foreground">{selectedFiles.size} of {allAudioFiles.length} file(s) selected</span>
         </div>
         <div className="flex items-center gap-2">
+          <Button variant="outline" size="sm" onClick={handleApplyReplayGain} disabled={selectedFiles.size === 0 || loading || applyingReplayGain}>
+            {applyingReplayGain ? <Spinner className="h-4 w-4"/> : <Volume2 className="h-4 w-4"/>}
+            ReplayGain
+          </Button>
+          <Button variant="outline" size="sm" onClick={handleRetagPreview} disabled={selectedFiles.size === 0 || loading || loadingRetag}>
+            {loadingRetag ? <Spinner className="h-4 w-4"/> : <Tags className="h-4 w-4"/>}
+            Retag
+          </Button>
           <Button variant="outline" size="sm" onClick={() => handlePreview(true)} disabled={selectedFiles.size === 0 || loading}>
             <Eye className="h-4 w-4"/>
             Preview
@@ -648,6 +739,37 @@ export function FileManagerPage() {
     </Dialog>
 
 
+    <Dialog open={showRetagPreview} onOpenChange={setShowRetagPreview}>
+      <DialogContent className="max-w-3xl max-h-[80vh] overflow-hidden flex flex-col [&>button]:hidden">
+        <DialogHeader>
+          <DialogTitle>Retag Preview</DialogTitle>
+          <DialogDescription>Tags will be rewritten from current Spotify metadata. Audio is not re-downloaded.</DialogDescription>
+        </DialogHeader>
+        <div className="flex-1 overflow-y-auto space-y-2 py-4">
+          {retagPreview.filter((item) => item.status !== "unchanged").map((item) => (<div key={item.path} className={`p-3 rounded-lg border ${item.error ? "border-destructive/50 bg-destructive/5" : "border-border"}`}>
+            <div className="text-sm text-muted-foreground break-all">{item.path.split(/[/\\]/).pop()}</div>
+            {item.error ? <div className="text-destructive text-xs mt-1">{item.error}</div> : (<div className="mt-2 space-y-1">
+              {(item.changes || []).map((change) => (<div key={change.field} className="grid grid-cols-[180px_1fr] gap-2 text-xs">
+                <span className="font-mono text-muted-foreground truncate">{change.field}</span>
+                <span className="break-all">
+                  {change.old ? <span className="text-destructive line-through">{change.old}</span> : null}
+                  {change.old && change.new ? " → " : null}
+                  {change.new ? <span className="text-primary">{change.new}</span> : null}
+                </span>
+              </div>))}
+            </div>)}
+          </div>))}
+        </div>
+        <DialogFooter>
+          <Button variant="outline" onClick={() => setShowRetagPreview(false)} disabled={retagging}>Cancel</Button>
+          <Button onClick={handleRetag} disabled={retagging}>
+            {retagging ? <><Spinner className="h-4 w-4"/>Retagging...</> : <>Retag {retagPreview.filter((r) => r.status === "changed").length} File(s)</>}
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>
+
+
     <Dialog open={showMetadata} onOpenChange={setShowMetadata}>
       <DialogContent className="max-w-md [&>button]:hidden">
         <DialogHeader>
@@ -664,6 +786,10 @@ export function FileManagerPage() {
           <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">Year</span><span>{metadataInfo.year ? metadataInfo.year.substring(0, 4) : "-"}</span></div>
           <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">UPC</span><span>{metadataInfo.upc || "-"}</span></div>
           <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">ISRC</span><span>{metadataInfo.isrc || "-"}</span></div>
+          <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">Spotify ID</span><span className="break-all">{metadataInfo.spotify_id || "-"}</span></div>
+          <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">MB Recording</span><span className="break-all">{metadataInfo.provenance?.musicbrainz_recording_id || "-"}</span></div>
+          <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">Source</span><span>{metadataInfo.provenance?.source_quality || "-"}</span></div>
+          <div className="grid grid-cols-[100px_1fr] gap-2 text-sm"><span className="text-muted-foreground">Downloaded</span><span>{metadataInfo.provenance?.download_date ? new Date(metadataInfo.provenance.download_date).toLocaleString() : "-"}</span></div>
         </div>) : (<div className="text-center py-4 text-muted-foreground">No metadata available</div>)}
         <DialogFooter><Button onClick={() => setShowMetadata(false)}>Close</Button></DialogFooter>
       </DialogContent>
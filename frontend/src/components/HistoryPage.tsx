@@ -40,6 +40,13 @@ interface DownloadHistoryItem {
     format: string;
     path: string;
     timestamp: number;
+    quality_verdict?: string;
+    quality_confidence?: number;
+    cutoff_hz?: number;
+    pcm_md5?: string;
+    streaminfo_md5?: string;
+    file_size?: number;
+    duration_seconds?: number;
 }
 interface FetchHistoryItem {
     id: string;
@@ -310,6 +317,7 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                                             <div className="flex flex-col items-start gap-1">
                                                 <span className="text-xs font-bold text-foreground">{normalizeHistoryFormat(item.format || "")}</span>
                                                 {(item.quality || normalizeHistoryFormat(item.format || "") === 'FLAC') && <span className="text-[11px] text-muted-foreground leading-none whitespace-nowrap">{getHistoryQualityLabel(item.format || "", item.quality || "")}</span>}
+                                                {(item.quality_verdict === "likely_transcode" || item.quality_verdict === "likely_upsampled") && <span className="text-[11px] text-destructive leading-none whitespace-nowrap" title={`Cutoff ${Math.round((item.cutoff_hz || 0) / 100) / 10} kHz`}>{item.quality_verdict === "likely_transcode" ? "Possible lossy source" : "Possibly upsampled"} ({Math.round((item.quality_confidence || 0) * 100)}%)</span>}
                                             </div>
                                         </td>
                                         <td className="p-3 align-middle text-sm text-muted-foreground text-left hidden xl:table-cell font-mono">
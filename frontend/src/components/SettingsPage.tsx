@@ -5,12 +5,12 @@ import { InputWithContext } from "@/components/ui/input-with-context";
 import { Label } from "@/components/ui/label";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
 import { Tooltip, TooltipContent, TooltipTrigger } from "@/components/ui/tooltip";
-import { FolderOpen, Save, RotateCcw, Info, MonitorCog, FolderCog, FolderLock, Router } from "lucide-react";
+import { FolderOpen, Save, RotateCcw, Info, MonitorCog, FolderCog, FolderLock, Router, ChevronUp, ChevronDown } from "lucide-react";
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
 import { Switch } from "@/components/ui/switch";
-import { getSettings, getSettingsWithDefaults, saveSettings, resetToDefaultSettings, applyThemeMode, applyFont, FONT_OPTIONS, FOLDER_PRESETS, FILENAME_PRESETS, TEMPLATE_VARIABLES, type Settings as SettingsType, type FontFamily, type FolderPreset, type FilenamePreset } from "@/lib/settings";
+import { getSettings, getSettingsWithDefaults, saveSettings, resetToDefaultSettings, applyThemeMode, applyFont, FONT_OPTIONS, LYRICS_PROVIDERS, FOLDER_PRESETS, FILENAME_PRESETS, TEMPLATE_VARIABLES, FILENAME_TEMPLATE_VARIABLES, type Settings as SettingsType, type FontFamily, type FolderPreset, type FilenamePreset } from "@/lib/settings";
 import { themes, applyTheme } from "@/lib/themes";
-import { SelectFolder, OpenConfigFolder } from "../../wailsjs/go/main/App";
+import { SelectFolder, OpenConfigFolder, PreviewFilenameTemplate } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { ApiStatusTab } from "./ApiStatusTab";
 import { FlacIcon, Mp3Icon } from "./FormatIcons";
@@ -23,6 +23,8 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
     const [tempSettings, setTempSettings] = useState<SettingsType>(savedSettings);
     const [isDark, setIsDark] = useState(document.documentElement.classList.contains('dark'));
     const [showResetConfirm, setShowResetConfirm] = useState(false);
+    const [filenamePreview, setFilenamePreview] = useState("");
+    const [filenameTemplateError, setFilenameTemplateError] = useState("");
     const hasUnsavedChanges = JSON.stringify(savedSettings) !== JSON.stringify(tempSettings);
     const resetToSaved = useCallback(() => {
         const freshSavedSettings = getSettings();
@@ -71,7 +73,30 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
         };
         loadDefaults();
     }, []);
+    useEffect(() => {
+        let cancelled = false;
+        PreviewFilenameTemplate(tempSettings.filenameTemplate || "", tempSettings.separator === "comma" ? ", " : "; ")
+            .then((preview) => {
+            if (!cancelled) {
+                setFilenamePreview(preview);
+                setFilenameTemplateError("");
+            }
+        })
+            .catch((error) => {
+            if (!cancelled) {
+                setFilenamePreview("");
+                setFilenameTemplateError(String(error));
+            }
+        });
+        return () => {
+            cancelled = true;
+        };
+    }, [tempSettings.filenameTemplate, tempSettings.separator]);
     const handleSave = async () => {
+        if (filenameTemplateError) {
+            toast.error(`Invalid filename format: ${filenameTemplateError}`);
+            return;
+        }
         await saveSettings(tempSettings);
         setSavedSettings(tempSettings);
         toast.success("Settings saved");
@@ -99,6 +124,32 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
             toast.error(`Error selecting folder: ${error}`);
         }
     };
+    const enabledLyricsProviders = tempSettings.lyricsProviders ?? LYRICS_PROVIDERS.map((provider) => provider.value);
+    const lyricsProviderRows = [
+        ...enabledLyricsProviders.filter((value) => LYRICS_PROVIDERS.some((provider) => provider.value === value)),
+        ...LYRICS_PROVIDERS.map((provider) => provider.value).filter((value) => !enabledLyricsProviders.includes(value)),
+    ];
+    const toggleLyricsProvider = (value: string, enabled: boolean) => {
+        setTempSettings((prev) => {
+            const current = prev.lyricsProviders ?? LYRICS_PROVIDERS.map((provider) => provider.value);
+            return {
+                ...prev,
+                lyricsProviders: enabled ? [...current.filter((name) => name !== value), value] : current.filter((name) => name !== value),
+            };
+        });
+    };
+    const moveLyricsProvider = (value: string, direction: -1 | 1) => {
+        setTempSettings((prev) => {
+            const current = [...(prev.lyricsProviders ?? LYRICS_PROVIDERS.map((provider) => provider.value))];
+            const index = current.indexOf(value);
+            const target = index + direction;
+            if (index < 0 || target < 0 || target >= current.length) {
+                return prev;
+            }
+            [current[index], current[target]] = [current[target], current[index]];
+            return { ...prev, lyricsProviders: current };
+        });
+    };
     const [activeTab, setActiveTab] = useState<"general" | "files" | "api">("general");
     return (<div className="space-y-4 h-full flex flex-col">
       <div className="flex items-center justify-between shrink-0">
@@ -236,6 +287,63 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                       </Select>
                     </div>
 
+                    <div className="space-y-2">
+                      <Label htmlFor="download-workers" className="text-sm">Parallel Downloads</Label>
+                      <InputWithContext id="download-workers" type="number" min={1} max={10} value={tempSettings.downloadWorkers ?? 3} onChange={(e) => setTempSettings(prev => ({ ...prev, downloadWorkers: Math.min(10, Math.max(1, Number(e.target.value) || 1)) }))} className="h-9 w-40"/>
+                    </div>
+
+                    <div className="space-y-2">
+                      <div className="flex items-center gap-2">
+                        <Label className="text-sm">Retries</Label>
+                        <Tooltip>
+                          <TooltipTrigger asChild>
+                            <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
+                          </TooltipTrigger>
+                          <TooltipContent side="top" className="max-w-xs">
+                            <p className="text-xs">Failed downloads are retried with exponential backoff, starting at the base delay and never waiting longer than the max delay.</p>
+                          </TooltipContent>
+                        </Tooltip>
+                      </div>
+                      <div className="flex gap-2">
+                        <div className="space-y-1">
+                          <Label htmlFor="retry-max-attempts" className="text-xs font-normal text-muted-foreground">Attempts</Label>
+                          <InputWithContext id="retry-max-attempts" type="number" min={1} max={10} value={tempSettings.retryMaxAttempts ?? 3} onChange={(e) => setTempSettings(prev => ({ ...prev, retryMaxAttempts: Math.min(10, Math.max(1, Number(e.target.value) || 1)) }))} className="h-9 w-24"/>
+                        </div>
+                        <div className="space-y-1">
+                          <Label htmlFor="retry-base-delay" className="text-xs font-normal text-muted-foreground">Base Delay (s)</Label>
+                          <InputWithContext id="retry-base-delay" type="number" min={1} value={tempSettings.retryBaseDelaySeconds ?? 2} onChange={(e) => setTempSettings(prev => ({ ...prev, retryBaseDelaySeconds: Math.max(1, Number(e.target.value) || 1) }))} className="h-9 w-24"/>
+                        </div>
+                        <div className="space-y-1">
+                          <Label htmlFor="retry-max-delay" className="text-xs font-normal text-muted-foreground">Max Delay (s)</Label>
+                          <InputWithContext id="retry-max-delay" type="number" min={1} value={tempSettings.retryMaxDelaySeconds ?? 60} onChange={(e) => setTempSettings(prev => ({ ...prev, retryMaxDelaySeconds: Math.max(1, Number(e.target.value) || 1) }))} className="h-9 w-24"/>
+                        </div>
+                      </div>
+                    </div>
+
+                    <div className="space-y-2">
+                      <div className="flex items-center gap-2">
+                        <Label className="text-sm">Bandwidth</Label>
+                        <Tooltip>
+                          <TooltipTrigger asChild>
+                            <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
+                          </TooltipTrigger>
+                          <TooltipContent side="top" className="max-w-xs">
+                            <p className="text-xs">Limit is in KB/s, 0 means unlimited. Download windows are comma-separated HH:MM-HH:MM ranges, optionally with their own limit, e.g. 01:00-07:00@unlimited, 19:00-23:00@500. The queue waits outside the windows.</p>
+                          </TooltipContent>
+                        </Tooltip>
+                      </div>
+                      <div className="flex gap-2">
+                        <div className="space-y-1">
+                          <Label htmlFor="bandwidth-limit" className="text-xs font-normal text-muted-foreground">Limit (KB/s)</Label>
+                          <InputWithContext id="bandwidth-limit" type="number" min={0} value={tempSettings.bandwidthLimit ?? 0} onChange={(e) => setTempSettings(prev => ({ ...prev, bandwidthLimit: Math.max(0, Number(e.target.value) || 0) }))} className="h-9 w-24"/>
+                        </div>
+                        <div className="space-y-1 flex-1">
+                          <Label htmlFor="download-windows" className="text-xs font-normal text-muted-foreground">Download Windows</Label>
+                          <InputWithContext id="download-windows" value={tempSettings.downloadWindows ?? ""} onChange={(e) => setTempSettings(prev => ({ ...prev, downloadWindows: e.target.value }))} placeholder="01:00-07:00@unlimited" className="h-9"/>
+                        </div>
+                      </div>
+                    </div>
+
                     <div className="border-t pt-4"/>
 
                    <div className="space-y-4">
@@ -243,10 +351,42 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                         <Switch id="embed-lyrics" checked={tempSettings.embedLyrics} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, embedLyrics: checked }))}/>
                         <Label htmlFor="embed-lyrics" className="cursor-pointer text-sm font-normal">Embed Lyrics</Label>
                       </div>
+                      <div className="space-y-2">
+                        <div className="flex items-center gap-2">
+                          <Label className="text-sm">Lyrics Providers</Label>
+                          <Tooltip>
+                            <TooltipTrigger asChild>
+                              <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
+                            </TooltipTrigger>
+                            <TooltipContent side="top" className="max-w-xs">
+                              <p className="text-xs">Enabled providers are tried from top to bottom until synced lyrics are found.</p>
+                            </TooltipContent>
+                          </Tooltip>
+                        </div>
+                        {lyricsProviderRows.map((value) => {
+                const provider = LYRICS_PROVIDERS.find((p) => p.value === value);
+                const enabled = enabledLyricsProviders.includes(value);
+                const index = enabledLyricsProviders.indexOf(value);
+                return (<div key={value} className="flex items-center gap-3">
+                            <Switch id={`lyrics-provider-${value}`} checked={enabled} onCheckedChange={(checked) => toggleLyricsProvider(value, checked)}/>
+                            <Label htmlFor={`lyrics-provider-${value}`} className="cursor-pointer text-sm font-normal flex-1">{provider?.label ?? value}</Label>
+                            <Button type="button" variant="ghost" size="icon" className="h-7 w-7" disabled={!enabled || index === 0} onClick={() => moveLyricsProvider(value, -1)}>
+                              <ChevronUp className="h-4 w-4"/>
+                            </Button>
+                            <Button type="button" variant="ghost" size="icon" className="h-7 w-7" disabled={!enabled || index === enabledLyricsProviders.length - 1} onClick={() => moveLyricsProvider(value, 1)}>
+                              <ChevronDown className="h-4 w-4"/>
+                            </Button>
+                          </div>);
+            })}
+                      </div>
                       <div className="flex items-center gap-3">
                         <Switch id="embed-max-quality-cover" checked={tempSettings.embedMaxQualityCover} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, embedMaxQualityCover: checked }))}/>
                         <Label htmlFor="embed-max-quality-cover" className="cursor-pointer text-sm font-normal">Embed Max Quality Cover</Label>
                       </div>
+                      <div className="flex items-center gap-3">
+                        <Switch id="replay-gain-on-download" checked={tempSettings.replayGainOnDownload ?? false} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, replayGainOnDownload: checked }))}/>
+                        <Label htmlFor="replay-gain-on-download" className="cursor-pointer text-sm font-normal">Write ReplayGain Tags</Label>
+                      </div>
                       <div className="flex items-center gap-3">
                         <Switch id="embed-genre" checked={tempSettings.embedGenre} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, embedGenre: checked }))}/>
                         <Label htmlFor="embed-genre" className="cursor-pointer text-sm font-normal">Embed Genre</Label>
@@ -255,6 +395,10 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                           <Switch id="use-single-genre" checked={tempSettings.useSingleGenre} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, useSingleGenre: checked }))}/>
                           <Label htmlFor="use-single-genre" className="cursor-pointer text-sm font-normal">Use Single Genre</Label>
                         </div>)}
+                      <div className="flex items-center gap-3">
+                        <Switch id="musicbrainz-full-tagging" checked={tempSettings.musicBrainzFullTagging ?? false} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, musicBrainzFullTagging: checked }))}/>
+                        <Label htmlFor="musicbrainz-full-tagging" className="cursor-pointer text-sm font-normal">Full MusicBrainz Tags</Label>
+                      </div>
                    </div>
               </div>
           </div>)}
@@ -321,6 +465,11 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                        <Label htmlFor="redownload-with-suffix" className="text-sm cursor-pointer font-normal">Redownload With Suffix</Label>
                     </div>
 
+                    <div className="flex items-center gap-3">
+                      <Switch id="skip-existing-isrc" checked={tempSettings.skipExistingISRC ?? false} onCheckedChange={(checked) => setTempSettings(prev => ({ ...prev, skipExistingISRC: checked }))}/>
+                       <Label htmlFor="skip-existing-isrc" className="text-sm cursor-pointer font-normal">Skip Tracks Already in Library (ISRC)</Label>
+                    </div>
+
 
               </div>
 
@@ -332,7 +481,8 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                         <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
                       </TooltipTrigger>
                       <TooltipContent side="top">
-                        <p className="text-xs whitespace-nowrap">Variables: {TEMPLATE_VARIABLES.map(v => v.key).join(", ")}</p>
+                        <p className="text-xs whitespace-nowrap">Variables: {FILENAME_TEMPLATE_VARIABLES.map(v => v.key).join(", ")}</p>
+                        <p className="text-xs whitespace-nowrap">Optional: [{"{disc}"}-], fallback: {"{album_artist|artist}"}, modifiers: {"{track:03}"}, {"{title:upper}"}, {"{album:.30}"}</p>
                       </TooltipContent>
                     </Tooltip>
                   </div>
@@ -370,8 +520,8 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest }: Setting
                     </Select>
                   </div>
 
-                  {tempSettings.filenameTemplate && (<p className="text-xs text-muted-foreground pt-1">
-                    Preview: <span className="font-mono">{tempSettings.filenameTemplate.replace(/\{artist\}/g, tempSettings.separator === "comma" ? "Kendrick Lamar, SZA" : "Kendrick Lamar; SZA").replace(/\{album\}/g, "Black Panther").replace(/\{album_artist\}/g, "Kendrick Lamar").replace(/\{title\}/g, "All The Stars").replace(/\{track\}/g, "01").replace(/\{disc\}/g, "1").replace(/\{year\}/g, "2018").replace(/\{date\}/g, "2018-02-09").replace(/\{isrc\}/g, "USUM71801234")}.{tempSettings.audioFormat}</span>
+                  {filenameTemplateError ? (<p className="text-xs text-destructive pt-1">{filenameTemplateError}</p>) : tempSettings.filenameTemplate && (<p className="text-xs text-muted-foreground pt-1">
+                    Preview: <span className="font-mono">{filenamePreview}.{tempSettings.audioFormat}</span>
                   </p>)}
               </div>
           </div>)}
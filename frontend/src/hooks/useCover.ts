@@ -1,8 +1,9 @@
 import { useState, useRef } from "react";
 import { toast } from "sonner";
 import { DownloadCover } from "../../wailsjs/go/main/App";
-import { getSettingsWithDefaults, parseTemplate, type TemplateData } from "@/lib/settings";
-import { joinPath, sanitizePath, getFirstArtist } from "@/lib/utils";
+import { getSettingsWithDefaults, templateUsesToken } from "@/lib/settings";
+import { resolveTrackFolderPath } from "@/lib/api";
+import { getFirstArtist } from "@/lib/utils";
 import type { TrackMetadata } from "@/types/api";
 import { logger } from "@/lib/logger";
 export const useCover = () => {
@@ -23,37 +24,19 @@ export const useCover = () => {
         const settings = await getSettingsWithDefaults();
         setDownloadingCoverTrack(id);
         try {
-            const os = settings.operatingSystem;
-            let outputDir = settings.downloadPath;
-            const placeholder = "__SLASH_PLACEHOLDER__";
-            const yearValue = releaseDate?.substring(0, 4);
             const displayArtist = settings.useFirstArtistOnly && artistName ? getFirstArtist(artistName) : artistName;
             const displayAlbumArtist = settings.useFirstArtistOnly && albumArtist ? getFirstArtist(albumArtist) : albumArtist;
-            const templateData: TemplateData = {
-                artist: displayArtist?.replace(/\//g, placeholder),
-                album: albumName?.replace(/\//g, placeholder),
-                album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-                title: trackName?.replace(/\//g, placeholder),
-                track: position,
-                year: yearValue,
-                date: releaseDate,
-                playlist: playlistName?.replace(/\//g, placeholder),
-            };
-            const folderTemplate = settings.folderTemplate || "";
-            const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
-            if (settings.createPlaylistFolder && playlistName && (!isAlbum || !useAlbumSubfolder)) {
-                outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-            }
-            if (settings.folderTemplate) {
-                const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                if (folderPath) {
-                    const parts = folderPath.split("/").filter((p: string) => p.trim());
-                    for (const part of parts) {
-                        const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                        outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                    }
-                }
-            }
+            const folderPath = await resolveTrackFolderPath(settings, {
+                spotify_id: trackId,
+                track_name: trackName,
+                artist_name: artistName,
+                album_name: albumName,
+                album_artist: albumArtist,
+                release_date: releaseDate,
+                position,
+                disc_number: discNumber,
+            }, playlistName, isAlbum);
+            const outputDir = folderPath.output_dir;
             const response = await DownloadCover({
                 cover_url: coverUrl,
                 track_name: trackName || "",
@@ -120,39 +103,23 @@ export const useCover = () => {
             setDownloadingCoverTrack(id);
             setCoverDownloadProgress(Math.round((completed / total) * 100));
             try {
-                const os = settings.operatingSystem;
-                let outputDir = settings.downloadPath;
-                const placeholder = "__SLASH_PLACEHOLDER__";
-                const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
+                const useAlbumTrackNumber = templateUsesToken(settings.folderTemplate, "album");
                 const trackPosition = useAlbumTrackNumber ? (track.track_number || i + 1) : (i + 1);
-                const yearValue = track.release_date?.substring(0, 4);
                 const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
                 const displayAlbumArtist = settings.useFirstArtistOnly && track.album_artist ? getFirstArtist(track.album_artist) : track.album_artist;
-                const templateData: TemplateData = {
-                    artist: displayArtist?.replace(/\//g, placeholder),
-                    album: track.album_name?.replace(/\//g, placeholder),
-                    album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-                    title: track.name?.replace(/\//g, placeholder),
-                    track: trackPosition,
-                    year: yearValue,
-                    date: track.release_date,
-                    playlist: playlistName?.replace(/\//g, placeholder),
-                };
-                const folderTemplate = settings.folderTemplate || "";
-                const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
-                if (settings.createPlaylistFolder && playlistName && (!isAlbum || !useAlbumSubfolder)) {
-                    outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-                }
-                if (settings.folderTemplate) {
-                    const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                    if (folderPath) {
-                        const parts = folderPath.split("/").filter((p: string) => p.trim());
-                        for (const part of parts) {
-                            const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                            outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                        }
-                    }
-                }
+                const folderPath = await resolveTrackFolderPath(settings, {
+                    spotify_id: track.spotify_id,
+                    track_name: track.name,
+                    artist_name: track.artists,
+                    album_name: track.album_name,
+                    album_artist: track.album_artist,
+                    release_date: track.release_date,
+                    isrc: track.isrc,
+                    position: trackPosition,
+                    disc_number: track.disc_number,
+                    total_tracks: track.total_tracks,
+                }, playlistName, isAlbum);
+                const outputDir = folderPath.output_dir;
                 const response = await DownloadCover({
                     cover_url: track.images || "",
                     track_name: track.name || "",
@@ -1,10 +1,11 @@
 import { useState, useRef } from "react";
-import { downloadTrack, fetchSpotifyMetadata } from "@/lib/api";
-import { CheckFilesExistence, CreateM3U8File, SkipDownloadItem } from "../../wailsjs/go/main/App";
-import { getSettingsWithDefaults, parseTemplate, type Settings, type TemplateData } from "@/lib/settings";
+import { downloadTrack, enqueueDownloads, fetchSpotifyMetadata, resolveTrackFolderPath } from "@/lib/api";
+import { AddToDownloadQueue, CancelDownloadItem, CheckFilesExistence, CreateM3U8File, SkipDownloadItem } from "../../wailsjs/go/main/App";
+import { waitForDownloadItems } from "@/lib/download-events";
+import { getSettingsWithDefaults, templateUsesToken, type Settings } from "@/lib/settings";
 import { ensureValidToken } from "@/lib/token-manager";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
-import { joinPath, sanitizePath, getFirstArtist } from "@/lib/utils";
+import { getFirstArtist } from "@/lib/utils";
 import { logger } from "@/lib/logger";
 import type { TrackMetadata } from "@/types/api";
 interface CheckFileExistenceRequest {
@@ -23,6 +24,10 @@ interface CheckFileExistenceRequest {
     include_track_number?: boolean;
     audio_format?: string;
     relative_path?: string;
+    upc?: string;
+    total_tracks?: number;
+    duration?: number;
+    explicit?: boolean;
 }
 interface BatchTrackPathInfo {
     displayArtist: string;
@@ -44,15 +49,11 @@ function normalizeReleaseDate(releaseDate?: string): string {
     const dateWithoutTime = trimmedReleaseDate.split("T")[0] || trimmedReleaseDate;
     return dateWithoutTime.split(" ")[0] || dateWithoutTime;
 }
-function getReleaseYear(releaseDate?: string): string {
-    const normalizedReleaseDate = normalizeReleaseDate(releaseDate);
-    return normalizedReleaseDate.length >= 4 ? normalizedReleaseDate.slice(0, 4) : "";
-}
 const GetTrackISRC = (spotifyId: string): Promise<string> => (window as any)["go"]["main"]["App"]["GetTrackISRC"](spotifyId);
 function templateNeedsISRC(settings: Settings): boolean {
     const folderTemplate = settings.folderTemplate || "";
     const filenameTemplate = settings.filenameTemplate || "";
-    return folderTemplate.includes("{isrc}") || filenameTemplate.includes("{isrc}");
+    return templateUsesToken(folderTemplate, "isrc") || templateUsesToken(filenameTemplate, "isrc");
 }
 async function enrichTrackISRC(track: TrackMetadata, settings: Settings): Promise<TrackMetadata> {
     if (!templateNeedsISRC(settings) || track.isrc || !track.spotify_id) {
@@ -76,7 +77,7 @@ async function enrichTracksISRC(tracks: TrackMetadata[], settings: Settings): Pr
 }
 function folderTemplateNeedsReleaseDate(settings: Settings): boolean {
     const folderTemplate = settings.folderTemplate || "";
-    return folderTemplate.includes("{year}") || folderTemplate.includes("{date}");
+    return templateUsesToken(folderTemplate, "year") || templateUsesToken(folderTemplate, "date");
 }
 async function enrichTrackReleaseDate(track: TrackMetadata, settings: Settings): Promise<TrackMetadata> {
     const normalizedReleaseDate = normalizeReleaseDate(track.release_date);
@@ -116,9 +117,7 @@ async function enrichTracksReleaseDates(tracks: TrackMetadata[], settings: Setti
     }
     return await Promise.all(tracks.map((track) => enrichTrackReleaseDate(track, settings)));
 }
-function buildBatchTrackPathInfo(track: TrackMetadata, settings: Settings, playlistName: string | undefined, isAlbum: boolean | undefined, fallbackPosition: number): BatchTrackPathInfo {
-    const os = settings.operatingSystem;
-    const placeholder = "__SLASH_PLACEHOLDER__";
+async function buildBatchTrackPathInfo(track: TrackMetadata, settings: Settings, playlistName: string | undefined, isAlbum: boolean | undefined, fallbackPosition: number): Promise<BatchTrackPathInfo> {
     const folderTemplate = settings.folderTemplate || "";
     const finalTrackNumber = track.track_number || 0;
     const hasSubfolder = folderTemplate.trim() !== "";
@@ -127,49 +126,27 @@ function buildBatchTrackPathInfo(track: TrackMetadata, settings: Settings, playl
     const displayAlbumArtist = settings.useFirstArtistOnly && track.album_artist
         ? getFirstArtist(track.album_artist)
         : (track.album_artist || track.artists || "");
-    const templateData: TemplateData = {
-        artist: displayArtist.replace(/\//g, placeholder),
-        album: (track.album_name || "").replace(/\//g, placeholder),
-        album_artist: displayAlbumArtist.replace(/\//g, placeholder),
-        title: (track.name || "").replace(/\//g, placeholder),
-        isrc: track.isrc?.replace(/\//g, placeholder),
-        track: trackPosition,
-        disc: track.disc_number,
-        year: getReleaseYear(track.release_date),
-        date: normalizeReleaseDate(track.release_date),
-        playlist: playlistName?.replace(/\//g, placeholder),
-    };
-    const useAlbumSubfolder = folderTemplate.includes("{album}") ||
-        folderTemplate.includes("{album_artist}") ||
-        folderTemplate.includes("{playlist}");
-    let baseOutputDir = settings.downloadPath;
-    if (settings.createPlaylistFolder &&
-        playlistName &&
-        (!isAlbum || !useAlbumSubfolder)) {
-        baseOutputDir = joinPath(os, baseOutputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-    }
-    let targetOutputDir = baseOutputDir;
-    let relativePath = "";
-    if (folderTemplate) {
-        const folderPath = parseTemplate(folderTemplate, templateData);
-        if (folderPath) {
-            const parts = folderPath.split("/").filter((p: string) => p.trim());
-            const sanitizedParts = parts.map((part: string) => {
-                const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                return sanitizePath(sanitizedPart, os);
-            });
-            relativePath = sanitizedParts.join(os === "Windows" ? "\\" : "/");
-            for (const part of sanitizedParts) {
-                targetOutputDir = joinPath(os, targetOutputDir, part);
-            }
-        }
-    }
+    const folderPath = await resolveTrackFolderPath(settings, {
+        spotify_id: track.spotify_id,
+        track_name: track.name,
+        artist_name: track.artists,
+        album_name: track.album_name,
+        album_artist: track.album_artist,
+        release_date: normalizeReleaseDate(track.release_date),
+        isrc: track.isrc,
+        upc: track.upc,
+        explicit: track.is_explicit || false,
+        duration: Math.floor((track.duration_ms || 0) / 1000),
+        disc_number: track.disc_number,
+        total_tracks: track.total_tracks,
+        position: trackPosition,
+    }, playlistName, isAlbum);
     return {
         displayArtist,
         displayAlbumArtist,
-        baseOutputDir,
-        targetOutputDir,
-        relativePath,
+        baseOutputDir: folderPath.base_dir,
+        targetOutputDir: folderPath.output_dir,
+        relativePath: folderPath.relative_path || "",
         trackPosition,
         useAlbumTrackNumber: hasSubfolder,
     };
@@ -187,16 +164,9 @@ export function useDownload() {
         artists: string;
     } | null>(null);
     const shouldStopDownloadRef = useRef(false);
-    const isUnauthorizedDownloadError = (error?: string) => {
-        const msg = (error || "").toLowerCase();
-        return msg.includes("unauthorized") || msg.includes("403") || msg.includes("401") || msg.includes("err_unauthorized");
-    };
     const downloadWithSpotiDownloader = async (track: TrackMetadata, settings: Settings, playlistName?: string, position?: number, retryCount: number = 0, isAlbum?: boolean, releaseYear?: string) => {
         const enrichedTrack = await enrichTrackISRC(track, settings);
-        const os = settings.operatingSystem;
-        let outputDir = settings.downloadPath;
         let useAlbumTrackNumber = false;
-        const placeholder = "__SLASH_PLACEHOLDER__";
         let finalReleaseDate = normalizeReleaseDate(enrichedTrack.release_date);
         let finalTrackNumber = enrichedTrack.track_number;
         if (enrichedTrack.spotify_id) {
@@ -216,7 +186,6 @@ export function useDownload() {
             }
         }
         const resolvedReleaseDate = finalReleaseDate || normalizeReleaseDate(enrichedTrack.release_date);
-        const yearValue = releaseYear || getReleaseYear(resolvedReleaseDate);
         const hasSubfolder = settings.folderTemplate && settings.folderTemplate.trim() !== "";
         const trackNumberForTemplate = hasSubfolder && finalTrackNumber > 0 ? finalTrackNumber : position || 0;
         if (hasSubfolder) {
@@ -228,39 +197,22 @@ export function useDownload() {
         const displayAlbumArtist = settings.useFirstArtistOnly && enrichedTrack.album_artist
             ? getFirstArtist(enrichedTrack.album_artist)
             : enrichedTrack.album_artist;
-        const templateData: TemplateData = {
-            artist: displayArtist?.replace(/\//g, placeholder) || undefined,
-            album: enrichedTrack.album_name?.replace(/\//g, placeholder) || undefined,
-            album_artist: displayAlbumArtist?.replace(/\//g, placeholder) ||
-                displayArtist?.replace(/\//g, placeholder) ||
-                undefined,
-            title: enrichedTrack.name?.replace(/\//g, placeholder) || undefined,
-            isrc: enrichedTrack.isrc?.replace(/\//g, placeholder) || undefined,
-            track: trackNumberForTemplate,
-            disc: enrichedTrack.disc_number,
-            year: yearValue,
-            date: resolvedReleaseDate || undefined,
-            playlist: playlistName?.replace(/\//g, placeholder) || undefined,
-        };
-        const folderTemplate = settings.folderTemplate || "";
-        const useAlbumSubfolder = folderTemplate.includes("{album}") ||
-            folderTemplate.includes("{album_artist}") ||
-            folderTemplate.includes("{playlist}");
-        if (settings.createPlaylistFolder &&
-            playlistName &&
-            (!isAlbum || !useAlbumSubfolder)) {
-            outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-        }
-        if (settings.folderTemplate) {
-            const folderPath = parseTemplate(settings.folderTemplate, templateData);
-            if (folderPath) {
-                const parts = folderPath.split("/").filter((p: string) => p.trim());
-                for (const part of parts) {
-                    const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                    outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                }
-            }
-        }
+        const folderPath = await resolveTrackFolderPath(settings, {
+            spotify_id: enrichedTrack.spotify_id,
+            track_name: enrichedTrack.name,
+            artist_name: enrichedTrack.artists,
+            album_name: enrichedTrack.album_name,
+            album_artist: enrichedTrack.album_artist,
+            release_date: resolvedReleaseDate || releaseYear || "",
+            isrc: enrichedTrack.isrc,
+            upc: enrichedTrack.upc,
+            explicit: enrichedTrack.is_explicit || false,
+            duration: Math.floor((enrichedTrack.duration_ms || 0) / 1000),
+            disc_number: enrichedTrack.disc_number,
+            total_tracks: enrichedTrack.total_tracks,
+            position: trackNumberForTemplate,
+        }, playlistName, isAlbum);
+        const outputDir = folderPath.output_dir;
         if (enrichedTrack.name && enrichedTrack.artists) {
             try {
                 const checkRequest: CheckFileExistenceRequest = {
@@ -278,6 +230,10 @@ export function useDownload() {
                     filename_format: settings.filenameTemplate || "",
                     include_track_number: settings.trackNumber || false,
                     audio_format: settings.audioFormat,
+                    upc: enrichedTrack.upc,
+                    total_tracks: enrichedTrack.total_tracks || 0,
+                    duration: Math.floor((enrichedTrack.duration_ms || 0) / 1000),
+                    explicit: enrichedTrack.is_explicit || false,
                 };
                 const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, settings.audioFormat, [checkRequest]);
                 if (existenceResults.length > 0 && existenceResults[0].exists) {
@@ -294,7 +250,6 @@ export function useDownload() {
             }
         }
         const sessionToken = await ensureValidToken();
-        const { AddToDownloadQueue } = await import("../../wailsjs/go/main/App");
         const itemID = await AddToDownloadQueue(enrichedTrack.spotify_id || "", enrichedTrack.name || "", displayArtist || "", enrichedTrack.album_name || "");
         const response = await downloadTrack({
             track_id: enrichedTrack.spotify_id || "",
@@ -310,6 +265,9 @@ export function useDownload() {
             total_tracks: enrichedTrack.total_tracks,
             spotify_total_discs: enrichedTrack.total_discs,
             isrc: enrichedTrack.isrc,
+            upc: enrichedTrack.upc,
+            explicit: enrichedTrack.is_explicit || false,
+            duration: Math.floor((enrichedTrack.duration_ms || 0) / 1000),
             copyright: enrichedTrack.copyright,
             publisher: enrichedTrack.publisher,
             output_dir: outputDir,
@@ -385,255 +343,12 @@ export function useDownload() {
             setDownloadingTrack(null);
         }
     };
-    const handleDownloadSelected = async (selectedTracks: string[], allTracks: TrackMetadata[], playlistName?: string, isAlbum?: boolean) => {
-        if (selectedTracks.length === 0) {
-            toast.error("No tracks selected");
-            return;
-        }
-        logger.info(`starting batch download: ${selectedTracks.length} selected tracks`);
-        const settings = await getSettingsWithDefaults();
-        setIsDownloading(true);
-        setBulkDownloadType("selected");
-        setDownloadProgress(0);
-        const selectedTrackObjects = await enrichTracksISRC(await enrichTracksReleaseDates(selectedTracks
-            .map((id) => allTracks.find((t) => t.spotify_id === id))
-            .filter((t): t is TrackMetadata => t !== undefined), settings), settings);
-        const selectedTrackPathInfo = selectedTrackObjects.map((track, index) => ({
-            track,
-            pathInfo: buildBatchTrackPathInfo(track, settings, playlistName, isAlbum, index + 1),
-        }));
-        const outputDir = selectedTrackPathInfo[0]?.pathInfo.baseOutputDir || settings.downloadPath;
-        logger.info(`checking existing files in parallel...`);
-        const existenceChecks = selectedTrackPathInfo.map(({ track, pathInfo }) => {
-            return {
-                spotify_id: track.spotify_id || "",
-                track_name: track.name || "",
-                artist_name: pathInfo.displayArtist,
-                album_name: track.album_name || "",
-                album_artist: pathInfo.displayAlbumArtist,
-                release_date: normalizeReleaseDate(track.release_date),
-                isrc: track.isrc,
-                track_number: track.track_number || 0,
-                disc_number: track.disc_number || 0,
-                position: pathInfo.trackPosition,
-                use_album_track_number: pathInfo.useAlbumTrackNumber,
-                filename_format: settings.filenameTemplate || "",
-                include_track_number: settings.trackNumber || false,
-                audio_format: settings.audioFormat,
-                relative_path: pathInfo.relativePath,
-            };
-        });
-        const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, settings.audioFormat, existenceChecks);
-        const existingSpotifyIDs = new Set<string>();
-        const existingFilePathsBySpotifyID = new Map<string, string>();
-        const finalFilePaths = new Map<string, string>();
-        for (const result of existenceResults) {
-            if (result.exists) {
-                existingSpotifyIDs.add(result.spotify_id);
-                existingFilePathsBySpotifyID.set(result.spotify_id, result.file_path || "");
-                finalFilePaths.set(result.spotify_id, result.file_path || "");
-            }
-        }
-        logger.info(`found ${existingSpotifyIDs.size} existing files`);
-        const { AddToDownloadQueue } = await import("../../wailsjs/go/main/App");
-        for (const { track, pathInfo } of selectedTrackPathInfo) {
-            const trackID = track.spotify_id || "";
-            if (existingSpotifyIDs.has(trackID)) {
-                const itemID = await AddToDownloadQueue(track.spotify_id || "", track.name || "", pathInfo.displayArtist, track.album_name || "");
-                const filePath = existingFilePathsBySpotifyID.get(trackID) || "";
-                setTimeout(() => SkipDownloadItem(itemID, filePath), 10);
-                setSkippedTracks((prev: Set<string>) => new Set(prev).add(trackID));
-                setDownloadedTracks((prev: Set<string>) => new Set(prev).add(trackID));
-            }
-        }
-        const tracksToDownload = selectedTrackObjects.filter((track) => {
-            const trackID = track.spotify_id || "";
-            return !existingSpotifyIDs.has(trackID);
-        });
-        let sessionToken = settings.sessionToken || "";
-        if (tracksToDownload.length > 0) {
-            try {
-                sessionToken = await ensureValidToken();
-            }
-            catch (err) {
-                logger.error(`failed to fetch session token for batch: ${err}`);
-                toast.error(err instanceof Error ? err.message : "Failed to fetch session token");
-                setDownloadingTrack(null);
-                setCurrentDownloadInfo(null);
-                setIsDownloading(false);
-                setBulkDownloadType(null);
-                shouldStopDownloadRef.current = false;
-                return;
-            }
-        }
-        let successCount = 0;
-        let errorCount = 0;
-        let skippedCount = existingSpotifyIDs.size;
-        const total = selectedTracks.length;
-        setDownloadProgress(Math.round((skippedCount / total) * 100));
-        for (let i = 0; i < tracksToDownload.length; i++) {
-            if (shouldStopDownloadRef.current) {
-                toast.info(`Download stopped. ${successCount} tracks downloaded, ${tracksToDownload.length - i} remaining.`);
-                break;
-            }
-            const track = tracksToDownload[i];
-            const id = track.spotify_id || "";
-            const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
-            setDownloadingTrack(id);
-            setCurrentDownloadInfo({ name: track.name, artists: displayArtist || "" });
-            try {
-                const playlistIndex = selectedTracks.indexOf(id) + 1;
-                const pathInfo = buildBatchTrackPathInfo(track, settings, playlistName, isAlbum, playlistIndex);
-                let response = await downloadTrack({
-                    track_id: id,
-                    session_token: sessionToken,
-                    track_name: track.name || "",
-                    artist_name: track.artists,
-                    album_name: track.album_name,
-                    album_artist: track.album_artist || track.artists,
-                    release_date: normalizeReleaseDate(track.release_date),
-                    cover_url: track.images,
-                    album_track_number: track.track_number,
-                    disc_number: track.disc_number,
-                    total_tracks: track.total_tracks,
-                    spotify_total_discs: track.total_discs,
-                    isrc: track.isrc,
-                    copyright: track.copyright,
-                    publisher: track.publisher,
-                    output_dir: pathInfo.targetOutputDir,
-                    audio_format: settings.audioFormat,
-                    filename_format: settings.filenameTemplate,
-                    track_number: settings.trackNumber,
-                    position: pathInfo.trackPosition,
-                    use_album_track_number: pathInfo.useAlbumTrackNumber,
-                    embed_lyrics: settings.embedLyrics,
-                    embed_max_quality_cover: settings.embedMaxQualityCover,
-                    use_first_artist_only: settings.useFirstArtistOnly,
-                    use_single_genre: settings.useSingleGenre,
-                    embed_genre: settings.embedGenre,
-                });
-                if (!response.success && isUnauthorizedDownloadError(response.error)) {
-                    sessionToken = await ensureValidToken(true);
-                    response = await downloadTrack({
-                        track_id: id,
-                        session_token: sessionToken,
-                        track_name: track.name || "",
-                        artist_name: track.artists,
-                        album_name: track.album_name,
-                        album_artist: track.album_artist || track.artists,
-                        release_date: normalizeReleaseDate(track.release_date),
-                        cover_url: track.images,
-                        album_track_number: track.track_number,
-                        disc_number: track.disc_number,
-                        total_tracks: track.total_tracks,
-                        spotify_total_discs: track.total_discs,
-                        copyright: track.copyright,
-                        publisher: track.publisher,
-                        output_dir: pathInfo.targetOutputDir,
-                        audio_format: settings.audioFormat,
-                        filename_format: settings.filenameTemplate,
-                        track_number: settings.trackNumber,
-                        position: pathInfo.trackPosition,
-                        use_album_track_number: pathInfo.useAlbumTrackNumber,
-                        embed_lyrics: settings.embedLyrics,
-                        embed_max_quality_cover: settings.embedMaxQualityCover,
-                        use_first_artist_only: settings.useFirstArtistOnly,
-                        use_single_genre: settings.useSingleGenre,
-                        embed_genre: settings.embedGenre,
-                    });
-                }
-                if (response.success) {
-                    if (response.already_exists) {
-                        skippedCount++;
-                        logger.info(`skipped: ${track.name} - ${displayArtist} (already exists)`);
-                        setSkippedTracks((prev) => new Set(prev).add(id));
-                    }
-                    else {
-                        successCount++;
-                        logger.success(`downloaded: ${track.name} - ${displayArtist}`);
-                    }
-                    if (response.file) {
-                        finalFilePaths.set(id, response.file);
-                        finalFilePaths.set(track.spotify_id || id, response.file);
-                    }
-                    setDownloadedTracks((prev) => new Set(prev).add(id));
-                    setFailedTracks((prev) => {
-                        const newSet = new Set(prev);
-                        newSet.delete(id);
-                        return newSet;
-                    });
-                }
-                else {
-                    errorCount++;
-                    logger.error(`failed: ${track.name} - ${displayArtist}`);
-                    setFailedTracks((prev) => new Set(prev).add(id));
-                }
-            }
-            catch (err) {
-                errorCount++;
-                logger.error(`error: ${track.name} - ${err}`);
-                setFailedTracks((prev) => new Set(prev).add(id));
-            }
-            const completedCount = skippedCount + successCount + errorCount;
-            setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
-        }
-        setDownloadingTrack(null);
-        setCurrentDownloadInfo(null);
-        setIsDownloading(false);
-        setBulkDownloadType(null);
-        shouldStopDownloadRef.current = false;
-        if (settings.createM3u8File && playlistName) {
-            const paths = selectedTrackObjects
-                .map((t) => finalFilePaths.get(t.spotify_id || "") || "")
-                .filter((p) => p !== "");
-            if (paths.length > 0) {
-                try {
-                    logger.info(`creating m3u8 playlist: ${playlistName}`);
-                    await CreateM3U8File(playlistName, outputDir, paths);
-                    toast.success("M3U8 playlist created");
-                }
-                catch (err) {
-                    logger.error(`failed to create m3u8 playlist: ${err}`);
-                    toast.error(`Failed to create M3U8 playlist: ${err}`);
-                }
-            }
-        }
-        logger.info(`batch complete: ${successCount} downloaded, ${skippedCount} skipped, ${errorCount} failed`);
-        if (errorCount === 0 && skippedCount === 0) {
-            toast.success(`Downloaded ${successCount} tracks successfully`);
-        }
-        else if (errorCount === 0 && successCount === 0) {
-            toast.info(`${skippedCount} tracks already exist`);
-        }
-        else if (errorCount === 0) {
-            toast.info(`${successCount} downloaded, ${skippedCount} skipped`);
-        }
-        else {
-            const parts = [];
-            if (successCount > 0)
-                parts.push(`${successCount} downloaded`);
-            if (skippedCount > 0)
-                parts.push(`${skippedCount} skipped`);
-            parts.push(`${errorCount} failed`);
-            toast.warning(parts.join(", "));
-        }
-    };
-    const handleDownloadAll = async (tracks: TrackMetadata[], playlistName?: string, isAlbum?: boolean) => {
-        const tracksWithId = tracks.filter((track) => track.spotify_id);
-        if (tracksWithId.length === 0) {
-            toast.error("No tracks available for download");
-            return;
-        }
-        logger.info(`starting batch download: ${tracksWithId.length} tracks`);
-        const settings = await getSettingsWithDefaults();
-        setIsDownloading(true);
-        setBulkDownloadType("all");
-        setDownloadProgress(0);
-        const enrichedTracksWithId = await enrichTracksISRC(await enrichTracksReleaseDates(tracksWithId, settings), settings);
-        const trackPathInfo = enrichedTracksWithId.map((track, index) => ({
+    const activeBatchItemsRef = useRef<Set<string>>(new Set());
+    const downloadBatch = async (batchTracks: TrackMetadata[], settings: Settings, playlistName: string | undefined, isAlbum: boolean | undefined, total: number) => {
+        const trackPathInfo = await Promise.all(batchTracks.map(async (track, index) => ({
             track,
-            pathInfo: buildBatchTrackPathInfo(track, settings, playlistName, isAlbum, index + 1),
-        }));
+            pathInfo: await buildBatchTrackPathInfo(track, settings, playlistName, isAlbum, index + 1),
+        })));
         const outputDir = trackPathInfo[0]?.pathInfo.baseOutputDir || settings.downloadPath;
         logger.info(`checking existing files in parallel...`);
         const existenceChecks = trackPathInfo.map(({ track, pathInfo }) => {
@@ -653,25 +368,25 @@ export function useDownload() {
                 include_track_number: settings.trackNumber || false,
                 audio_format: settings.audioFormat || "mp3",
                 relative_path: pathInfo.relativePath,
+                upc: track.upc,
+                total_tracks: track.total_tracks || 0,
+                duration: Math.floor((track.duration_ms || 0) / 1000),
+                explicit: track.is_explicit || false,
             };
         });
         const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, settings.audioFormat, existenceChecks);
-        const finalFilePaths: string[] = new Array(enrichedTracksWithId.length).fill("");
-        const existingSpotifyIDs = new Set<string>();
+        const finalFilePaths = new Map<string, string>();
         const existingFilePaths = new Map<string, string>();
-        for (let i = 0; i < existenceResults.length; i++) {
-            const result = existenceResults[i];
+        for (const result of existenceResults) {
             if (result.exists) {
-                existingSpotifyIDs.add(result.spotify_id);
                 existingFilePaths.set(result.spotify_id, result.file_path || "");
-                finalFilePaths[i] = result.file_path || "";
+                finalFilePaths.set(result.spotify_id, result.file_path || "");
             }
         }
-        logger.info(`found ${existingSpotifyIDs.size} existing files`);
-        const { AddToDownloadQueue } = await import("../../wailsjs/go/main/App");
+        logger.info(`found ${existingFilePaths.size} existing files`);
         for (const { track, pathInfo } of trackPathInfo) {
             const trackID = track.spotify_id || "";
-            if (existingSpotifyIDs.has(trackID)) {
+            if (existingFilePaths.has(trackID)) {
                 const itemID = await AddToDownloadQueue(trackID, track.name || "", pathInfo.displayArtist, track.album_name || "");
                 const filePath = existingFilePaths.get(trackID) || "";
                 setTimeout(() => SkipDownloadItem(itemID, filePath), 10);
@@ -679,47 +394,18 @@ export function useDownload() {
                 setDownloadedTracks((prev: Set<string>) => new Set(prev).add(trackID));
             }
         }
-        const tracksToDownload = enrichedTracksWithId.filter((track) => {
-            const trackID = track.spotify_id || "";
-            return !existingSpotifyIDs.has(trackID);
-        });
-        let sessionToken = settings.sessionToken || "";
-        if (tracksToDownload.length > 0) {
-            try {
-                sessionToken = await ensureValidToken();
-            }
-            catch (err) {
-                logger.error(`failed to fetch session token for batch: ${err}`);
-                toast.error(err instanceof Error ? err.message : "Failed to fetch session token");
-                setDownloadingTrack(null);
-                setCurrentDownloadInfo(null);
-                setIsDownloading(false);
-                setBulkDownloadType(null);
-                shouldStopDownloadRef.current = false;
-                return;
-            }
-        }
+        const pendingTracks = trackPathInfo.filter(({ track }) => !existingFilePaths.has(track.spotify_id || ""));
         let successCount = 0;
         let errorCount = 0;
-        let skippedCount = existingSpotifyIDs.size;
-        const total = enrichedTracksWithId.length;
+        let cancelledCount = 0;
+        let skippedCount = existingFilePaths.size;
         setDownloadProgress(Math.round((skippedCount / total) * 100));
-        for (let i = 0; i < tracksToDownload.length; i++) {
-            if (shouldStopDownloadRef.current) {
-                toast.info(`Download stopped. ${successCount} tracks downloaded, ${tracksToDownload.length - i} remaining.`);
-                break;
-            }
-            const track = tracksToDownload[i];
-            const id = track.spotify_id || "";
-            const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
-            setDownloadingTrack(id);
-            setCurrentDownloadInfo({ name: track.name, artists: displayArtist || "" });
+        if (pendingTracks.length > 0) {
+            let itemIDs: string[] = [];
             try {
-                const playlistIndex = enrichedTracksWithId.findIndex((t) => t.spotify_id === id) + 1;
-                const pathInfo = buildBatchTrackPathInfo(track, settings, playlistName, isAlbum, playlistIndex);
-                let response = await downloadTrack({
-                    track_id: id,
-                    session_token: sessionToken,
+                itemIDs = await enqueueDownloads(pendingTracks.map(({ track, pathInfo }) => ({
+                    track_id: track.spotify_id || "",
+                    session_token: "",
                     track_name: track.name || "",
                     artist_name: track.artists || "",
                     album_name: track.album_name || "",
@@ -731,6 +417,9 @@ export function useDownload() {
                     total_tracks: track.total_tracks || 0,
                     spotify_total_discs: track.total_discs || 0,
                     isrc: track.isrc,
+                    upc: track.upc,
+                    explicit: track.is_explicit || false,
+                    duration: Math.floor((track.duration_ms || 0) / 1000),
                     copyright: track.copyright || "",
                     publisher: track.publisher || "",
                     output_dir: pathInfo.targetOutputDir,
@@ -739,44 +428,41 @@ export function useDownload() {
                     track_number: settings.trackNumber,
                     position: pathInfo.trackPosition,
                     use_album_track_number: pathInfo.useAlbumTrackNumber,
+                    spotify_id: track.spotify_id,
                     embed_lyrics: settings.embedLyrics,
                     embed_max_quality_cover: settings.embedMaxQualityCover,
                     use_first_artist_only: settings.useFirstArtistOnly,
                     use_single_genre: settings.useSingleGenre,
                     embed_genre: settings.embedGenre,
-                });
-                if (!response.success && isUnauthorizedDownloadError(response.error)) {
-                    sessionToken = await ensureValidToken(true);
-                    response = await downloadTrack({
-                        track_id: id,
-                        session_token: sessionToken,
-                        track_name: track.name || "",
-                        artist_name: track.artists || "",
-                        album_name: track.album_name || "",
-                        album_artist: track.album_artist || track.artists || "",
-                        release_date: normalizeReleaseDate(track.release_date),
-                        cover_url: track.images || "",
-                        album_track_number: track.track_number || 0,
-                        disc_number: track.disc_number || 0,
-                        total_tracks: track.total_tracks || 0,
-                        spotify_total_discs: track.total_discs || 0,
-                        copyright: track.copyright || "",
-                        publisher: track.publisher || "",
-                        output_dir: pathInfo.targetOutputDir,
-                        audio_format: settings.audioFormat,
-                        filename_format: settings.filenameTemplate,
-                        track_number: settings.trackNumber,
-                        position: pathInfo.trackPosition,
-                        use_album_track_number: pathInfo.useAlbumTrackNumber,
-                        embed_lyrics: settings.embedLyrics,
-                        embed_max_quality_cover: settings.embedMaxQualityCover,
-                        use_first_artist_only: settings.useFirstArtistOnly,
-                        use_single_genre: settings.useSingleGenre,
-                        embed_genre: settings.embedGenre,
-                    });
+                })));
+            }
+            catch (err) {
+                logger.error(`failed to queue batch: ${err}`);
+                toast.error(err instanceof Error ? err.message : `Failed to queue downloads: ${err}`);
+                return null;
+            }
+            const tracksByItemID = new Map(itemIDs.map((itemID, index) => [itemID, pendingTracks[index].track]));
+            activeBatchItemsRef.current = new Set(itemIDs);
+            if (shouldStopDownloadRef.current) {
+                itemIDs.forEach((itemID) => CancelDownloadItem(itemID).catch(() => { }));
+            }
+            await waitForDownloadItems(itemIDs, (item, finished) => {
+                const track = tracksByItemID.get(item.id);
+                if (!track) {
+                    return;
+                }
+                const id = track.spotify_id || "";
+                const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
+                if (!finished) {
+                    if (item.status === "downloading") {
+                        setDownloadingTrack(id);
+                        setCurrentDownloadInfo({ name: track.name, artists: displayArtist || "" });
+                    }
+                    return;
                 }
-                if (response.success) {
-                    if (response.already_exists) {
+                activeBatchItemsRef.current.delete(item.id);
+                if (item.status === "completed" || item.status === "skipped") {
+                    if (item.status === "skipped") {
                         skippedCount++;
                         logger.info(`skipped: ${track.name} - ${displayArtist} (already exists)`);
                         setSkippedTracks((prev) => new Set(prev).add(id));
@@ -785,40 +471,57 @@ export function useDownload() {
                         successCount++;
                         logger.success(`downloaded: ${track.name} - ${displayArtist}`);
                     }
+                    if (item.file_path) {
+                        finalFilePaths.set(id, item.file_path);
+                    }
                     setDownloadedTracks((prev) => new Set(prev).add(id));
                     setFailedTracks((prev) => {
                         const newSet = new Set(prev);
                         newSet.delete(id);
                         return newSet;
                     });
-                    if (response.file) {
-                        finalFilePaths[playlistIndex - 1] = response.file;
-                    }
+                }
+                else if (item.status === "cancelled") {
+                    cancelledCount++;
                 }
                 else {
                     errorCount++;
-                    logger.error(`failed: ${track.name} - ${displayArtist}`);
+                    logger.error(`failed: ${track.name} - ${displayArtist}${item.error_message ? ` - ${item.error_message}` : ""}`);
                     setFailedTracks((prev) => new Set(prev).add(id));
                 }
-            }
-            catch (err) {
-                errorCount++;
-                logger.error(`error: ${track.name} - ${err}`);
-                setFailedTracks((prev) => new Set(prev).add(id));
-            }
-            const completedCount = skippedCount + successCount + errorCount;
-            setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+                const completedCount = skippedCount + successCount + errorCount + cancelledCount;
+                setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+            });
+            activeBatchItemsRef.current = new Set();
+        }
+        if (cancelledCount > 0) {
+            toast.info(`Download stopped. ${successCount} tracks downloaded, ${cancelledCount} remaining.`);
+        }
+        const paths = batchTracks
+            .map((t) => finalFilePaths.get(t.spotify_id || "") || "")
+            .filter((p) => p !== "");
+        return { outputDir, paths, successCount, skippedCount, errorCount };
+    };
+    const runBatchDownload = async (batchTracks: TrackMetadata[], settings: Settings, playlistName: string | undefined, isAlbum: boolean | undefined, total: number) => {
+        let result: Awaited<ReturnType<typeof downloadBatch>> = null;
+        try {
+            result = await downloadBatch(batchTracks, settings, playlistName, isAlbum, total);
+        }
+        finally {
+            setDownloadingTrack(null);
+            setCurrentDownloadInfo(null);
+            setIsDownloading(false);
+            setBulkDownloadType(null);
+            shouldStopDownloadRef.current = false;
+        }
+        if (!result) {
+            return;
         }
-        setDownloadingTrack(null);
-        setCurrentDownloadInfo(null);
-        setIsDownloading(false);
-        setBulkDownloadType(null);
-        shouldStopDownloadRef.current = false;
-        shouldStopDownloadRef.current = false;
-        if (settings.createM3u8File && playlistName) {
+        const { outputDir, paths, successCount, skippedCount, errorCount } = result;
+        if (settings.createM3u8File && playlistName && paths.length > 0) {
             try {
                 logger.info(`creating m3u8 playlist: ${playlistName}`);
-                await CreateM3U8File(playlistName, outputDir, finalFilePaths.filter((p) => p !== ""));
+                await CreateM3U8File(playlistName, outputDir, paths);
                 toast.success("M3U8 playlist created");
             }
             catch (err) {
@@ -846,9 +549,39 @@ export function useDownload() {
             toast.warning(parts.join(", "));
         }
     };
+    const handleDownloadSelected = async (selectedTracks: string[], allTracks: TrackMetadata[], playlistName?: string, isAlbum?: boolean) => {
+        if (selectedTracks.length === 0) {
+            toast.error("No tracks selected");
+            return;
+        }
+        logger.info(`starting batch download: ${selectedTracks.length} selected tracks`);
+        const settings = await getSettingsWithDefaults();
+        setIsDownloading(true);
+        setBulkDownloadType("selected");
+        setDownloadProgress(0);
+        const selectedTrackObjects = await enrichTracksISRC(await enrichTracksReleaseDates(selectedTracks
+            .map((id) => allTracks.find((t) => t.spotify_id === id))
+            .filter((t): t is TrackMetadata => t !== undefined), settings), settings);
+        await runBatchDownload(selectedTrackObjects, settings, playlistName, isAlbum, selectedTracks.length);
+    };
+    const handleDownloadAll = async (tracks: TrackMetadata[], playlistName?: string, isAlbum?: boolean) => {
+        const tracksWithId = tracks.filter((track) => track.spotify_id);
+        if (tracksWithId.length === 0) {
+            toast.error("No tracks available for download");
+            return;
+        }
+        logger.info(`starting batch download: ${tracksWithId.length} tracks`);
+        const settings = await getSettingsWithDefaults();
+        setIsDownloading(true);
+        setBulkDownloadType("all");
+        setDownloadProgress(0);
+        const enrichedTracksWithId = await enrichTracksISRC(await enrichTracksReleaseDates(tracksWithId, settings), settings);
+        await runBatchDownload(enrichedTracksWithId, settings, playlistName, isAlbum, enrichedTracksWithId.length);
+    };
     const handleStopDownload = () => {
         logger.info("download stopped by user");
         shouldStopDownloadRef.current = true;
+        activeBatchItemsRef.current.forEach((itemID) => CancelDownloadItem(itemID).catch(() => { }));
         toast.info("Stopping download...");
     };
     const resetDownloadedTracks = () => {
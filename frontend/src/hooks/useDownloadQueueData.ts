@@ -1,6 +1,6 @@
 import { useEffect, useState } from "react";
-import { GetDownloadQueue } from "../../wailsjs/go/main/App";
 import { backend } from "../../wailsjs/go/models";
+import { subscribeDownloadQueue } from "@/lib/download-events";
 export function useDownloadQueueData() {
     const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
         is_downloading: false,
@@ -13,19 +13,6 @@ export function useDownloadQueueData() {
         failed_count: 0,
         skipped_count: 0,
     }));
-    useEffect(() => {
-        const fetchQueue = async () => {
-            try {
-                const info = await GetDownloadQueue();
-                setQueueInfo(info);
-            }
-            catch (error) {
-                console.error("Failed to get download queue:", error);
-            }
-        };
-        fetchQueue();
-        const interval = setInterval(fetchQueue, 200);
-        return () => clearInterval(interval);
-    }, []);
+    useEffect(() => subscribeDownloadQueue(setQueueInfo), []);
     return queueInfo;
 }
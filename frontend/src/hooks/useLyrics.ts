@@ -1,8 +1,9 @@
 import { useState, useRef } from "react";
 import { toast } from "sonner";
 import { DownloadLyrics } from "../../wailsjs/go/main/App";
-import { getSettingsWithDefaults, parseTemplate, type TemplateData } from "@/lib/settings";
-import { joinPath, sanitizePath, getFirstArtist } from "@/lib/utils";
+import { getSettingsWithDefaults, templateUsesToken } from "@/lib/settings";
+import { resolveTrackFolderPath } from "@/lib/api";
+import { getFirstArtist } from "@/lib/utils";
 import type { TrackMetadata } from "@/types/api";
 import { logger } from "@/lib/logger";
 const GetTrackISRC = (spotifyId: string): Promise<string> => (window as any)["go"]["main"]["App"]["GetTrackISRC"](spotifyId);
@@ -15,7 +16,7 @@ async function resolveTemplateISRC(settings: {
     }
     const folderTemplate = settings.folderTemplate || "";
     const filenameTemplate = settings.filenameTemplate || "";
-    if (!folderTemplate.includes("{isrc}") && !filenameTemplate.includes("{isrc}")) {
+    if (!templateUsesToken(folderTemplate, "isrc") && !templateUsesToken(filenameTemplate, "isrc")) {
         return "";
     }
     try {
@@ -42,40 +43,22 @@ export const useLyrics = () => {
         const settings = await getSettingsWithDefaults();
         setDownloadingLyricsTrack(spotifyId);
         try {
-            const os = settings.operatingSystem;
-            let outputDir = settings.downloadPath;
-            const placeholder = "__SLASH_PLACEHOLDER__";
-            const yearValue = releaseDate?.substring(0, 4);
             const displayArtist = settings.useFirstArtistOnly && artistName ? getFirstArtist(artistName) : artistName;
             const displayAlbumArtist = settings.useFirstArtistOnly && albumArtist ? getFirstArtist(albumArtist) : albumArtist;
             const resolvedTemplateISRC = await resolveTemplateISRC(settings, spotifyId);
-            const templateData: TemplateData = {
-                artist: displayArtist?.replace(/\//g, placeholder),
-                album: albumName?.replace(/\//g, placeholder),
-                album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-                title: trackName?.replace(/\//g, placeholder),
-                isrc: resolvedTemplateISRC?.replace(/\//g, placeholder),
-                track: position,
-                year: yearValue,
-                date: releaseDate,
-                playlist: playlistName?.replace(/\//g, placeholder),
-            };
-            const folderTemplate = settings.folderTemplate || "";
-            const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
-            if (settings.createPlaylistFolder && playlistName && (!isAlbum || !useAlbumSubfolder)) {
-                outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-            }
-            if (settings.folderTemplate) {
-                const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                if (folderPath) {
-                    const parts = folderPath.split("/").filter((p: string) => p.trim());
-                    for (const part of parts) {
-                        const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                        outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                    }
-                }
-            }
-            const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
+            const folderPath = await resolveTrackFolderPath(settings, {
+                spotify_id: spotifyId,
+                track_name: trackName,
+                artist_name: artistName,
+                album_name: albumName,
+                album_artist: albumArtist,
+                release_date: releaseDate,
+                isrc: resolvedTemplateISRC,
+                position,
+                disc_number: discNumber,
+            }, playlistName, isAlbum);
+            const outputDir = folderPath.output_dir;
+            const useAlbumTrackNumber = templateUsesToken(settings.folderTemplate, "album");
             const response = await DownloadLyrics({
                 spotify_id: spotifyId,
                 track_name: trackName || "",
@@ -144,41 +127,24 @@ export const useLyrics = () => {
             setDownloadingLyricsTrack(id);
             setLyricsDownloadProgress(Math.round((completed / total) * 100));
             try {
-                const os = settings.operatingSystem;
-                let outputDir = settings.downloadPath;
-                const placeholder = "__SLASH_PLACEHOLDER__";
-                const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
+                const useAlbumTrackNumber = templateUsesToken(settings.folderTemplate, "album");
                 const trackPosition = useAlbumTrackNumber ? (track.track_number || i + 1) : (i + 1);
-                const yearValue = track.release_date?.substring(0, 4);
                 const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
                 const displayAlbumArtist = settings.useFirstArtistOnly && track.album_artist ? getFirstArtist(track.album_artist) : track.album_artist;
-                const resolvedTemplateISRC = await resolveTemplateISRC(settings, id);
-                const templateData: TemplateData = {
-                    artist: displayArtist?.replace(/\//g, placeholder),
-                    album: track.album_name?.replace(/\//g, placeholder),
-                    album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-                    title: track.name?.replace(/\//g, placeholder),
-                    isrc: resolvedTemplateISRC?.replace(/\//g, placeholder),
-                    track: trackPosition,
-                    year: yearValue,
-                    date: track.release_date,
-                    playlist: playlistName?.replace(/\//g, placeholder),
-                };
-                const folderTemplate = settings.folderTemplate || "";
-                const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
-                if (settings.createPlaylistFolder && playlistName && (!isAlbum || !useAlbumSubfolder)) {
-                    outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
-                }
-                if (settings.folderTemplate) {
-                    const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                    if (folderPath) {
-                        const parts = folderPath.split("/").filter((p: string) => p.trim());
-                        for (const part of parts) {
-                            const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                            outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                        }
-                    }
-                }
+                const resolvedTemplateISRC = track.isrc || await resolveTemplateISRC(settings, id);
+                const folderPath = await resolveTrackFolderPath(settings, {
+                    spotify_id: id,
+                    track_name: track.name,
+                    artist_name: track.artists,
+                    album_name: track.album_name,
+                    album_artist: track.album_artist,
+                    release_date: track.release_date,
+                    isrc: resolvedTemplateISRC,
+                    position: trackPosition,
+                    disc_number: track.disc_number,
+                    total_tracks: track.total_tracks,
+                }, playlistName, isAlbum);
+                const outputDir = folderPath.output_dir;
                 const response = await DownloadLyrics({
                     spotify_id: id,
                     track_name: track.name || "",
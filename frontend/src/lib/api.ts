@@ -1,6 +1,7 @@
-import type { SpotifyMetadataResponse, DownloadRequest, DownloadResponse, HealthResponse, CurrentIPInfo, LyricsDownloadRequest, LyricsDownloadResponse, CoverDownloadRequest, CoverDownloadResponse, HeaderDownloadRequest, HeaderDownloadResponse, GalleryImageDownloadRequest, GalleryImageDownloadResponse, AvatarDownloadRequest, AvatarDownloadResponse, } from "@/types/api";
-import { GetSpotifyMetadata, GetCurrentIPInfo, DownloadTrack, DownloadLyrics, DownloadCover, DownloadHeader, DownloadGalleryImage, DownloadAvatar } from "../../wailsjs/go/main/App";
+import type { SpotifyMetadataResponse, DownloadRequest, DownloadResponse, FolderPathResult, HealthResponse, CurrentIPInfo, LyricsDownloadRequest, LyricsDownloadResponse, CoverDownloadRequest, CoverDownloadResponse, HeaderDownloadRequest, HeaderDownloadResponse, GalleryImageDownloadRequest, GalleryImageDownloadResponse, AvatarDownloadRequest, AvatarDownloadResponse, } from "@/types/api";
+import { GetSpotifyMetadata, GetCurrentIPInfo, DownloadTrack, EnqueueDownloads, ResolveFolderPath, DownloadLyrics, DownloadCover, DownloadHeader, DownloadGalleryImage, DownloadAvatar } from "../../wailsjs/go/main/App";
 import { main } from "../../wailsjs/go/models";
+import { templateUsesToken, type Settings } from "@/lib/settings";
 export async function fetchSpotifyMetadata(url: string, batch: boolean = true, delay: number = 1.0, timeout: number = 300.0): Promise<SpotifyMetadataResponse> {
     const req = new main.SpotifyMetadataRequest({
         url,
@@ -18,6 +19,22 @@ export async function downloadTrack(request: DownloadRequest): Promise<DownloadR
     }
     return await DownloadTrack(req);
 }
+export async function enqueueDownloads(requests: DownloadRequest[]): Promise<string[]> {
+    return await EnqueueDownloads(requests.map((request) => new main.DownloadRequest(request)));
+}
+export async function resolveTrackFolderPath(settings: Settings, request: Omit<DownloadRequest, "session_token">, playlistName?: string, isAlbum?: boolean): Promise<FolderPathResult> {
+    const folderTemplate = settings.folderTemplate || "";
+    const usePlaylistName = settings.createPlaylistFolder || templateUsesToken(folderTemplate, "playlist");
+    return await ResolveFolderPath(new main.DownloadRequest({
+        ...request,
+        session_token: "",
+        output_dir: settings.downloadPath,
+        folder_template: folderTemplate,
+        playlist_name: usePlaylistName ? playlistName : undefined,
+        is_album: isAlbum || false,
+        use_first_artist_only: settings.useFirstArtistOnly,
+    }));
+}
 export async function downloadLyrics(request: LyricsDownloadRequest): Promise<LyricsDownloadResponse> {
     const req = new main.LyricsDownloadRequest(request);
     return await DownloadLyrics(req);
@@ -0,0 +1,206 @@
+import { GetDownloadProgress, GetDownloadQueue } from "../../wailsjs/go/main/App";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import { backend } from "../../wailsjs/go/models";
+export interface DownloadProgressInfo {
+    is_downloading: boolean;
+    mb_downloaded: number;
+    speed_mbps: number;
+}
+interface DownloadProgressEvent {
+    item_id?: string;
+    progress: number;
+    speed: number;
+    overall: DownloadProgressInfo;
+}
+export const DOWNLOAD_EVENTS = {
+    itemAdded: "download:item-added",
+    itemStarted: "download:item-started",
+    itemUpdated: "download:item-updated",
+    progress: "download:progress",
+    itemCompleted: "download:item-completed",
+    itemFailed: "download:item-failed",
+    queueCleared: "download:queue-cleared",
+    sessionReset: "download:session-reset",
+    bandwidth: "download:bandwidth",
+} as const;
+type Listener<T> = (value: T) => void;
+let queueInfo = new backend.DownloadQueueInfo({
+    is_downloading: false,
+    queue: [],
+    current_speed: 0,
+    total_downloaded: 0,
+    session_start_time: 0,
+    queued_count: 0,
+    downloading_count: 0,
+    completed_count: 0,
+    failed_count: 0,
+    skipped_count: 0,
+    paused_count: 0,
+    cancelled_count: 0,
+    workers: 0,
+});
+let progressInfo: DownloadProgressInfo = {
+    is_downloading: false,
+    mb_downloaded: 0,
+    speed_mbps: 0,
+};
+const queueListeners = new Set<Listener<backend.DownloadQueueInfo>>();
+const progressListeners = new Set<Listener<DownloadProgressInfo>>();
+let started = false;
+function publishQueue(next: backend.DownloadQueueInfo) {
+    queueInfo = next;
+    queueListeners.forEach((listener) => listener(queueInfo));
+}
+function publishProgress(next: DownloadProgressInfo) {
+    progressInfo = next;
+    progressListeners.forEach((listener) => listener(progressInfo));
+}
+function withQueue(queue: backend.DownloadItem[], overrides: Partial<backend.DownloadQueueInfo> = {}): backend.DownloadQueueInfo {
+    const counts: Record<string, number> = {};
+    let speed = 0;
+    for (const item of queue) {
+        counts[item.status] = (counts[item.status] || 0) + 1;
+        if (item.status === "downloading") {
+            speed += item.speed;
+        }
+    }
+    return new backend.DownloadQueueInfo({
+        ...queueInfo,
+        ...overrides,
+        queue,
+        current_speed: speed,
+        queued_count: counts.queued || 0,
+        downloading_count: counts.downloading || 0,
+        completed_count: counts.completed || 0,
+        failed_count: counts.failed || 0,
+        skipped_count: counts.skipped || 0,
+        paused_count: counts.paused || 0,
+        cancelled_count: counts.cancelled || 0,
+    });
+}
+function upsertItem(item: backend.DownloadItem, overrides: Partial<backend.DownloadQueueInfo> = {}) {
+    const index = queueInfo.queue.findIndex((existing) => existing.id === item.id);
+    const queue = [...queueInfo.queue];
+    if (index >= 0) {
+        queue[index] = item;
+    }
+    else {
+        queue.push(item);
+    }
+    publishQueue(withQueue(queue, overrides));
+}
+function handleProgress(event: DownloadProgressEvent) {
+    publishProgress(event.overall);
+    if (!event.item_id) {
+        if (queueInfo.is_downloading !== event.overall.is_downloading) {
+            publishQueue(withQueue(queueInfo.queue, { is_downloading: event.overall.is_downloading }));
+        }
+        return;
+    }
+    const index = queueInfo.queue.findIndex((item) => item.id === event.item_id);
+    if (index < 0) {
+        return;
+    }
+    const queue = [...queueInfo.queue];
+    queue[index] = new backend.DownloadItem({ ...queue[index], progress: event.progress, speed: event.speed });
+    publishQueue(withQueue(queue, { is_downloading: event.overall.is_downloading }));
+}
+export async function refreshDownloadQueue() {
+    try {
+        const [info, progress] = await Promise.all([GetDownloadQueue(), GetDownloadProgress()]);
+        publishQueue(info);
+        publishProgress(progress);
+    }
+    catch (error) {
+        console.error("Failed to get download queue:", error);
+    }
+}
+function startDownloadEvents() {
+    if (started) {
+        return;
+    }
+    started = true;
+    EventsOn(DOWNLOAD_EVENTS.itemAdded, (item: backend.DownloadItem) => {
+        upsertItem(item, {
+            session_start_time: queueInfo.session_start_time || Math.floor(Date.now() / 1000),
+        });
+    });
+    EventsOn(DOWNLOAD_EVENTS.itemStarted, (item: backend.DownloadItem) => upsertItem(item, { is_downloading: true }));
+    EventsOn(DOWNLOAD_EVENTS.itemUpdated, (item: backend.DownloadItem) => upsertItem(item));
+    EventsOn(DOWNLOAD_EVENTS.itemFailed, (item: backend.DownloadItem) => upsertItem(item));
+    EventsOn(DOWNLOAD_EVENTS.itemCompleted, (item: backend.DownloadItem) => {
+        upsertItem(item, { total_downloaded: queueInfo.total_downloaded + item.progress });
+    });
+    EventsOn(DOWNLOAD_EVENTS.progress, handleProgress);
+    EventsOn(DOWNLOAD_EVENTS.queueCleared, (info: backend.DownloadQueueInfo) => publishQueue(info));
+    EventsOn(DOWNLOAD_EVENTS.sessionReset, (info: backend.DownloadQueueInfo) => publishQueue(info));
+    EventsOn(DOWNLOAD_EVENTS.bandwidth, (bandwidth: backend.BandwidthStatus) => {
+        publishQueue(new backend.DownloadQueueInfo({ ...queueInfo, bandwidth }));
+    });
+    refreshDownloadQueue();
+}
+export function subscribeDownloadQueue(listener: Listener<backend.DownloadQueueInfo>) {
+    startDownloadEvents();
+    queueListeners.add(listener);
+    listener(queueInfo);
+    return () => {
+        queueListeners.delete(listener);
+    };
+}
+export function subscribeDownloadProgress(listener: Listener<DownloadProgressInfo>) {
+    startDownloadEvents();
+    progressListeners.add(listener);
+    listener(progressInfo);
+    return () => {
+        progressListeners.delete(listener);
+    };
+}
+const TERMINAL_STATUSES = new Set(["completed", "failed", "skipped", "cancelled"]);
+export function waitForDownloadItems(itemIDs: string[], onUpdate: (item: backend.DownloadItem, finished: boolean) => void): Promise<void> {
+    return new Promise((resolve) => {
+        const pending = new Set(itemIDs);
+        const seen = new Set<string>();
+        const lastStatus = new Map<string, string>();
+        let done = false;
+        let unsubscribe: (() => void) | null = null;
+        const finish = () => {
+            done = true;
+            unsubscribe?.();
+            resolve();
+        };
+        const listener = (info: backend.DownloadQueueInfo) => {
+            if (done) {
+                return;
+            }
+            const present = new Set<string>();
+            for (const item of info.queue) {
+                if (!pending.has(item.id)) {
+                    continue;
+                }
+                present.add(item.id);
+                seen.add(item.id);
+                if (TERMINAL_STATUSES.has(item.status)) {
+                    pending.delete(item.id);
+                    onUpdate(item, true);
+                }
+                else if (lastStatus.get(item.id) !== item.status) {
+                    lastStatus.set(item.id, item.status);
+                    onUpdate(item, false);
+                }
+            }
+            for (const id of [...pending]) {
+                if (seen.has(id) && !present.has(id)) {
+                    pending.delete(id);
+                    onUpdate(new backend.DownloadItem({ id, status: "cancelled" }), true);
+                }
+            }
+            if (pending.size === 0) {
+                finish();
+            }
+        };
+        unsubscribe = subscribeDownloadQueue(listener);
+        if (done) {
+            unsubscribe();
+        }
+    });
+}
@@ -31,6 +31,17 @@ export interface Settings {
     embedGenre: boolean;
     redownloadWithSuffix: boolean;
     separator: "comma" | "semicolon";
+    downloadWorkers?: number;
+    bandwidthLimit?: number;
+    downloadWindows?: string;
+    retryMaxAttempts?: number;
+    retryBaseDelaySeconds?: number;
+    retryMaxDelaySeconds?: number;
+    lyricsProviders?: string[];
+    replayGainOnDownload?: boolean;
+    skipExistingISRC?: boolean;
+    musicBrainzFullTagging?: boolean;
+    libraryRoot?: string;
 }
 export const FOLDER_PRESETS: Record<FolderPreset, {
     label: string;
@@ -39,14 +50,14 @@ export const FOLDER_PRESETS: Record<FolderPreset, {
     "none": { label: "No Subfolder", template: "" },
     "artist": { label: "Artist", template: "{artist}" },
     "album": { label: "Album", template: "{album}" },
-    "year-album": { label: "[Year] Album", template: "[{year}] {album}" },
-    "year-artist-album": { label: "[Year] Artist - Album", template: "[{year}] {artist} - {album}" },
+    "year-album": { label: "[Year] Album", template: "[[{year}]] {album}" },
+    "year-artist-album": { label: "[Year] Artist - Album", template: "[[{year}]] {artist} - {album}" },
     "artist-album": { label: "Artist / Album", template: "{artist}/{album}" },
-    "artist-year-album": { label: "Artist / [Year] Album", template: "{artist}/[{year}] {album}" },
+    "artist-year-album": { label: "Artist / [Year] Album", template: "{artist}/[[{year}]] {album}" },
     "artist-year-nested-album": { label: "Artist / Year / Album", template: "{artist}/{year}/{album}" },
     "album-artist": { label: "Album Artist", template: "{album_artist}" },
     "album-artist-album": { label: "Album Artist / Album", template: "{album_artist}/{album}" },
-    "album-artist-year-album": { label: "Album Artist / [Year] Album", template: "{album_artist}/[{year}] {album}" },
+    "album-artist-year-album": { label: "Album Artist / [Year] Album", template: "{album_artist}/[[{year}]] {album}" },
     "album-artist-year-nested-album": { label: "Album Artist / Year / Album", template: "{album_artist}/{year}/{album}" },
     "year": { label: "Year", template: "{year}" },
     "year-artist": { label: "Year / Artist", template: "{year}/{artist}" },
@@ -81,6 +92,21 @@ export const TEMPLATE_VARIABLES = [
     { key: "{date}", description: "Release date (YYYY-MM-DD)", example: "2014-10-27" },
     { key: "{isrc}", description: "Track ISRC", example: "USUM71412345" },
 ];
+export const FILENAME_TEMPLATE_VARIABLES = [
+    ...TEMPLATE_VARIABLES,
+    { key: "{upc}", description: "Album UPC", example: "00843930013562" },
+    { key: "{spotify_id}", description: "Spotify track ID", example: "0cqRj7pUJDkTCEsJkx8snD" },
+    { key: "{total_tracks}", description: "Number of tracks on the album", example: "13" },
+    { key: "{duration}", description: "Track duration (m.ss)", example: "3.39" },
+    { key: "{explicit}", description: "\"Explicit\" for explicit tracks", example: "Explicit" },
+];
+export function templateUsesToken(template: string | undefined, name: string): boolean {
+    const tokens = (template || "").replace(/\[\[|\]\]/g, "").match(/\{[^{}\[\]]*\}/g) || [];
+    return tokens.some((token: string) => {
+        const names = token.slice(1, -1).split(":")[0].split("|");
+        return names.some((tokenName: string) => tokenName.trim().toLowerCase() === name);
+    });
+}
 function detectOS(): "Windows" | "linux/MacOS" {
     const platform = window.navigator.platform.toLowerCase();
     if (platform.includes('win')) {
@@ -112,8 +138,21 @@ export const DEFAULT_SETTINGS: Settings = {
     useSingleGenre: false,
     embedGenre: false,
     redownloadWithSuffix: false,
-    separator: "semicolon"
+    separator: "semicolon",
+    downloadWorkers: 3,
+    bandwidthLimit: 0,
+    downloadWindows: "",
+    retryMaxAttempts: 3,
+    retryBaseDelaySeconds: 2,
+    retryMaxDelaySeconds: 60
 };
+export const LYRICS_PROVIDERS: {
+    value: string;
+    label: string;
+}[] = [
+    { value: "lrclib", label: "LRCLIB" },
+    { value: "netease", label: "NetEase" },
+];
 export const FONT_OPTIONS: {
     value: FontFamily;
     label: string;
@@ -194,6 +233,9 @@ function normalizeSettingsData(source: LegacySettings | null | undefined): Setti
             parsed.folderTemplate = "";
         }
     }
+    if (parsed.folderPreset && parsed.folderPreset !== "custom" && parsed.folderPreset in FOLDER_PRESETS) {
+        parsed.folderTemplate = FOLDER_PRESETS[parsed.folderPreset].template;
+    }
     if (!("filenamePreset" in parsed) && "filenameFormat" in parsed) {
         const format = parsed.filenameFormat;
         if (format === "title-artist") {
@@ -266,6 +308,9 @@ export async function loadSettings(): Promise<Settings> {
             if (backendSettings) {
                 cachedSettings = normalizeSettingsData(backendSettings);
                 hasLoadedSettings = true;
+                if (cachedSettings.folderTemplate !== (backendSettings as LegacySettings).folderTemplate) {
+                    await SaveToBackend(toBackendSettingsPayload(cachedSettings));
+                }
                 return cachedSettings;
             }
         }
@@ -290,34 +335,6 @@ export async function loadSettings(): Promise<Settings> {
         loadSettingsPromise = null;
     }
 }
-export interface TemplateData {
-    artist?: string;
-    album?: string;
-    album_artist?: string;
-    title?: string;
-    isrc?: string;
-    track?: number;
-    disc?: number;
-    year?: string;
-    date?: string;
-    playlist?: string;
-}
-export function parseTemplate(template: string, data: TemplateData): string {
-    if (!template)
-        return "";
-    let result = template;
-    result = result.replace(/\{title\}/g, data.title || "Unknown Title");
-    result = result.replace(/\{artist\}/g, data.artist || "Unknown Artist");
-    result = result.replace(/\{album\}/g, data.album || "Unknown Album");
-    result = result.replace(/\{album_artist\}/g, data.album_artist || data.artist || "Unknown Artist");
-    result = result.replace(/\{isrc\}/g, data.isrc || "");
-    result = result.replace(/\{track\}/g, data.track ? String(data.track).padStart(2, "0") : "00");
-    result = result.replace(/\{disc\}/g, data.disc ? String(data.disc) : "1");
-    result = result.replace(/\{year\}/g, data.year || "0000");
-    result = result.replace(/\{date\}/g, data.date || "0000-00-00");
-    result = result.replace(/\{playlist\}/g, data.playlist || "");
-    return result;
-}
 export async function getSettingsWithDefaults(): Promise<Settings> {
     const settings = await loadSettings();
     if (!settings.downloadPath) {
@@ -125,6 +125,9 @@ export interface DownloadRequest {
     total_tracks?: number;
     spotify_total_discs?: number;
     isrc?: string;
+    upc?: string;
+    explicit?: boolean;
+    duration?: number;
     copyright?: string;
     publisher?: string;
     output_dir?: string;
@@ -137,10 +140,18 @@ export interface DownloadRequest {
     embed_lyrics?: boolean;
     embed_max_quality_cover?: boolean;
     item_id?: string;
+    folder_template?: string;
+    playlist_name?: string;
+    is_album?: boolean;
     use_first_artist_only?: boolean;
     use_single_genre?: boolean;
     embed_genre?: boolean;
 }
+export interface FolderPathResult {
+    base_dir: string;
+    output_dir: string;
+    relative_path?: string;
+}
 export interface DownloadResponse {
     success: boolean;
     message: string;
@@ -180,6 +191,7 @@ export interface LyricsDownloadResponse {
     file?: string;
     error?: string;
     already_exists?: boolean;
+    source?: string;
 }
 export interface TimeSlice {
     time: number;
@@ -278,4 +290,15 @@ export interface AudioMetadata {
     year: string;
     upc?: string;
     isrc?: string;
+    spotify_id?: string;
+    provenance?: Provenance;
+}
+export interface Provenance {
+    spotify_track_id?: string;
+    spotify_album_id?: string;
+    spotify_artist_ids?: string[];
+    musicbrainz_recording_id?: string;
+    musicbrainz_release_id?: string;
+    download_date?: string;
+    source_quality?: string;
 }
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afkarxyz/SpotiDownloader/backend"
+)
+
+type SyncPlaylistRequest struct {
+	URL           string `json:"url"`
+	OutputDir     string `json:"output_dir,omitempty"`
+	AudioFormat   string `json:"audio_format,omitempty"`
+	RemovedAction string `json:"removed_action,omitempty"`
+	ArchiveDir    string `json:"archive_dir,omitempty"`
+}
+
+type SyncPlaylistResult struct {
+	PlaylistID string   `json:"playlist_id"`
+	Name       string   `json:"name"`
+	OutputDir  string   `json:"output_dir"`
+	FirstSync  bool     `json:"first_sync"`
+	Total      int      `json:"total"`
+	Existing   int      `json:"existing"`
+	Added      []string `json:"added"`
+	Removed    []string `json:"removed"`
+	Archived   []string `json:"archived,omitempty"`
+	Deleted    []string `json:"deleted,omitempty"`
+	QueuedIDs  []string `json:"queued_ids,omitempty"`
+	M3U8Path   string   `json:"m3u8_path,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+type playlistSyncPlan struct {
+	state         backend.PlaylistSyncState
+	playlistID    string
+	requests      []DownloadRequest
+	pending       []DownloadRequest
+	removed       []backend.PlaylistSyncTrack
+	removedAction string
+	archiveDir    string
+	result        SyncPlaylistResult
+}
+
+func expectedDownloadPath(req DownloadRequest) string {
+	fileExt := ".mp3"
+	if req.AudioFormat == "flac" {
+		fileExt = ".flac"
+	}
+
+	filename := backend.BuildFilenameFromTemplate(req.FilenameFormat, downloadTemplateData(req), req.TrackNumber)
+	filename = backend.SanitizeFilename(filename) + fileExt
+
+	return filepath.Join(backend.NormalizePath(resolveDownloadDir(req)), filename)
+}
+
+func (a *App) planPlaylistSync(req SyncPlaylistRequest) (*playlistSyncPlan, error) {
+	playlistID, err := backend.SpotifyPlaylistID(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := backend.GetPlaylistSyncState(playlistID, "SpotiDownloader")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sync state: %v", err)
+	}
+
+	defaults := loadDownloadDefaults()
+	if previous != nil {
+		if previous.OutputDir != "" {
+			defaults.OutputDir = previous.OutputDir
+		}
+		if previous.AudioFormat != "" {
+			defaults.AudioFormat = previous.AudioFormat
+		}
+	}
+	if strings.TrimSpace(req.OutputDir) != "" {
+		defaults.OutputDir = req.OutputDir
+	}
+	if req.AudioFormat == "mp3" || req.AudioFormat == "flac" {
+		defaults.AudioFormat = req.AudioFormat
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	data, err := backend.GetFilteredSpotifyData(ctx, req.URL, false, 0, defaults.Separator, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch playlist: %v", err)
+	}
+
+	collection, err := buildDownloadCollection(data, defaults)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Type != "playlist" {
+		return nil, fmt.Errorf("not a playlist: %s", req.URL)
+	}
+
+	playlistName := ""
+	if defaults.CreatePlaylistFolder {
+		playlistName = playlistFolderName(collection.Name, collection.Owner, defaults.PlaylistOwnerFolder)
+	}
+	playlistDir := backend.NormalizePath(backend.SanitizeFolderPath(playlistFolderDir(defaults.OutputDir, playlistName, defaults.FolderTemplate, false)))
+
+	plan := &playlistSyncPlan{
+		playlistID: playlistID,
+		requests:   collection.Requests,
+		state: backend.PlaylistSyncState{
+			PlaylistID:  playlistID,
+			URL:         req.URL,
+			Name:        collection.Name,
+			Owner:       collection.Owner,
+			OutputDir:   defaults.OutputDir,
+			AudioFormat: defaults.AudioFormat,
+		},
+		result: SyncPlaylistResult{
+			PlaylistID: playlistID,
+			Name:       collection.Name,
+			OutputDir:  playlistDir,
+			FirstSync:  previous == nil,
+			Total:      len(collection.Requests),
+			Added:      []string{},
+			Removed:    []string{},
+		},
+	}
+
+	checks := make([]CheckFileExistenceRequest, 0, len(collection.Requests))
+	for _, r := range collection.Requests {
+		data := downloadTemplateData(r)
+		checks = append(checks, CheckFileExistenceRequest{
+			SpotifyID:           r.SpotifyID,
+			TrackName:           r.TrackName,
+			ArtistName:          data.Artist,
+			AlbumName:           r.AlbumName,
+			AlbumArtist:         data.AlbumArtist,
+			ReleaseDate:         r.ReleaseDate,
+			ISRC:                r.ISRC,
+			TrackNumber:         r.AlbumTrackNumber,
+			DiscNumber:          r.DiscNumber,
+			Position:            r.Position,
+			UseAlbumTrackNumber: r.UseAlbumTrackNumber,
+			FilenameFormat:      r.FilenameFormat,
+			IncludeTrackNumber:  r.TrackNumber,
+			AudioFormat:         r.AudioFormat,
+			FolderTemplate:      r.FolderTemplate,
+			PlaylistName:        r.PlaylistName,
+			PlaylistOwner:       r.PlaylistOwner,
+			IsAlbum:             r.IsAlbum,
+			UPC:                 r.UPC,
+			TotalTracks:         r.TotalTracks,
+			Duration:            r.Duration,
+			Explicit:            r.Explicit,
+		})
+	}
+	existence := a.CheckFilesExistence(defaults.OutputDir, "", defaults.AudioFormat, checks)
+
+	previousTracks := make(map[string]backend.PlaylistSyncTrack)
+	if previous != nil {
+		for _, track := range previous.Tracks {
+			previousTracks[track.SpotifyID] = track
+		}
+	}
+
+	current := make(map[string]struct{}, len(collection.Requests))
+	for i, r := range collection.Requests {
+		current[r.SpotifyID] = struct{}{}
+
+		filePath := ""
+		exists := i < len(existence) && existence[i].Exists
+		if exists {
+			filePath = existence[i].FilePath
+			if filePath == "" {
+				filePath = expectedDownloadPath(r)
+			}
+		} else if prev, ok := previousTracks[r.SpotifyID]; ok && prev.FilePath != "" {
+			if _, statErr := os.Stat(prev.FilePath); statErr == nil {
+				exists = true
+				filePath = prev.FilePath
+			}
+		}
+
+		if exists {
+			plan.result.Existing++
+		} else {
+			plan.pending = append(plan.pending, r)
+			plan.result.Added = append(plan.result.Added, r.SpotifyID)
+		}
+
+		plan.state.Tracks = append(plan.state.Tracks, backend.PlaylistSyncTrack{
+			SpotifyID:  r.SpotifyID,
+			TrackName:  r.TrackName,
+			ArtistName: r.ArtistName,
+			FilePath:   filePath,
+		})
+	}
+
+	plan.removedAction = req.RemovedAction
+	if plan.removedAction == "" {
+		plan.removedAction = backend.SyncRemovedKeep
+	}
+	plan.archiveDir = req.ArchiveDir
+	if plan.archiveDir == "" {
+		plan.archiveDir = filepath.Join(playlistDir, "_archive")
+	}
+
+	if previous != nil {
+		for _, track := range previous.Tracks {
+			if _, ok := current[track.SpotifyID]; ok {
+				continue
+			}
+			plan.removed = append(plan.removed, track)
+			plan.result.Removed = append(plan.result.Removed, track.SpotifyID)
+		}
+	}
+
+	return plan, nil
+}
+
+func (p *playlistSyncPlan) setTrackPath(spotifyID, filePath string) {
+	for i := range p.state.Tracks {
+		if p.state.Tracks[i].SpotifyID == spotifyID {
+			p.state.Tracks[i].FilePath = filePath
+		}
+	}
+}
+
+func (p *playlistSyncPlan) applyRemovals() {
+	currentPaths := make(map[string]struct{}, len(p.state.Tracks))
+	for _, track := range p.state.Tracks {
+		if track.FilePath != "" {
+			currentPaths[track.FilePath] = struct{}{}
+		}
+	}
+
+	for _, track := range p.removed {
+		if track.FilePath == "" {
+			continue
+		}
+		if _, shared := currentPaths[track.FilePath]; shared {
+			continue
+		}
+
+		newPath, err := backend.ApplyRemovedTrackAction(track.FilePath, p.removedAction, p.archiveDir)
+		if err != nil {
+			p.result.Errors = append(p.result.Errors, err.Error())
+			continue
+		}
+		switch {
+		case p.removedAction == backend.SyncRemovedArchive && newPath != "":
+			p.result.Archived = append(p.result.Archived, newPath)
+		case p.removedAction == backend.SyncRemovedDelete:
+			p.result.Deleted = append(p.result.Deleted, track.FilePath)
+		}
+	}
+}
+
+func (a *App) finishPlaylistSync(plan *playlistSyncPlan, failed int) error {
+	if failed > 0 {
+		if len(plan.removed) > 0 && plan.removedAction != backend.SyncRemovedKeep {
+			plan.result.Errors = append(plan.result.Errors, fmt.Sprintf("skipped removing %d track(s) because %d download(s) failed", len(plan.removed), failed))
+		}
+	} else {
+		plan.applyRemovals()
+	}
+
+	paths := make([]string, 0, len(plan.state.Tracks))
+	for _, track := range plan.state.Tracks {
+		if track.FilePath != "" {
+			paths = append(paths, track.FilePath)
+		}
+	}
+
+	m3u8Path, err := backend.WriteM3U8File(plan.state.Name, plan.result.OutputDir, paths)
+	if err != nil {
+		plan.result.Errors = append(plan.result.Errors, fmt.Sprintf("failed to write m3u8: %v", err))
+	}
+	plan.result.M3U8Path = m3u8Path
+
+	if failed > 0 {
+		plan.state.Tracks = append(plan.state.Tracks, plan.removed...)
+	}
+
+	return backend.SavePlaylistSyncState(plan.state, "SpotiDownloader")
+}
+
+func (a *App) SyncPlaylist(req SyncPlaylistRequest) (SyncPlaylistResult, error) {
+	plan, err := a.planPlaylistSync(req)
+	if err != nil {
+		return SyncPlaylistResult{}, err
+	}
+
+	if len(plan.pending) == 0 {
+		if err := a.finishPlaylistSync(plan, 0); err != nil {
+			return plan.result, fmt.Errorf("failed to save sync state: %v", err)
+		}
+		fmt.Printf("[Sync] %s: %d new, %d existing, %d removed\n", plan.result.Name, len(plan.result.Added), plan.result.Existing, len(plan.result.Removed))
+		return plan.result, nil
+	}
+
+	ids, err := a.EnqueueDownloads(plan.pending)
+	if err != nil {
+		return plan.result, err
+	}
+	plan.result.QueuedIDs = ids
+
+	go a.completePlaylistSync(plan, ids)
+
+	return plan.result, nil
+}
+
+func (a *App) completePlaylistSync(plan *playlistSyncPlan, ids []string) {
+	items := backend.WaitForDownloadItems(ids)
+
+	failed := 0
+	for i, id := range ids {
+		item := items[id]
+		switch item.Status {
+		case backend.StatusCompleted, backend.StatusSkipped:
+			if item.FilePath != "" {
+				plan.setTrackPath(plan.pending[i].SpotifyID, item.FilePath)
+			}
+		default:
+			failed++
+		}
+	}
+
+	if err := a.finishPlaylistSync(plan, failed); err != nil {
+		fmt.Printf("[Sync] Failed to save sync state for %s: %v\n", plan.result.Name, err)
+		return
+	}
+	fmt.Printf("[Sync] %s: %d new, %d existing, %d removed, %d failed\n", plan.result.Name, len(plan.result.Added), plan.result.Existing, len(plan.result.Removed), failed)
+}
+
+func (a *App) GetPlaylistSyncStates() ([]backend.PlaylistSyncState, error) {
+	return backend.GetPlaylistSyncStates("SpotiDownloader")
+}
+
+func (a *App) DeletePlaylistSyncState(playlistID string) error {
+	return backend.DeletePlaylistSyncState(playlistID, "SpotiDownloader")
+}
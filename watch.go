@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/afkarxyz/SpotiDownloader/backend"
+)
+
+func (a *App) fetchWatchJobs(watch backend.Watch) (string, []backend.DownloadJob, error) {
+	defaults := loadDownloadDefaults()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	data, err := backend.GetFilteredSpotifyData(ctx, watch.URL, false, 0, defaults.Separator, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch %s: %v", watch.URL, err)
+	}
+
+	collection, err := buildDownloadCollection(data, defaults)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jobs := make([]backend.DownloadJob, 0, len(collection.Requests))
+	for _, req := range collection.Requests {
+		job, err := newDownloadJob(req)
+		if err != nil {
+			return "", nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return collection.Name, jobs, nil
+}
+
+func (a *App) AddWatch(spotifyURL string, intervalHours int) (*backend.Watch, error) {
+	return backend.AddWatch(spotifyURL, intervalHours)
+}
+
+func (a *App) ListWatches() ([]backend.Watch, error) {
+	return backend.ListWatches()
+}
+
+func (a *App) RemoveWatch(watchID string) error {
+	return backend.RemoveWatch(watchID)
+}
+
+func (a *App) SetWatchEnabled(watchID string, enabled bool) error {
+	return backend.SetWatchEnabled(watchID, enabled)
+}
+
+func (a *App) RunWatchNow(watchID string) (*backend.WatchRun, error) {
+	return backend.RunWatch(watchID)
+}
+
+func (a *App) GetWatchRuns(watchID string, limit int) ([]backend.WatchRun, error) {
+	return backend.GetWatchRuns(watchID, limit)
+}